@@ -0,0 +1,384 @@
+package clogviewr
+
+import (
+    "fmt"
+    "github.com/gdamore/tcell/v2"
+    "sort"
+    "strings"
+)
+
+// markAction is the action of the key following the set mark or the jump to mark key
+type markAction int
+
+const (
+    markNone markAction = iota
+    markSet
+    markJump
+)
+
+// bookmarkMarker is drawn in the gutter of bookmarked events without marks
+const bookmarkMarker = '●'
+
+// storeMark is the bookmark and the marks of an event that is only in the spill file or the open file
+type storeMark struct {
+    bookmarked bool
+    marks      string
+}
+
+// ToggleBookmark bookmarks the current event, or removes its bookmark. It returns true if the event is bookmarked.
+//
+// Bookmarks and marks are kept with events in memory and with events evicted to the spill file or lines of the open
+// file dropped from memory. They are removed when the event is evicted without a spill file.
+func (lv *LogView) ToggleBookmark() bool {
+    lv.Lock()
+    defer lv.Unlock()
+
+    if lv.current == nil {
+        return false
+    }
+    lv.setBookmark(lv.current, !lv.current.bookmarked)
+    return lv.current.bookmarked
+}
+
+// SetBookmark bookmarks the event with the id, or removes its bookmark. It returns false if there is no such event
+// in memory.
+func (lv *LogView) SetBookmark(eventID string, bookmarked bool) bool {
+    lv.Lock()
+    defer lv.Unlock()
+
+    event := lv.findByEventId(eventID)
+    if event == nil || eventID == "" {
+        return false
+    }
+    lv.setBookmark(event, bookmarked)
+    return true
+}
+
+// IsBookmarked checks whether the event with the id is bookmarked
+func (lv *LogView) IsBookmarked(eventID string) bool {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    event := lv.findByEventId(eventID)
+    return event != nil && eventID != "" && event.bookmarked
+}
+
+// GetBookmarks returns the bookmarked events, oldest first
+func (lv *LogView) GetBookmarks() []*LogEvent {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    events := make([]*LogEvent, 0)
+    for _, marked := range lv.findMarked(func(bookmarked bool, _ string) bool { return bookmarked }) {
+        if event := lv.markedEvent(marked); event != nil {
+            events = append(events, event)
+        }
+    }
+    return events
+}
+
+// ScrollToNextBookmark scrolls to the next displayed bookmarked event after the current one. It returns false if
+// there is none.
+func (lv *LogView) ScrollToNextBookmark() bool {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    return lv.scrollToBookmark(false)
+}
+
+// ScrollToPreviousBookmark scrolls to the previous displayed bookmarked event before the current one. It returns
+// false if there is none.
+func (lv *LogView) ScrollToPreviousBookmark() bool {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    return lv.scrollToBookmark(true)
+}
+
+// SetMark sets the mark with the name, a letter, on the current event. A mark is set on one event at a time, it is
+// moved from the event it was set on before.
+func (lv *LogView) SetMark(name rune) error {
+    lv.Lock()
+    defer lv.Unlock()
+
+    if !isMarkName(name) {
+        return fmt.Errorf("invalid mark %q, marks are named by letters", name)
+    }
+    if lv.current == nil {
+        return fmt.Errorf("no event to mark")
+    }
+    lv.setMark(lv.current, name)
+    return nil
+}
+
+// ScrollToMark scrolls to the event with the mark. It returns false if the mark is not set or its event is not
+// displayed.
+func (lv *LogView) ScrollToMark(name rune) bool {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    return lv.scrollToMark(name)
+}
+
+// GetMarks returns the events with marks by the names of the marks
+func (lv *LogView) GetMarks() map[rune]*LogEvent {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    marks := make(map[rune]*LogEvent)
+    for _, marked := range lv.findMarked(func(_ bool, marks string) bool { return marks != "" }) {
+        if event := lv.markedEvent(marked); event != nil {
+            for _, name := range marked.marks {
+                marks[name] = event
+            }
+        }
+    }
+    return marks
+}
+
+// ClearBookmarks removes all bookmarks and marks
+func (lv *LogView) ClearBookmarks() {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.dropStoreMarks()
+    for event := lv.firstEvent; event != nil && lv.markedEvents > 0; event = event.next {
+        if event.order <= 1 {
+            lv.updateMarks(event, false, "")
+        }
+    }
+}
+
+// IsMarkPending returns true if the next key names a mark to set or to jump to
+func (lv *LogView) IsMarkPending() bool {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.pendingMark != markNone
+}
+
+// SetBookmarkStyle sets the style of the bookmark and mark markers drawn in the gutter
+func (lv *LogView) SetBookmarkStyle(style tcell.Style) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.bookmarkStyle = style
+}
+
+// *******************************
+// internal implementation details
+
+func isMarkName(name rune) bool {
+    return name >= 'a' && name <= 'z' || name >= 'A' && name <= 'Z'
+}
+
+// handleMarkKey sets or jumps to the mark named by the key following the set mark or jump to mark key, any other
+// key cancels
+func (lv *LogView) handleMarkKey(event *tcell.EventKey) {
+    action := lv.pendingMark
+    lv.pendingMark = markNone
+    if event.Key() != tcell.KeyRune || !isMarkName(event.Rune()) {
+        return
+    }
+    if action == markSet && lv.current != nil {
+        lv.setMark(lv.current, event.Rune())
+    } else if action == markJump {
+        lv.scrollToMark(event.Rune())
+    }
+}
+
+func (lv *LogView) setBookmark(event *logEventLine, bookmarked bool) {
+    event = findFirstWrappedLine(event)
+    lv.updateMarks(event, bookmarked, event.marks)
+}
+
+func (lv *LogView) setMark(event *logEventLine, name rune) {
+    for index, mark := range lv.storeMarks {
+        if strings.ContainsRune(mark.marks, name) {
+            lv.updateStoreMark(index, mark.bookmarked, strings.ReplaceAll(mark.marks, string(name), ""))
+        }
+    }
+    for e := lv.firstEvent; e != nil; e = e.next {
+        if e.order <= 1 && strings.ContainsRune(e.marks, name) {
+            lv.updateMarks(e, e.bookmarked, strings.ReplaceAll(e.marks, string(name), ""))
+        }
+    }
+    event = findFirstWrappedLine(event)
+    marks := []rune(event.marks + string(name))
+    sort.Slice(marks, func(i, j int) bool { return marks[i] < marks[j] })
+    lv.updateMarks(event, event.bookmarked, string(marks))
+}
+
+// updateMarks sets the bookmark and the marks of the event, counting marked events
+func (lv *LogView) updateMarks(event *logEventLine, bookmarked bool, marks string) {
+    wasMarked := event.bookmarked || event.marks != ""
+    event.bookmarked = bookmarked
+    event.marks = marks
+    if marked := bookmarked || marks != ""; marked != wasMarked {
+        if marked {
+            lv.updateMarkedEvents(1)
+        } else {
+            lv.updateMarkedEvents(-1)
+        }
+    }
+}
+
+// updateMarkedEvents changes the number of marked events, lines are wrapped again when the gutter is shown or hidden
+func (lv *LogView) updateMarkedEvents(delta int) {
+    hadGutter := lv.markedEvents > 0
+    lv.markedEvents += delta
+    if hadGutter != (lv.markedEvents > 0) {
+        lv.forceWrap = true
+    }
+}
+
+// keepStoreMarks moves the bookmark and the marks of the event leaving memory to the position of the event in the
+// store, the event stays counted as marked
+func (lv *LogView) keepStoreMarks(event *logEventLine, index int) {
+    if !event.bookmarked && event.marks == "" {
+        return
+    }
+    lv.storeMarks[index] = storeMark{bookmarked: event.bookmarked, marks: event.marks}
+    event.bookmarked = false
+    event.marks = ""
+}
+
+// updateStoreMark sets the bookmark and the marks of the event at the position in the store, counting marked events
+func (lv *LogView) updateStoreMark(index int, bookmarked bool, marks string) {
+    if bookmarked || marks != "" {
+        lv.storeMarks[index] = storeMark{bookmarked: bookmarked, marks: marks}
+        return
+    }
+    delete(lv.storeMarks, index)
+    lv.updateMarkedEvents(-1)
+}
+
+// dropStoreMarks removes the bookmarks and marks of events that are only in the store, when the store is closed
+func (lv *LogView) dropStoreMarks() {
+    if len(lv.storeMarks) > 0 {
+        lv.updateMarkedEvents(-len(lv.storeMarks))
+        lv.storeMarks = make(map[int]storeMark)
+    }
+}
+
+// markedPosition is the position of an event with a bookmark or marks, line is nil if the event is only in the store
+type markedPosition struct {
+    index      int
+    line       *logEventLine
+    bookmarked bool
+    marks      string
+}
+
+// findMarked returns the positions of the events, in memory or only in the store, whose bookmark and marks match,
+// in order
+func (lv *LogView) findMarked(match func(bookmarked bool, marks string) bool) []markedPosition {
+    found := make([]markedPosition, 0)
+    for index, mark := range lv.storeMarks {
+        if match(mark.bookmarked, mark.marks) {
+            found = append(found, markedPosition{index: index, bookmarked: mark.bookmarked, marks: mark.marks})
+        }
+    }
+    index := lv.storeCount()
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.order > 1 {
+            continue
+        }
+        if match(event.bookmarked, event.marks) {
+            position := markedPosition{index: index, line: event, bookmarked: event.bookmarked, marks: event.marks}
+            if event.fromSpill {
+                position.index = event.spillIndex
+            }
+            found = append(found, position)
+        }
+        if !event.fromSpill {
+            index++
+        }
+    }
+    sort.Slice(found, func(i, j int) bool { return found[i].index < found[j].index })
+    return found
+}
+
+// markedEvent returns the marked event, reading it from the store if it is not in memory
+func (lv *LogView) markedEvent(marked markedPosition) *LogEvent {
+    if marked.line != nil {
+        return marked.line.AsLogEvent()
+    }
+    store := lv.store()
+    if store == nil {
+        return nil
+    }
+    records, err := store.page(marked.index, marked.index+1)
+    if err != nil || len(records) == 0 {
+        return nil
+    }
+    return records[0].logEvent()
+}
+
+// scrollToBookmark scrolls to the nearest displayed bookmarked event after or before the current one, paging it in
+// if needed
+func (lv *LogView) scrollToBookmark(backward bool) bool {
+    if lv.current == nil {
+        return false
+    }
+    current := lv.indexOf(lv.current)
+    bookmarks := lv.findMarked(func(bookmarked bool, _ string) bool { return bookmarked })
+    for i := range bookmarks {
+        index := bookmarks[i].index
+        if backward {
+            index = bookmarks[len(bookmarks)-1-i].index
+        }
+        if backward && index >= current || !backward && index <= current {
+            continue
+        }
+        if event := lv.eventAt(index); event != nil && event.bookmarked && lv.isVisible(event) {
+            lv.moveTo(event)
+            return true
+        }
+    }
+    return false
+}
+
+// scrollToMark scrolls to the event with the mark, paging it in if needed
+func (lv *LogView) scrollToMark(name rune) bool {
+    marked := lv.findMarked(func(_ bool, marks string) bool { return strings.ContainsRune(marks, name) })
+    if len(marked) == 0 {
+        return false
+    }
+    event := lv.eventAt(marked[0].index)
+    if event == nil || !lv.isVisible(event) {
+        return false
+    }
+    lv.moveTo(event)
+    return true
+}
+
+// gutterWidth returns the width of the gutter with bookmark and mark markers, 0 if there are no marked events
+func (lv *LogView) gutterWidth() int {
+    if lv.markedEvents > 0 {
+        return 2
+    }
+    return 0
+}
+
+// printGutter prints the marker of the first line of a marked event: the first of its marks, or a bullet if it is
+// only bookmarked
+func (lv *LogView) printGutter(screen tcell.Screen, x int, y int, event *logEventLine) int {
+    if event.order <= 1 && (event.bookmarked || event.marks != "") {
+        screen.SetContent(x, y, gutterMarker(event.bookmarked, event.marks), nil, lv.bookmarkStyle)
+    }
+    return x + lv.gutterWidth()
+}
+
+// gutterMarker returns the marker of an event with the bookmark and the marks, a space if it has neither
+func gutterMarker(bookmarked bool, marks string) rune {
+    if marks != "" {
+        return []rune(marks)[0]
+    }
+    if bookmarked {
+        return bookmarkMarker
+    }
+    return ' '
+}
@@ -0,0 +1,175 @@
+package clogviewr
+
+import (
+    "bytes"
+    "encoding/json"
+    "github.com/gdamore/tcell/v2"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestLogView_Bookmarks(t *testing.T) {
+    lv := NewLogView()
+    lv.SetHighlightCurrentEvent(true)
+    lv.AppendEvents(randomEvents(10, time.Now()))
+
+    lv.ScrollToEventID("e2")
+    if !lv.ToggleBookmark() || !lv.IsBookmarked("e2") {
+        t.Fatalf("Expected e2 to be bookmarked")
+    }
+    lv.SetBookmark("e7", true)
+    lv.ScrollToEventID("e4")
+    if err := lv.SetMark('a'); err != nil {
+        t.Fatal(err)
+    }
+    if err := lv.SetMark('1'); err == nil {
+        t.Errorf("Expected marks to be named by letters")
+    }
+
+    if !lv.ScrollToNextBookmark() || lv.GetCurrentEvent().EventID != "e7" {
+        t.Errorf("Expected next bookmark e7, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if lv.ScrollToNextBookmark() {
+        t.Errorf("Expected no bookmark after e7")
+    }
+    if !lv.ScrollToPreviousBookmark() || lv.GetCurrentEvent().EventID != "e2" {
+        t.Errorf("Expected previous bookmark e2, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if !lv.ScrollToMark('a') || lv.GetCurrentEvent().EventID != "e4" {
+        t.Errorf("Expected mark a on e4, got %s", lv.GetCurrentEvent().EventID)
+    }
+    lv.ScrollToEventID("e5")
+    _ = lv.SetMark('a')
+    if marks := lv.GetMarks(); len(marks) != 1 || marks['a'].EventID != "e5" {
+        t.Errorf("Expected mark a to move to e5, got %v", marks)
+    }
+
+    // marks are kept with the event when lines are wrapped again
+    lv.SetLineWrap(true)
+    lv.pageWidth = 3
+    lv.rewrapLines()
+    if bookmarks := lv.GetBookmarks(); len(bookmarks) != 2 || bookmarks[0].EventID != "e2" || bookmarks[1].EventID != "e7" {
+        t.Errorf("Expected bookmarks to survive wrapping, got %d", len(bookmarks))
+    }
+    if !lv.ScrollToMark('a') || lv.GetCurrentEvent().EventID != "e5" {
+        t.Errorf("Expected mark a to survive wrapping")
+    }
+
+    lv.SetMaxEvents(5)
+    lv.AppendEvent(NewLogEvent("e10", "Event #10"))
+    if bookmarks := lv.GetBookmarks(); len(bookmarks) != 1 || bookmarks[0].EventID != "e7" || lv.markedEvents != 1 {
+        t.Errorf("Expected the bookmark of e2 and the mark of e5 to be evicted, got %d bookmarks and %d marked events", len(bookmarks), lv.markedEvents)
+    }
+
+    lv.ClearBookmarks()
+    if len(lv.GetBookmarks()) != 0 || len(lv.GetMarks()) != 0 || lv.gutterWidth() != 0 {
+        t.Errorf("Expected no bookmarks and marks")
+    }
+}
+
+func TestLogView_BookmarkGutter(t *testing.T) {
+    screen := tcell.NewSimulationScreen("UTF-8")
+    screen.Init()
+    screen.SetSize(20, 2)
+
+    lv := NewLogView()
+    lv.AppendEvents(randomEvents(3, time.Now()))
+    lv.SetBookmark("e0", true)
+    lv.ScrollToEventID("e1")
+    _ = lv.SetMark('q')
+
+    y := 0
+    for event := lv.firstEvent; event != nil && y < 2; event = event.next {
+        lv.drawEvent(screen, 0, y, event)
+        y++
+    }
+    for y, expected := range []string{"● Event #0", "q Event #1"} {
+        text := strings.Builder{}
+        for x := 0; x < len([]rune(expected)); x++ {
+            r, _, _, _ := screen.GetContent(x, y)
+            text.WriteRune(r)
+        }
+        if text.String() != expected {
+            t.Errorf("Expected line %d to be %q, got %q", y, expected, text.String())
+        }
+    }
+
+    var buf bytes.Buffer
+    if err := lv.Export(&buf, ExportJSON, ExportAll); err != nil {
+        t.Fatal(err)
+    }
+    var record jsonExportRecord
+    _ = json.Unmarshal([]byte(strings.Split(buf.String(), "\n")[1]), &record)
+    if record.EventID != "e1" || record.Marks != "q" || record.Bookmark {
+        t.Errorf("Expected mark q to be exported with e1, got %+v", record)
+    }
+    buf.Reset()
+    _ = lv.Export(&buf, ExportText, ExportAll)
+    if lines := strings.Split(buf.String(), "\n"); lines[0] != "● Event #0" || lines[2] != "  Event #2" {
+        t.Errorf("Expected the gutter in text export, got %q", buf.String())
+    }
+}
+
+func TestLogView_SpillBookmarks(t *testing.T) {
+    lv := newSpillingLogView(t, 30)
+    defer lv.SetSpillFile("")
+    lv.SetBookmark("e25", true)
+    lv.SetBookmark("e27", true)
+    lv.ScrollToEventID("e28")
+    _ = lv.SetMark('a')
+    lv.SetFollowing(true)
+    lv.AppendEvents(randomEvents(100, time.Now())[40:])
+    if lv.findByEventId("e28") != nil || lv.markedEvents != 3 {
+        t.Fatalf("Expected marked events to be evicted to the spill file and stay counted, %d marked", lv.markedEvents)
+    }
+
+    if bookmarks := lv.GetBookmarks(); len(bookmarks) != 2 || bookmarks[0].EventID != "e25" || bookmarks[1].EventID != "e27" {
+        t.Errorf("Expected spilled bookmarks e25 and e27, got %d", len(bookmarks))
+    }
+    if marks := lv.GetMarks(); marks['a'] == nil || marks['a'].EventID != "e28" {
+        t.Errorf("Expected spilled mark a on e28, got %v", marks)
+    }
+    if !lv.ScrollToMark('a') || lv.GetCurrentEvent().EventID != "e28" {
+        t.Fatalf("Expected the spilled event with mark a to be paged in")
+    }
+    if !lv.ScrollToPreviousBookmark() || lv.GetCurrentEvent().EventID != "e27" || !lv.ScrollToPreviousBookmark() || lv.GetCurrentEvent().EventID != "e25" {
+        t.Errorf("Expected previous bookmarks e27 and e25, got %s", lv.GetCurrentEvent().EventID)
+    }
+    lv.ScrollToBottom()
+    if !lv.ScrollToPreviousBookmark() || lv.GetCurrentEvent().EventID != "e27" {
+        t.Errorf("Expected the last bookmark e27 before the events in memory, got %s", lv.GetCurrentEvent().EventID)
+    }
+
+    // marks set on paged in events go to the spill file with them again
+    lv.ScrollToEventID("e3")
+    _ = lv.SetMark('a')
+    lv.ScrollToBottom()
+    lv.SetFollowing(true)
+    lv.AppendEvents(randomEvents(200, time.Now())[100:])
+    if marks := lv.GetMarks(); len(marks) != 1 || marks['a'].EventID != "e3" || lv.markedEvents != 3 {
+        t.Errorf("Expected mark a to move to e3, got %v with %d marked", marks, lv.markedEvents)
+    }
+
+    var buf bytes.Buffer
+    if err := lv.SaveSession(&buf); err != nil {
+        t.Fatal(err)
+    }
+    restored := NewLogView()
+    if err := restored.SetSpillFile(filepath.Join(t.TempDir(), "restored.log")); err != nil {
+        t.Fatal(err)
+    }
+    defer restored.SetSpillFile("")
+    if err := restored.LoadSession(&buf); err != nil {
+        t.Fatal(err)
+    }
+    if bookmarks := restored.GetBookmarks(); len(bookmarks) != 2 || bookmarks[1].EventID != "e27" || restored.GetMarks()['a'].EventID != "e3" {
+        t.Errorf("Expected spilled bookmarks and marks to be restored from the session")
+    }
+
+    lv.ClearBookmarks()
+    if len(lv.GetBookmarks()) != 0 || len(lv.GetMarks()) != 0 || lv.gutterWidth() != 0 {
+        t.Errorf("Expected no bookmarks and marks")
+    }
+}
@@ -0,0 +1,141 @@
+package clogviewr
+
+import (
+    "encoding/base64"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "sync"
+)
+
+// SetClipboardWriter sets the writer copy actions send OSC 52 sequences to. Sequences must reach the terminal the
+// application runs in between screen updates, UI sets a TerminalClipboard flushed before each draw. Copying fails
+// if no writer is set.
+func (lv *LogView) SetClipboardWriter(w io.Writer) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.clipboard = w
+}
+
+// CopyCurrentMessage copies the message of the current event to the terminal clipboard.
+// Wrapped and concatenated lines are copied as a single message, without timestamp and source.
+func (lv *LogView) CopyCurrentMessage() error {
+    return lv.copy(lv.currentMessageText)
+}
+
+// CopyCurrentEvent copies the current event to the terminal clipboard with its source and timestamp,
+// if they are displayed
+func (lv *LogView) CopyCurrentEvent() error {
+    return lv.copy(lv.currentEventText)
+}
+
+// CopySelection copies selected events to the terminal clipboard, one event per line, with their source and timestamp,
+// if they are displayed
+func (lv *LogView) CopySelection() error {
+    return lv.copy(lv.selectionText)
+}
+
+// TerminalClipboard queues OSC 52 sequences written by copy actions until Flush writes them to the terminal, so that
+// they do not interleave with screen updates. Terminals that do not support OSC 52 ignore them.
+type TerminalClipboard struct {
+    tty     io.Writer
+    pending []byte
+
+    sync.Mutex
+}
+
+// NewTerminalClipboard creates a clipboard flushed to the writer, or to the controlling terminal if it is nil
+func NewTerminalClipboard(tty io.Writer) *TerminalClipboard {
+    return &TerminalClipboard{tty: tty}
+}
+
+// Write queues the sequences until the next Flush
+func (c *TerminalClipboard) Write(p []byte) (int, error) {
+    c.Lock()
+    defer c.Unlock()
+
+    c.pending = append(c.pending, p...)
+    return len(p), nil
+}
+
+// Flush writes the queued sequences to the terminal, it is called between screen updates
+func (c *TerminalClipboard) Flush() error {
+    c.Lock()
+    defer c.Unlock()
+
+    if len(c.pending) == 0 {
+        return nil
+    }
+    if c.tty == nil {
+        // the terminal tcell draws to, stdout if there is none
+        if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+            c.tty = tty
+        } else {
+            c.tty = os.Stdout
+        }
+    }
+    _, err := c.tty.Write(c.pending)
+    c.pending = c.pending[:0]
+    return err
+}
+
+// *******************************
+// internal implementation details
+
+// copy gets the text under the lock and writes it to the clipboard writer once the lock is released
+func (lv *LogView) copy(text func() (string, error)) error {
+    lv.Lock()
+    s, err := text()
+    w := lv.clipboard
+    lv.Unlock()
+
+    if err != nil {
+        return err
+    }
+    return copyToClipboard(w, s)
+}
+
+func (lv *LogView) currentMessageText() (string, error) {
+    if lv.current == nil {
+        return "", fmt.Errorf("no current event")
+    }
+    return lv.current.AsLogEvent().Message, nil
+}
+
+func (lv *LogView) currentEventText() (string, error) {
+    if lv.current == nil {
+        return "", fmt.Errorf("no current event")
+    }
+    return lv.eventsText([]*LogEvent{lv.current.AsLogEvent()})
+}
+
+func (lv *LogView) selectionText() (string, error) {
+    if lv.selectionAnchor == nil {
+        return "", fmt.Errorf("nothing is selected")
+    }
+    return lv.eventsText(lv.selectedEvents())
+}
+
+// eventsText returns events in plain text export format, without bookmarks and marks
+func (lv *LogView) eventsText(events []*LogEvent) (string, error) {
+    exported := &exportedEvents{events: make([]exportedEvent, len(events))}
+    for i, event := range events {
+        exported.events[i] = exportedEvent{LogEvent: event}
+    }
+    text := &strings.Builder{}
+    if err := lv.exportText(text, exported, false); err != nil {
+        return "", err
+    }
+    return strings.TrimSuffix(text.String(), "\n"), nil
+}
+
+// copyToClipboard writes the text to the system clipboard with OSC 52 escape sequence
+func copyToClipboard(w io.Writer, text string) error {
+    if w == nil {
+        return fmt.Errorf("clipboard is not available")
+    }
+    _, err := fmt.Fprintf(w, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+    return err
+}
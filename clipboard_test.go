@@ -0,0 +1,81 @@
+package clogviewr
+
+import (
+    "bytes"
+    "encoding/base64"
+    "strings"
+    "testing"
+    "time"
+)
+
+func decodeOSC52(t *testing.T, seq string) string {
+    if !strings.HasPrefix(seq, "\x1b]52;c;") || !strings.HasSuffix(seq, "\a") {
+        t.Fatalf("Invalid OSC 52 sequence %q", seq)
+    }
+    text, err := base64.StdEncoding.DecodeString(seq[len("\x1b]52;c;") : len(seq)-1])
+    if err != nil {
+        t.Fatalf("Invalid OSC 52 payload: %v", err)
+    }
+    return string(text)
+}
+
+func TestLogView_Copy(t *testing.T) {
+    lv := NewLogView()
+    var buf bytes.Buffer
+    lv.SetClipboardWriter(&buf)
+    lv.SetShowSource(false)
+    lv.SetShowTimestamp(true)
+    lv.SetTimestampFormat("15:04:05")
+    ts := time.Date(2021, 03, 01, 10, 0, 0, 0, time.Local)
+    lv.AppendEvents(randomEvents(5, ts))
+    lv.ScrollToEventID("e1")
+
+    if err := lv.CopyCurrentMessage(); err != nil {
+        t.Fatalf("Failed to copy: %v", err)
+    }
+    if text := decodeOSC52(t, buf.String()); text != lv.GetCurrentEvent().Message {
+        t.Errorf("Expected current message to be copied, got %q", text)
+    }
+
+    buf.Reset()
+    _ = lv.CopyCurrentEvent()
+    if text := decodeOSC52(t, buf.String()); text != "10:00:01 "+lv.GetCurrentEvent().Message {
+        t.Errorf("Expected current event with timestamp to be copied, got %q", text)
+    }
+
+    buf.Reset()
+    if err := lv.CopySelection(); err == nil {
+        t.Errorf("Copying without selection must fail")
+    }
+    lv.StartSelection()
+    lv.ScrollToEventID("e3")
+    _ = lv.CopySelection()
+    if lines := strings.Split(decodeOSC52(t, buf.String()), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[2], "10:00:03 ") {
+        t.Errorf("Expected 3 selected events to be copied, got %q", lines)
+    }
+}
+
+func TestTerminalClipboard(t *testing.T) {
+    var tty bytes.Buffer
+    clipboard := NewTerminalClipboard(&tty)
+    lv := NewLogView()
+    if err := lv.CopyCurrentMessage(); err == nil {
+        t.Errorf("Copying without a clipboard writer must fail")
+    }
+    lv.SetClipboardWriter(clipboard)
+    lv.AppendEvents(randomEvents(2, time.Now()))
+
+    _ = lv.CopyCurrentMessage()
+    if tty.Len() != 0 {
+        t.Errorf("Copied text must wait for the next flush")
+    }
+    _ = clipboard.Flush()
+    if text := decodeOSC52(t, tty.String()); text != "Event #1" {
+        t.Errorf("Expected the current message to be flushed, got %q", text)
+    }
+    tty.Reset()
+    _ = clipboard.Flush()
+    if tty.Len() != 0 {
+        t.Errorf("Flushed sequences must not be written again")
+    }
+}
@@ -3,6 +3,7 @@ package clogviewr
 import (
     "fmt"
     "github.com/gdamore/tcell/v2"
+    "regexp"
     "strings"
     "time"
 )
@@ -23,6 +24,22 @@ const (
     LogLevelAll
 )
 
+// String returns the name of the log level
+func (l LogLevel) String() string {
+    switch l {
+    case LogLevelInfo:
+        return "info"
+    case LogLevelWarning:
+        return "warning"
+    case LogLevelError:
+        return "error"
+    case LogLevelAll:
+        return "all"
+    default:
+        return fmt.Sprintf("level(%d)", uint(l))
+    }
+}
+
 // LogEvent that can be added to LogView.
 // Contains following fields:
 //
@@ -55,6 +72,17 @@ func NewLogEvent(eventID string, message string) *LogEvent {
     }
 }
 
+var (
+    uuidPattern   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+    numberPattern = regexp.MustCompile(`\d+`)
+)
+
+// maskVariables replaces UUIDs and numbers in the message with placeholders, so messages that differ only
+// in ids, counters or durations are equal
+func maskVariables(message string) string {
+    return numberPattern.ReplaceAllString(uuidPattern.ReplaceAllString(message, "<uuid>"), "<n>")
+}
+
 // printString is the most dump printing function. It just prints the string starting at x,y with
 // a given style. No checks whatsoever are performed
 func printString(screen tcell.Screen, x int, y int, text string, style tcell.Style) {
@@ -82,3 +110,26 @@ func minInt(a, b int) int {
         return b
     }
 }
+
+func maxInt(a, b int) int {
+    if a > b {
+        return a
+    } else {
+        return b
+    }
+}
+
+func absDuration(d time.Duration) time.Duration {
+    if d < 0 {
+        return -d
+    }
+    return d
+}
+
+func maxInt64(a, b int64) int64 {
+    if a > b {
+        return a
+    } else {
+        return b
+    }
+}
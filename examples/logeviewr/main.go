@@ -141,7 +141,18 @@ func main() {
             ui.HandleSearch(s[1:])
             return
         }
+        if strings.HasPrefix(s, "?") {
+            ui.HandleSearchBackward(s[1:])
+            return
+        }
+        if strings.HasPrefix(s, "&") {
+            ui.HandleFilter(s[1:])
+            return
+        }
         if strings.HasPrefix(s, ":") {
+            if ui.HandleCommand(s[1:]) {
+                return
+            }
             ui.HandleGotoLine(s[1:])
             return
         }
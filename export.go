@@ -0,0 +1,413 @@
+package clogviewr
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "github.com/gdamore/tcell/v2"
+    "html"
+    "io"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// ExportFormat defines the format events are exported in
+type ExportFormat int
+
+const (
+    // ExportText exports events as plain text, with timestamp and source if they are displayed
+    ExportText = ExportFormat(iota)
+    // ExportJSON exports events as JSON lines with all event fields
+    ExportJSON
+    // ExportCSV exports events as CSV with a header row
+    ExportCSV
+    // ExportANSI exports events as text coloured with ANSI escape sequences
+    ExportANSI
+    // ExportHTML exports events as a standalone HTML document
+    ExportHTML
+)
+
+var exportFormatNames = map[string]ExportFormat{
+    "text": ExportText,
+    "json": ExportJSON,
+    "csv":  ExportCSV,
+    "ansi": ExportANSI,
+    "html": ExportHTML,
+}
+
+// ParseExportFormat returns the export format by its name: text, json, csv, ansi or html
+func ParseExportFormat(name string) (ExportFormat, error) {
+    if format, ok := exportFormatNames[strings.ToLower(name)]; ok {
+        return format, nil
+    }
+    return ExportText, fmt.Errorf("unknown export format: %s", name)
+}
+
+// ExportScope defines which events are exported
+type ExportScope int
+
+const (
+    // ExportAll exports all events, including spilled ones or all the lines of the open file, and events hidden by
+    // filters
+    ExportAll = ExportScope(iota)
+    // ExportSelection exports selected events
+    ExportSelection
+    // ExportFiltered exports the events displayed with the current filters, including spilled ones or the lines of
+    // the open file
+    ExportFiltered
+)
+
+// jsonExportRecord is a single line of JSON lines export
+type jsonExportRecord struct {
+    EventID   string      `json:"id"`
+    Source    string      `json:"source"`
+    Timestamp time.Time   `json:"timestamp"`
+    Level     string      `json:"level"`
+    Message   string      `json:"message"`
+    Data      interface{} `json:"data,omitempty"`
+    Bookmark  bool        `json:"bookmark,omitempty"`
+    Marks     string      `json:"marks,omitempty"`
+}
+
+// exportedEvent is an event with its bookmark and marks
+type exportedEvent struct {
+    *LogEvent
+    bookmarked bool
+    marks      string
+}
+
+// exportedEvents is a snapshot of the events to export taken while holding the lock. Events of the store are read
+// when the events are written, lines of the open file without holding the lock.
+type exportedEvents struct {
+    // settings is a copy of the settings of the log view the events are formatted with
+    settings *LogView
+    // store events with index lower than storeCount and matching the predicate are exported before the events
+    // in memory
+    store      pagedStore
+    storeCount int
+    predicate  func(event *LogEvent) bool
+    storeMarks map[int]markedPosition
+    events     []exportedEvent
+    // text exports have a gutter with the markers of the events if there are marked events, like the log view
+    marked bool
+}
+
+// Export writes events in the given format. Coloured formats use the highlighting of the log view.
+// Lines of the open file are streamed to the writer without holding the lock, so that the log view can be drawn and
+// appended to meanwhile.
+func (lv *LogView) Export(w io.Writer, format ExportFormat, scope ExportScope) error {
+    lv.Lock()
+    events, err := lv.exportEvents(scope)
+    if lv.file == nil {
+        // spilled events can only be read while holding the lock
+        defer lv.Unlock()
+    } else {
+        lv.Unlock()
+    }
+    if err != nil {
+        return err
+    }
+
+    out := bufio.NewWriter(w)
+    switch format {
+    case ExportText:
+        err = events.settings.exportText(out, events, false)
+    case ExportANSI:
+        err = events.settings.exportText(out, events, true)
+    case ExportJSON:
+        err = exportJSON(out, events)
+    case ExportCSV:
+        err = exportCSV(out, events)
+    case ExportHTML:
+        err = events.settings.exportHTML(out, events)
+    default:
+        err = fmt.Errorf("unknown export format: %d", format)
+    }
+    if err != nil {
+        return err
+    }
+    return out.Flush()
+}
+
+// *******************************
+// internal implementation details
+
+func (lv *LogView) exportEvents(scope ExportScope) (*exportedEvents, error) {
+    events := &exportedEvents{settings: lv.exportSettings(), marked: lv.markedEvents > 0}
+    switch scope {
+    case ExportAll, ExportFiltered:
+        filtered := scope == ExportFiltered
+        if store := lv.store(); store != nil {
+            events.store, events.storeCount = store, store.count()
+            events.predicate = func(_ *LogEvent) bool { return true }
+            if filtered {
+                events.predicate = lv.visiblePredicate(events.predicate)
+            }
+            events.storeMarks = make(map[int]markedPosition)
+            for _, marked := range lv.findMarked(func(bookmarked bool, marks string) bool { return bookmarked || marks != "" }) {
+                if marked.line == nil || marked.line.fromSpill {
+                    events.storeMarks[marked.index] = marked
+                }
+            }
+        }
+        for event := lv.firstEvent; event != nil; event = event.next {
+            if event.order <= 1 && !event.fromSpill && (!filtered || lv.isVisible(event)) {
+                events.events = append(events.events, exportedEvent{LogEvent: event.AsLogEvent(), bookmarked: event.bookmarked, marks: event.marks})
+            }
+        }
+        return events, nil
+    case ExportSelection:
+        first, last, _ := lv.selectionBounds()
+        for event := first; event != nil; event = event.next {
+            if event.order <= 1 && lv.isVisible(event) {
+                events.events = append(events.events, exportedEvent{LogEvent: event.AsLogEvent(), bookmarked: event.bookmarked, marks: event.marks})
+            }
+            if event == last {
+                break
+            }
+        }
+        return events, nil
+    }
+    return nil, fmt.Errorf("unknown export scope: %d", scope)
+}
+
+// exportSettings returns a copy of the settings events are formatted with, so that they can be formatted without
+// holding the lock
+func (lv *LogView) exportSettings() *LogView {
+    return &LogView{
+        highlightingEnabled: lv.highlightingEnabled,
+        highlightPattern:    lv.highlightPattern,
+        highlightRules:      append([]HighlightRule(nil), lv.highlightRules...),
+        highlightLevels:     lv.highlightLevels,
+        warningBgColor:      lv.warningBgColor,
+        errorBgColor:        lv.errorBgColor,
+        bookmarkStyle:       lv.bookmarkStyle,
+        sourceStyle:         lv.sourceStyle,
+        timestampStyle:      lv.timestampStyle,
+        showSource:          lv.showSource,
+        showTimestamp:       lv.showTimestamp,
+        timestampFormat:     lv.timestampFormat,
+        defaultStyle:        lv.defaultStyle,
+    }
+}
+
+// forEach calls f for every exported event in order until f returns an error
+func (e *exportedEvents) forEach(f func(event exportedEvent) error) error {
+    if e.store != nil {
+        var err error
+        scanErr := e.store.scan(0, e.storeCount, func(index int, event *LogEvent) bool {
+            if e.predicate(event) {
+                marked := e.storeMarks[index]
+                err = f(exportedEvent{LogEvent: event, bookmarked: marked.bookmarked, marks: marked.marks})
+            }
+            return err == nil
+        })
+        if err != nil {
+            return err
+        }
+        if scanErr != nil {
+            return scanErr
+        }
+    }
+    for _, event := range e.events {
+        if err := f(event); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// gutter returns the marker of the event followed by a space
+func (e exportedEvent) gutter() string {
+    return string(gutterMarker(e.bookmarked, e.marks)) + " "
+}
+
+// header returns the source and timestamp of the event as they are displayed in the log view
+func (lv *LogView) header(event *LogEvent) (string, string) {
+    var source, ts string
+    if lv.showSource {
+        source = event.Source
+    }
+    if lv.showTimestamp {
+        ts = event.Timestamp.Format(lv.timestampFormat)
+    }
+    return source, ts
+}
+
+// spans calculates the highlighting of the whole event message
+func (lv *LogView) spans(event *LogEvent) []styledSpan {
+    line := lv.colorize(lv.newEventLine(event))
+    if line.styleSpans == nil {
+        return []styledSpan{{start: 0, end: len(event.Message), style: lv.baseStyle(line)}}
+    }
+    return line.styleSpans
+}
+
+func (lv *LogView) exportText(w io.StringWriter, events *exportedEvents, colored bool) error {
+    return events.forEach(func(event exportedEvent) error {
+        if events.marked {
+            marker := event.gutter()
+            if colored {
+                marker = ansiText(marker, lv.bookmarkStyle)
+            }
+            if _, err := w.WriteString(marker); err != nil {
+                return err
+            }
+        }
+        source, ts := lv.header(event.LogEvent)
+        if colored {
+            source = ansiText(source, lv.sourceStyle)
+            ts = ansiText(ts, lv.timestampStyle)
+        }
+        for _, h := range []string{source, ts} {
+            if h != "" {
+                if _, err := w.WriteString(h + " "); err != nil {
+                    return err
+                }
+            }
+        }
+        message := event.Message
+        if colored {
+            message = ""
+            for _, span := range lv.spans(event.LogEvent) {
+                message += ansiText(event.Message[span.start:span.end], span.style)
+            }
+        }
+        _, err := w.WriteString(message + "\n")
+        return err
+    })
+}
+
+func exportJSON(w io.Writer, events *exportedEvents) error {
+    encoder := json.NewEncoder(w)
+    return events.forEach(func(event exportedEvent) error {
+        return encoder.Encode(jsonExportRecord{
+            EventID:   event.EventID,
+            Source:    event.Source,
+            Timestamp: event.Timestamp,
+            Level:     event.Level.String(),
+            Message:   event.Message,
+            Data:      event.Data,
+            Bookmark:  event.bookmarked,
+            Marks:     event.marks,
+        })
+    })
+}
+
+func exportCSV(w io.Writer, events *exportedEvents) error {
+    writer := csv.NewWriter(w)
+    if err := writer.Write([]string{"id", "timestamp", "source", "level", "message", "data", "bookmark", "marks"}); err != nil {
+        return err
+    }
+    err := events.forEach(func(event exportedEvent) error {
+        data := ""
+        if event.Data != nil {
+            encoded, err := json.Marshal(event.Data)
+            if err != nil {
+                return err
+            }
+            data = string(encoded)
+        }
+        return writer.Write([]string{
+            event.EventID,
+            event.Timestamp.Format(time.RFC3339Nano),
+            event.Source,
+            event.Level.String(),
+            event.Message,
+            data,
+            strconv.FormatBool(event.bookmarked),
+            event.marks,
+        })
+    })
+    if err != nil {
+        return err
+    }
+    writer.Flush()
+    return writer.Error()
+}
+
+func (lv *LogView) exportHTML(w io.StringWriter, events *exportedEvents) error {
+    doc := &strings.Builder{}
+    doc.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Log events</title>\n</head>\n")
+    doc.WriteString(fmt.Sprintf("<body style=\"%s\">\n<pre>\n", cssStyle(lv.defaultStyle)))
+    if _, err := w.WriteString(doc.String()); err != nil {
+        return err
+    }
+    err := events.forEach(func(event exportedEvent) error {
+        doc.Reset()
+        if events.marked {
+            doc.WriteString(htmlSpan(event.gutter(), lv.bookmarkStyle))
+        }
+        source, ts := lv.header(event.LogEvent)
+        if source != "" {
+            doc.WriteString(htmlSpan(source, lv.sourceStyle) + " ")
+        }
+        if ts != "" {
+            doc.WriteString(htmlSpan(ts, lv.timestampStyle) + " ")
+        }
+        for _, span := range lv.spans(event.LogEvent) {
+            doc.WriteString(htmlSpan(event.Message[span.start:span.end], span.style))
+        }
+        doc.WriteString("\n")
+        _, err := w.WriteString(doc.String())
+        return err
+    })
+    if err != nil {
+        return err
+    }
+    _, err = w.WriteString("</pre>\n</body>\n</html>\n")
+    return err
+}
+
+// ansiText wraps text in ANSI escape sequences for 24-bit colours of the style
+func ansiText(text string, style tcell.Style) string {
+    if text == "" {
+        return text
+    }
+    fg, bg, attrs := style.Decompose()
+    codes := make([]string, 0)
+    if attrs&tcell.AttrBold != 0 {
+        codes = append(codes, "1")
+    }
+    if attrs&tcell.AttrUnderline != 0 {
+        codes = append(codes, "4")
+    }
+    if r, g, b := fg.RGB(); r >= 0 && fg != tcell.ColorDefault {
+        codes = append(codes, fmt.Sprintf("38;2;%d;%d;%d", r, g, b))
+    }
+    if r, g, b := bg.RGB(); r >= 0 && bg != tcell.ColorDefault {
+        codes = append(codes, fmt.Sprintf("48;2;%d;%d;%d", r, g, b))
+    }
+    if len(codes) == 0 {
+        return text
+    }
+    return "\x1b[" + strings.Join(codes, ";") + "m" + text + "\x1b[0m"
+}
+
+func cssStyle(style tcell.Style) string {
+    fg, bg, attrs := style.Decompose()
+    css := make([]string, 0)
+    if fg != tcell.ColorDefault && fg.Hex() >= 0 {
+        css = append(css, fmt.Sprintf("color:#%06x", fg.Hex()))
+    }
+    if bg != tcell.ColorDefault && bg.Hex() >= 0 {
+        css = append(css, fmt.Sprintf("background-color:#%06x", bg.Hex()))
+    }
+    if attrs&tcell.AttrBold != 0 {
+        css = append(css, "font-weight:bold")
+    }
+    if attrs&tcell.AttrUnderline != 0 {
+        css = append(css, "text-decoration:underline")
+    }
+    return strings.Join(css, ";")
+}
+
+func htmlSpan(text string, style tcell.Style) string {
+    css := cssStyle(style)
+    if css == "" {
+        return html.EscapeString(text)
+    }
+    return fmt.Sprintf("<span style=\"%s\">%s</span>", css, html.EscapeString(text))
+}
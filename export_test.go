@@ -0,0 +1,164 @@
+package clogviewr
+
+import (
+    "bytes"
+    "encoding/csv"
+    "encoding/json"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestLogView_ExportJSON(t *testing.T) {
+    lv := NewLogView()
+    events := randomEvents(10, time.Now())
+    events[3].Level = LogLevelError
+    events[3].Data = map[string]interface{}{"user": "alex"}
+    lv.AppendEvents(events)
+
+    var buf bytes.Buffer
+    if err := lv.Export(&buf, ExportJSON, ExportAll); err != nil {
+        t.Fatalf("Failed to export events: %v", err)
+    }
+    lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+    if len(lines) != 10 {
+        t.Fatalf("Expected 10 exported lines, got %d", len(lines))
+    }
+    var record jsonExportRecord
+    if err := json.Unmarshal([]byte(lines[3]), &record); err != nil {
+        t.Fatalf("Invalid JSON line: %v", err)
+    }
+    if record.EventID != "e3" || record.Level != "error" || record.Message != events[3].Message || record.Data == nil {
+        t.Errorf("Invalid exported event: %v", record)
+    }
+}
+
+func TestLogView_ExportSelectionCSV(t *testing.T) {
+    lv := NewLogView()
+    lv.AppendEvents(randomEvents(10, time.Now()))
+    lv.ScrollToEventID("e2")
+    lv.StartSelection()
+    lv.ScrollToEventID("e5")
+
+    if selection := lv.GetSelection(); len(selection) != 4 || selection[0].EventID != "e2" || selection[3].EventID != "e5" {
+        t.Fatalf("Expected events e2 to e5 to be selected, got %d events", len(selection))
+    }
+
+    var buf bytes.Buffer
+    if err := lv.Export(&buf, ExportCSV, ExportSelection); err != nil {
+        t.Fatalf("Failed to export events: %v", err)
+    }
+    records, err := csv.NewReader(&buf).ReadAll()
+    if err != nil {
+        t.Fatalf("Invalid CSV: %v", err)
+    }
+    if len(records) != 5 || records[0][0] != "id" || records[1][0] != "e2" || records[4][0] != "e5" {
+        t.Errorf("Invalid exported CSV: %v", records)
+    }
+
+    lv.ClearSelection()
+    if lv.HasSelection() || len(lv.GetSelection()) != 0 {
+        t.Errorf("Selection must be cleared")
+    }
+}
+
+func TestLogView_ExportColored(t *testing.T) {
+    lv := NewLogView()
+    lv.SetHighlightPattern(`(?P<red>Event) (?P<green>\d+)`)
+    lv.AppendEvent(NewLogEvent("1", "Event 1 <b>"))
+
+    var buf bytes.Buffer
+    if err := lv.Export(&buf, ExportANSI, ExportAll); err != nil {
+        t.Fatalf("Failed to export events: %v", err)
+    }
+    if !strings.Contains(buf.String(), "\x1b[38;2;255;0;0;48;2;0;0;0mEvent\x1b[0m") {
+        t.Errorf("Expected red ANSI highlighting, got %q", buf.String())
+    }
+
+    buf.Reset()
+    if err := lv.Export(&buf, ExportHTML, ExportAll); err != nil {
+        t.Fatalf("Failed to export events: %v", err)
+    }
+    if !strings.Contains(buf.String(), `<span style="color:#ff0000;background-color:#000000">Event</span>`) || !strings.Contains(buf.String(), "&lt;b&gt;") {
+        t.Errorf("Expected highlighted and escaped HTML, got %q", buf.String())
+    }
+}
+
+func TestParseExportFormat(t *testing.T) {
+    if format, err := ParseExportFormat("JSON"); err != nil || format != ExportJSON {
+        t.Errorf("Expected JSON format, got %v, %v", format, err)
+    }
+    if _, err := ParseExportFormat("xml"); err == nil {
+        t.Errorf("Unknown format must be rejected")
+    }
+}
+
+func TestLogView_ExportFiltered(t *testing.T) {
+    lv := newSpillingLogView(t, 100)
+    defer lv.SetSpillFile("")
+    lv.PushFilter(EventFilter{Name: "5", Predicate: func(event *LogEvent) bool {
+        return strings.HasSuffix(event.Message, "5")
+    }})
+
+    var buf bytes.Buffer
+    if err := lv.Export(&buf, ExportText, ExportFiltered); err != nil {
+        t.Fatalf("Failed to export events: %v", err)
+    }
+    lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+    if len(lines) != 10 || lines[0] != "Event #5" || lines[9] != "Event #95" {
+        t.Errorf("Expected spilled and in memory events matching the filter, got %v", lines)
+    }
+    buf.Reset()
+    _ = lv.Export(&buf, ExportText, ExportAll)
+    if lines = strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 100 {
+        t.Errorf("Expected all events to be exported, got %d", len(lines))
+    }
+}
+
+// blockingWriter blocks the first write until it is released
+type blockingWriter struct {
+    bytes.Buffer
+    started, release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+    if w.started != nil {
+        close(w.started)
+        w.started = nil
+        <-w.release
+    }
+    return w.Buffer.Write(p)
+}
+
+func TestLogView_ExportFile(t *testing.T) {
+    lv, _ := openTestFile(t, 3000)
+    defer lv.CloseFile()
+    lv.AppendEvent(NewLogEvent("live", "Live event"))
+
+    started := make(chan struct{})
+    w := &blockingWriter{started: started, release: make(chan struct{})}
+    done := make(chan error)
+    go func() {
+        done <- lv.Export(w, ExportText, ExportAll)
+    }()
+    <-started
+    // lines of the file are streamed without holding the lock
+    appended := make(chan struct{})
+    go func() {
+        lv.AppendEvent(NewLogEvent("late", "Late event"))
+        close(appended)
+    }()
+    select {
+    case <-appended:
+    case <-time.After(5 * time.Second):
+        t.Fatalf("Appending an event is blocked by the export of the file")
+    }
+    close(w.release)
+    if err := <-done; err != nil {
+        t.Fatalf("Failed to export events: %v", err)
+    }
+    lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+    if len(lines) != 3001 || lines[0] != "Line #1" || lines[2999] != "Line #3000" || lines[3000] != "Live event" {
+        t.Errorf("Expected the lines of the file and the live event to be exported, got %d lines", len(lines))
+    }
+}
@@ -0,0 +1,519 @@
+package clogviewr
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// fileIndexStep is the number of lines between two offsets in the file index. Lines in between are found by
+// reading forward from the closest indexed offset.
+const fileIndexStep = 256
+
+// fileWindowSize is the number of file lines the log view keeps in memory if it has no event limit
+const fileWindowSize = 10 * spillPageSize
+
+// FileProgress is the progress of indexing or searching a file opened with LogView.OpenFile
+type FileProgress struct {
+    // Searching is true for search progress and false for indexing progress
+    Searching bool
+    // Done is the number of bytes processed so far, Total is the size of the file
+    Done  int64
+    Total int64
+    // Err is the error that stopped indexing, if any
+    Err error
+}
+
+// IsComplete checks whether all the bytes have been processed
+func (p FileProgress) IsComplete() bool {
+    return p.Done >= p.Total
+}
+
+// OnFileProgress is a listener called as the open file is indexed or searched.
+// It is called from the indexing goroutine, or while searching, so it must not call LogView methods synchronously.
+type OnFileProgress func(progress FileProgress)
+
+// fileCheckpoint is an indexed line of the file
+type fileCheckpoint struct {
+    offset int64
+    // timestamp of the line, or of the previous checkpoint if the line has none
+    timestamp time.Time
+}
+
+// fileSource is a read-only store of events backed by a log file, one event per line. Offsets and timestamps of
+// every fileIndexStep-th line are indexed in the background, lines are read and parsed only when they are needed.
+type fileSource struct {
+    path   string
+    file   *os.File
+    size   int64
+    parser LineParser
+    // the parser may keep state, so it is never called concurrently
+    parserLock sync.Mutex
+
+    checkpoints []fileCheckpoint
+    // number of lines and bytes indexed so far
+    lines   int
+    indexed int64
+    err     error
+
+    onProgress OnFileProgress
+    stop       chan struct{}
+
+    sync.RWMutex
+}
+
+// newFileSource opens the file and starts indexing it. Only the bytes present at the moment the file is opened
+// are indexed.
+func newFileSource(path string, parser LineParser, onProgress OnFileProgress) (*fileSource, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    info, err := file.Stat()
+    if err != nil {
+        _ = file.Close()
+        return nil, err
+    }
+    f := &fileSource{
+        path:        path,
+        file:        file,
+        size:        info.Size(),
+        parser:      parser,
+        checkpoints: make([]fileCheckpoint, 0, info.Size()/(fileIndexStep*64)+1),
+        onProgress:  onProgress,
+        stop:        make(chan struct{}),
+    }
+    go f.index()
+    return f, nil
+}
+
+func (f *fileSource) count() int {
+    f.RLock()
+    defer f.RUnlock()
+
+    return f.lines
+}
+
+func (f *fileSource) progress() FileProgress {
+    f.RLock()
+    defer f.RUnlock()
+
+    return FileProgress{Done: f.indexed, Total: f.size, Err: f.err}
+}
+
+// read returns events with indexes in range [from, to)
+func (f *fileSource) read(from, to int) ([]*LogEvent, error) {
+    events := make([]*LogEvent, 0)
+    err := f.scan(from, to, func(_ int, event *LogEvent) bool {
+        events = append(events, event)
+        return true
+    })
+    return events, err
+}
+
+// page returns records of events with indexes in range [from, to), lines of a file have no repeat counts
+func (f *fileSource) page(from, to int) ([]eventRecord, error) {
+    records := make([]eventRecord, 0)
+    err := f.scan(from, to, func(_ int, event *LogEvent) bool {
+        records = append(records, newEventRecord(event))
+        return true
+    })
+    return records, err
+}
+
+// scan calls fn for every event with index in range [from, to) until fn returns false
+func (f *fileSource) scan(from, to int, fn func(index int, event *LogEvent) bool) error {
+    return f.stream(from, to, fn, false)
+}
+
+// search is scan that reports its progress to the listener
+func (f *fileSource) search(from, to int, fn func(index int, event *LogEvent) bool) error {
+    return f.stream(from, to, fn, true)
+}
+
+// indexOf returns the index of the first event with a given eventID or -1 if there is no such event.
+// Ids assigned by line numbers are found without reading the whole file.
+func (f *fileSource) indexOf(eventID string) int {
+    if lineNo, err := strconv.Atoi(eventID); err == nil && lineNo > 0 {
+        if events, err := f.read(lineNo-1, lineNo); err == nil && len(events) == 1 && events[0].EventID == eventID {
+            return lineNo - 1
+        }
+    }
+    index := -1
+    _ = f.scan(0, f.count(), func(i int, event *LogEvent) bool {
+        if event.EventID == eventID {
+            index = i
+            return false
+        }
+        return true
+    })
+    return index
+}
+
+// indexOfTimestamp returns the index of the first event with a timestamp equal to or greater than given, or -1 if
+// there is no such event. Timestamps are expected to grow with line numbers.
+func (f *fileSource) indexOfTimestamp(timestamp time.Time) int {
+    f.RLock()
+    cp := sort.Search(len(f.checkpoints), func(i int) bool {
+        return !f.checkpoints[i].timestamp.Before(timestamp)
+    })
+    f.RUnlock()
+    if cp > 0 {
+        cp--
+    }
+    index := -1
+    _ = f.scan(cp*fileIndexStep, f.count(), func(i int, event *LogEvent) bool {
+        if !event.Timestamp.Before(timestamp) {
+            index = i
+            return false
+        }
+        return true
+    })
+    return index
+}
+
+// close stops indexing and closes the file. Indexing goroutine is not waited for, it stops on its own.
+func (f *fileSource) close() error {
+    close(f.stop)
+    return f.file.Close()
+}
+
+// ****************
+// Internal methods
+
+// index reads the whole file, recording offsets and timestamps of every fileIndexStep-th line
+func (f *fileSource) index() {
+    reader := bufio.NewReaderSize(io.NewSectionReader(f.file, 0, f.size), 1024*1024)
+    reportStep := maxInt64(f.size/100, 1)
+    nextReport := int64(0)
+    offset := int64(0)
+    lines := 0
+    var last time.Time
+    for offset < f.size {
+        if lines%fileIndexStep == 0 && f.isClosed() {
+            return
+        }
+        // only indexed lines are parsed, the rest are just counted
+        var line []byte
+        length := 0
+        for {
+            chunk, err := reader.ReadSlice('\n')
+            if lines%fileIndexStep == 0 {
+                line = append(line, chunk...)
+            }
+            length += len(chunk)
+            if err == bufio.ErrBufferFull {
+                continue
+            }
+            if err != nil && err != io.EOF {
+                if !f.isClosed() {
+                    f.failed(err)
+                }
+                return
+            }
+            break
+        }
+        if length == 0 {
+            break
+        }
+        if lines%fileIndexStep == 0 {
+            if event := f.parse(trimLine(string(line)), lines+1); !event.Timestamp.IsZero() {
+                last = event.Timestamp
+            }
+            f.Lock()
+            f.checkpoints = append(f.checkpoints, fileCheckpoint{offset: offset, timestamp: last})
+            f.Unlock()
+        }
+        lines++
+        offset += int64(length)
+        if lines%fileIndexStep == 0 || offset >= f.size {
+            f.Lock()
+            f.lines = lines
+            f.indexed = offset
+            f.Unlock()
+        }
+        if offset >= nextReport && offset < f.size {
+            nextReport = offset + reportStep
+            f.report(FileProgress{Done: offset, Total: f.size})
+        }
+    }
+    f.Lock()
+    f.lines = lines
+    f.indexed = f.size
+    f.Unlock()
+    f.report(FileProgress{Done: f.size, Total: f.size})
+}
+
+func (f *fileSource) failed(err error) {
+    f.Lock()
+    f.err = fmt.Errorf("failed to index %s: %v", f.path, err)
+    progress := FileProgress{Done: f.indexed, Total: f.size, Err: f.err}
+    f.Unlock()
+    f.report(progress)
+}
+
+func (f *fileSource) isClosed() bool {
+    select {
+    case <-f.stop:
+        return true
+    default:
+        return false
+    }
+}
+
+// report notifies the listener about the progress, unless the file has been closed
+func (f *fileSource) report(progress FileProgress) {
+    if f.onProgress != nil && !f.isClosed() {
+        f.onProgress(progress)
+    }
+}
+
+// stream reads lines with indexes in range [from, to) starting from the closest indexed line
+func (f *fileSource) stream(from, to int, fn func(index int, event *LogEvent) bool, report bool) error {
+    f.RLock()
+    if from < 0 {
+        from = 0
+    }
+    if to > f.lines {
+        to = f.lines
+    }
+    if from >= to {
+        f.RUnlock()
+        return nil
+    }
+    cp := f.checkpoints[from/fileIndexStep]
+    f.RUnlock()
+
+    reader := bufio.NewReader(io.NewSectionReader(f.file, cp.offset, f.size-cp.offset))
+    reportStep := maxInt64(f.size/100, 1)
+    nextReport := cp.offset + reportStep
+    offset := cp.offset
+    last := cp.timestamp
+    for i := from - from%fileIndexStep; i < to; i++ {
+        line, err := reader.ReadString('\n')
+        if err != nil && (err != io.EOF || line == "") {
+            return fmt.Errorf("failed to read line %d of %s: %v", i+1, f.path, err)
+        }
+        offset += int64(len(line))
+        if i < from {
+            continue
+        }
+        event := f.parse(trimLine(line), i+1)
+        if event.Timestamp.IsZero() {
+            event.Timestamp = last
+        }
+        last = event.Timestamp
+        if !fn(i, event) {
+            break
+        }
+        if report && offset >= nextReport {
+            nextReport = offset + reportStep
+            f.report(FileProgress{Searching: true, Done: offset, Total: f.size})
+        }
+    }
+    if report {
+        f.report(FileProgress{Searching: true, Done: f.size, Total: f.size})
+    }
+    return nil
+}
+
+// parse converts the line to an event. Lines the parser skips or fails on are kept as they are, so that
+// every line of the file is an event.
+func (f *fileSource) parse(line string, lineNo int) *LogEvent {
+    if f.parser != nil {
+        f.parserLock.Lock()
+        event, err := f.parser(line, lineNo)
+        f.parserLock.Unlock()
+        if err == nil && event != nil {
+            return event
+        }
+    }
+    return NewLogEvent(strconv.Itoa(lineNo), line)
+}
+
+func trimLine(line string) string {
+    return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
+
+// OpenFile opens a log file as the source of the log view, one event per line. Instead of loading the whole file,
+// offsets and timestamps of its lines are indexed in the background, and the log view keeps in memory only a window
+// of lines around the displayed ones, reading them from the file as it scrolls. The window is limited by the event
+// limit, or to fileWindowSize lines if there is none.
+//
+// Lines are converted to events by the parser. If the parser is nil, or skips a line, the line becomes the event
+// message with the line number as the event id. Lines are parsed in any order as they are paged in, so the parser
+// should not depend on previous lines. Lines without a timestamp get the timestamp of the previous line.
+//
+// Events in the log view are cleared. While the file is open, spilled events are not paged in, and evicted events
+// are not spilled.
+func (lv *LogView) OpenFile(path string, parser LineParser) error {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.closeFile()
+    lv.fileErr = nil
+    file, err := newFileSource(path, parser, lv.fireOnFileProgress)
+    if err != nil {
+        return err
+    }
+    lv.clear()
+    lv.file = file
+    lv.following = false
+    return nil
+}
+
+// CloseFile closes the file opened with OpenFile and clears the log view
+func (lv *LogView) CloseFile() {
+    lv.Lock()
+    defer lv.Unlock()
+
+    if lv.file != nil {
+        lv.closeFile()
+        lv.clear()
+    }
+}
+
+// IsFileOpen checks whether the log view displays a file opened with OpenFile
+func (lv *LogView) IsFileOpen() bool {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.file != nil
+}
+
+// GetFileProgress returns the indexing progress of the open file. If the file has been closed because of an error,
+// the error is returned in the progress.
+func (lv *LogView) GetFileProgress() FileProgress {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    if lv.file == nil {
+        return FileProgress{Err: lv.fileErr}
+    }
+    return lv.file.progress()
+}
+
+// SetOnFileProgress sets a listener that is called as the open file is indexed and searched
+func (lv *LogView) SetOnFileProgress(listener OnFileProgress) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.onFileProgress = listener
+}
+
+// *******************************
+// internal implementation details
+
+func (lv *LogView) fireOnFileProgress(progress FileProgress) {
+    lv.RLock()
+    listener := lv.onFileProgress
+    lv.RUnlock()
+
+    if listener != nil {
+        listener(progress)
+    }
+}
+
+func (lv *LogView) closeFile() {
+    if lv.file == nil {
+        return
+    }
+    _ = lv.file.close()
+    lv.file = nil
+    lv.filePosition = nil
+    lv.dropStoreMarks()
+}
+
+// adjoinsWindow checks whether file lines in range [from, to) overlap or adjoin the lines in memory
+func (lv *LogView) adjoinsWindow(from, to int) bool {
+    first := lv.firstEvent
+    if first == nil || !first.fromSpill {
+        return true
+    }
+    last := findFirstWrappedLine(lv.lastEvent)
+    if !last.fromSpill {
+        return true
+    }
+    return from <= last.spillIndex+1 && to >= first.spillIndex
+}
+
+// dropWindow removes all the file lines from memory
+func (lv *LogView) dropWindow() {
+    for lv.firstEvent != nil && lv.firstEvent.fromSpill {
+        lv.evict(lv.firstEvent, false)
+    }
+}
+
+// trimWindow evicts file lines from the end of the window farther from the lines in range [from, to) until the window
+// fits the limit. A page of lines around the range and around the top line is kept.
+func (lv *LogView) trimWindow(from, to int) {
+    limit := lv.eventLimit
+    if limit == 0 {
+        limit = fileWindowSize
+    }
+    if lv.top != nil {
+        if top := findFirstWrappedLine(lv.top); top.fromSpill && top.spillIndex < from {
+            from = top.spillIndex
+        } else if top.fromSpill && top.spillIndex >= to {
+            to = top.spillIndex + 1
+        }
+    }
+    from -= spillPageSize
+    to += spillPageSize
+    for lv.eventCount > limit {
+        first, last := lv.firstEvent, findFirstWrappedLine(lv.lastEvent)
+        if !first.fromSpill || !last.fromSpill {
+            return
+        }
+        if last.spillIndex >= to && last.spillIndex-to >= from-first.spillIndex {
+            lv.evict(last, false)
+        } else if first.spillIndex < from {
+            lv.evict(first, false)
+        } else {
+            return
+        }
+    }
+}
+
+// findInFile searches for an event matching the predicate in the file. If lastEventId is not an empty string,
+// search starts after the event with that id. The search ends without a match once stop is closed.
+// If lastEventId is neither empty nor in the file, it returns false to indicate the file was not searched.
+func findInFile(file *fileSource, lastEventId string, predicate func(event *LogEvent) bool, stop <-chan struct{}) (*LogEvent, bool) {
+    start := 0
+    if lastEventId != "" {
+        start = file.indexOf(lastEventId) + 1
+        if start == 0 {
+            return nil, false
+        }
+    }
+    var match *LogEvent
+    _ = file.search(start, file.count(), func(_ int, event *LogEvent) bool {
+        if isStopped(stop) {
+            return false
+        }
+        if predicate(event) {
+            match = event
+            return false
+        }
+        return true
+    })
+    return match, true
+}
+
+// countInFile counts lines of the file matching the predicate until stop is closed
+func countInFile(file *fileSource, predicate func(event *LogEvent) bool, stop <-chan struct{}) int {
+    matches := 0
+    _ = file.search(0, file.count(), func(_ int, event *LogEvent) bool {
+        if predicate(event) {
+            matches++
+        }
+        return !isStopped(stop)
+    })
+    return matches
+}
@@ -0,0 +1,174 @@
+package clogviewr
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func openTestFile(t *testing.T, lines int) (*LogView, time.Time) {
+    ts := time.Date(2021, 03, 01, 10, 0, 0, 0, time.UTC)
+    text := &strings.Builder{}
+    for i := 0; i < lines; i++ {
+        text.WriteString(fmt.Sprintf("%s Line #%d\r\n", ts.Add(time.Duration(i)*time.Second).Format("2006-01-02 15:04:05"), i+1))
+    }
+    path := filepath.Join(t.TempDir(), "test.log")
+    if err := os.WriteFile(path, []byte(text.String()), 0600); err != nil {
+        t.Fatalf("Failed to write file: %v", err)
+    }
+
+    lv := NewLogView()
+    lv.SetHighlightCurrentEvent(true)
+    lv.SetMaxEvents(1200)
+    if err := lv.OpenFile(path, TimestampLineParser("2006-01-02 15:04:05")); err != nil {
+        t.Fatalf("Failed to open file: %v", err)
+    }
+    for !lv.GetFileProgress().IsComplete() {
+        time.Sleep(time.Millisecond)
+    }
+    if err := lv.GetFileProgress().Err; err != nil {
+        t.Fatalf("Failed to index file: %v", err)
+    }
+    return lv, ts
+}
+
+func TestLogView_OpenFile(t *testing.T) {
+    lv, ts := openTestFile(t, 10000)
+    defer lv.CloseFile()
+
+    lv.ScrollToTop()
+    if event := lv.GetCurrentEvent(); event == nil || event.Message != "Line #1" || event.EventID != "1" {
+        t.Fatalf("Expected the first line to be current, got %v", event)
+    }
+    for i := 0; i < 3000; i++ {
+        lv.SelectNextEvent()
+    }
+    if event := lv.GetCurrentEvent(); event.Message != "Line #3001" || !event.Timestamp.Equal(ts.Add(3000*time.Second)) {
+        t.Errorf("Expected line 3001 after scrolling, got %v", event)
+    }
+    if lv.EventCount() > 1200 {
+        t.Errorf("Expected at most 1200 lines in memory, got %d", lv.EventCount())
+    }
+
+    lv.ScrollToBottom()
+    if event := lv.GetCurrentEvent(); event.Message != "Line #10000" {
+        t.Errorf("Expected the last line to be current, got %v", event)
+    }
+    lv.SelectPrevEvent()
+    if event := lv.GetCurrentEvent(); event.Message != "Line #9999" {
+        t.Errorf("Expected the previous line to be current, got %v", event)
+    }
+
+    if !lv.ScrollToEventID("5000") || lv.GetCurrentEvent().Message != "Line #5000" {
+        t.Errorf("Failed to scroll to line 5000, current %v", lv.GetCurrentEvent())
+    }
+    if !lv.ScrollToTimestamp(ts.Add(7200*time.Second)) || lv.GetCurrentEvent().Message != "Line #7201" {
+        t.Errorf("Failed to scroll to timestamp, current %v", lv.GetCurrentEvent())
+    }
+    if lv.EventCount() > 1200 {
+        t.Errorf("Expected at most 1200 lines in memory, got %d", lv.EventCount())
+    }
+
+    // bookmarks stay with lines dropped from memory
+    lv.ToggleBookmark()
+    lv.ScrollToTop()
+    if lv.findByEventId("7201") != nil || !lv.ScrollToNextBookmark() || lv.GetCurrentEvent().Message != "Line #7201" {
+        t.Errorf("Expected the bookmark of line 7201 to be kept once the line is dropped, current %v", lv.GetCurrentEvent())
+    }
+}
+
+func TestLogView_SearchFile(t *testing.T) {
+    lv, _ := openTestFile(t, 10000)
+    defer lv.CloseFile()
+
+    progress := make([]FileProgress, 0)
+    lock := sync.Mutex{}
+    lv.SetOnFileProgress(func(p FileProgress) {
+        lock.Lock()
+        defer lock.Unlock()
+        progress = append(progress, p)
+    })
+
+    event := lv.FindMatchingEvent("", func(event *LogEvent) bool {
+        return event.Message == "Line #9500"
+    })
+    if event == nil || event.EventID != "9500" {
+        t.Fatalf("Failed to find line 9500, got %v", event)
+    }
+    event = lv.FindPreviousMatchingEvent("9500", func(event *LogEvent) bool {
+        return strings.HasSuffix(event.Message, "99")
+    })
+    if event == nil || event.EventID != "9499" {
+        t.Fatalf("Failed to find line 9499 backwards, got %v", event)
+    }
+    if hits := lv.FindTotalMatches(func(event *LogEvent) bool { return strings.HasSuffix(event.Message, "00") }); hits != 100 {
+        t.Errorf("Expected 100 matches, got %d", hits)
+    }
+
+    lock.Lock()
+    defer lock.Unlock()
+    if len(progress) < 2 || !progress[0].Searching || !progress[len(progress)-1].IsComplete() {
+        t.Errorf("Expected search progress to be reported, got %v", progress)
+    }
+}
+
+func TestLogView_ScrollToEventIDInFile(t *testing.T) {
+    text := &strings.Builder{}
+    for i := 1; i <= 1000; i++ {
+        text.WriteString(fmt.Sprintf("id-%d Line #%d\n", i, i))
+    }
+    path := filepath.Join(t.TempDir(), "ids.log")
+    if err := os.WriteFile(path, []byte(text.String()), 0600); err != nil {
+        t.Fatalf("Failed to write file: %v", err)
+    }
+    var blocking int32
+    var once sync.Once
+    started, release := make(chan struct{}), make(chan struct{})
+    parser := func(line string, lineNo int) (*LogEvent, error) {
+        if lineNo == 900 && atomic.LoadInt32(&blocking) == 1 {
+            once.Do(func() {
+                close(started)
+                <-release
+            })
+        }
+        fields := strings.SplitN(line, " ", 2)
+        return NewLogEvent(fields[0], fields[1]), nil
+    }
+    lv := NewLogView()
+    if err := lv.OpenFile(path, parser); err != nil {
+        t.Fatalf("Failed to open file: %v", err)
+    }
+    defer lv.CloseFile()
+    for !lv.GetFileProgress().IsComplete() {
+        time.Sleep(time.Millisecond)
+    }
+    lv.ScrollToTop()
+
+    atomic.StoreInt32(&blocking, 1)
+    done := make(chan bool)
+    go func() {
+        done <- lv.ScrollToEventID("id-950")
+    }()
+    <-started
+    // ids that are not line numbers are looked up in the file without holding the lock
+    appended := make(chan struct{})
+    go func() {
+        lv.AppendEvent(NewLogEvent("live", "Live event"))
+        close(appended)
+    }()
+    select {
+    case <-appended:
+    case <-time.After(5 * time.Second):
+        close(release)
+        t.Fatalf("Appending an event is blocked by the lookup of an event id in the file")
+    }
+    close(release)
+    if !<-done || lv.GetCurrentEvent().Message != "Line #950" {
+        t.Errorf("Failed to scroll to id-950, current %v", lv.GetCurrentEvent())
+    }
+}
@@ -0,0 +1,333 @@
+package clogviewr
+
+import (
+    "fmt"
+    "github.com/gdamore/tcell/v2"
+    "sort"
+    "strings"
+    "time"
+)
+
+// EventFilter hides events that do not match its predicate. Name describes the filter to the user. Pattern is the
+// pattern the predicate was parsed from, see ParsePattern, only filters with a pattern are saved in views and sessions.
+type EventFilter struct {
+    Name      string
+    Pattern   string
+    Predicate func(event *LogEvent) bool
+}
+
+// FilterContext makes events around the events matching the filters displayed, like grep -B, -A and -C.
+// Events within Before events before a match, After events after it, or within Window of its timestamp are
+// displayed dimmed, and groups of events that are not adjacent are separated by an underline.
+//
+// Context is found among the events in memory, searches in spilled events and open files match only the events
+// matching the filters.
+type FilterContext struct {
+    Before int
+    After  int
+    Window time.Duration
+}
+
+// IsEmpty checks whether the context displays no events
+func (c FilterContext) IsEmpty() bool {
+    return c.Before <= 0 && c.After <= 0 && c.Window <= 0
+}
+
+// String describes the context with grep options, and the time window, i.e. -B 1 -A 2 30s
+func (c FilterContext) String() string {
+    parts := make([]string, 0, 3)
+    if c.Before > 0 {
+        parts = append(parts, fmt.Sprintf("-B %d", c.Before))
+    }
+    if c.After > 0 {
+        parts = append(parts, fmt.Sprintf("-A %d", c.After))
+    }
+    if c.Window > 0 {
+        parts = append(parts, c.Window.String())
+    }
+    return strings.Join(parts, " ")
+}
+
+// PushFilter adds a filter on top of the filter stack. Only events matching all the filters are displayed, the rest
+// stay in the log view, but drawing, scrolling, searching and selection skip them.
+//
+// Filters are evaluated once per event, when it is appended or paged in, and for all the events when filters change.
+func (lv *LogView) PushFilter(filter EventFilter) {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.filters = append(lv.filters, filter)
+    lv.applyFilters()
+}
+
+// PopFilter removes the filter from the top of the filter stack and returns it.
+// It returns false if there are no filters.
+func (lv *LogView) PopFilter() (EventFilter, bool) {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    if len(lv.filters) == 0 {
+        return EventFilter{}, false
+    }
+    filter := lv.filters[len(lv.filters)-1]
+    lv.filters = lv.filters[:len(lv.filters)-1]
+    lv.applyFilters()
+    return filter, true
+}
+
+// ClearFilters removes all the filters
+func (lv *LogView) ClearFilters() {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.filters = nil
+    lv.applyFilters()
+}
+
+// GetFilters returns the filter stack, the most recently pushed filter is the last
+func (lv *LogView) GetFilters() []EventFilter {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return append([]EventFilter(nil), lv.filters...)
+}
+
+// SetFilterContext sets the number of events or the time window around the events matching the filters that is
+// displayed as well
+func (lv *LogView) SetFilterContext(context FilterContext) {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.filterContext = context
+    lv.applyFilters()
+}
+
+// GetFilterContext returns the context set by SetFilterContext
+func (lv *LogView) GetFilterContext() FilterContext {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.filterContext
+}
+
+// SetLevelVisible shows or hides events of the level. Like filtered out events, hidden events stay in the log view,
+// but drawing, scrolling, searching and selection skip them.
+func (lv *LogView) SetLevelVisible(level LogLevel, visible bool) {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    if level < LogLevelAll {
+        lv.hiddenLevels[level] = !visible
+        lv.revealCurrent()
+    }
+}
+
+// IsLevelVisible checks whether events of the level are displayed
+func (lv *LogView) IsLevelVisible(level LogLevel) bool {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return level >= LogLevelAll || !lv.hiddenLevels[level]
+}
+
+// SetSourceVisible shows or hides events of the source, the same way as SetLevelVisible
+func (lv *LogView) SetSourceVisible(source string, visible bool) {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    if visible {
+        delete(lv.hiddenSources, source)
+    } else {
+        lv.hiddenSources[source] = true
+    }
+    lv.revealCurrent()
+}
+
+// IsSourceVisible checks whether events of the source are displayed
+func (lv *LogView) IsSourceVisible(source string) bool {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return !lv.hiddenSources[source]
+}
+
+// GetHiddenSources returns the sources hidden with SetSourceVisible
+func (lv *LogView) GetHiddenSources() []string {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    sources := make([]string, 0, len(lv.hiddenSources))
+    for source := range lv.hiddenSources {
+        sources = append(sources, source)
+    }
+    sort.Strings(sources)
+    return sources
+}
+
+// ShowAllSources shows events of all the sources hidden with SetSourceVisible
+func (lv *LogView) ShowAllSources() {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.hiddenSources = make(map[string]bool)
+    lv.revealCurrent()
+}
+
+// *******************************
+// internal implementation details
+
+// applyFilters evaluates the filters for all the events in memory and moves the current event to a displayed one
+func (lv *LogView) applyFilters() {
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.order <= 1 {
+            event.filteredOut = len(lv.filters) > 0 && !matchesFilters(lv.filters, event.AsLogEvent())
+            event.inContext = false
+        }
+    }
+    if lv.hasFilterContext() {
+        for event := lv.firstEvent; event != nil; event = event.next {
+            if event.order <= 1 && !event.filteredOut {
+                lv.markContext(event)
+            }
+        }
+    }
+    lv.revealCurrent()
+}
+
+// filterEvent evaluates the filters for the event, which is already linked to its neighbours, and updates the
+// context of the neighbours if the event matches
+func (lv *LogView) filterEvent(event *logEventLine) {
+    event.filteredOut = len(lv.filters) > 0 && !matchesFilters(lv.filters, event.AsLogEvent())
+    event.inContext = false
+    if !lv.hasFilterContext() {
+        return
+    }
+    if !event.filteredOut {
+        lv.markContext(event)
+        return
+    }
+    event.inContext = lv.isInContext(event)
+}
+
+// isInContext checks whether a matching event is close enough to the event to show it as context
+func (lv *LogView) isInContext(event *logEventLine) bool {
+    inContext := false
+    isMatch := func(neighbour *logEventLine) bool {
+        inContext = !neighbour.filteredOut
+        return !inContext
+    }
+    lv.walkNeighbours(event, true, lv.filterContext.After, isMatch)
+    if !inContext {
+        lv.walkNeighbours(event, false, lv.filterContext.Before, isMatch)
+    }
+    return inContext
+}
+
+func (lv *LogView) hasFilterContext() bool {
+    return len(lv.filters) > 0 && !lv.filterContext.IsEmpty()
+}
+
+// markContext marks events in the context of the matching event
+func (lv *LogView) markContext(event *logEventLine) {
+    mark := func(neighbour *logEventLine) bool {
+        neighbour.inContext = neighbour.filteredOut
+        return true
+    }
+    lv.walkNeighbours(event, true, lv.filterContext.Before, mark)
+    lv.walkNeighbours(event, false, lv.filterContext.After, mark)
+}
+
+// contextNeighbours returns the filtered out events whose context may change when the event is removed or stops matching
+func (lv *LogView) contextNeighbours(event *logEventLine) []*logEventLine {
+    var neighbours []*logEventLine
+    if !lv.hasFilterContext() {
+        return nil
+    }
+    collect := func(neighbour *logEventLine) bool {
+        if neighbour.filteredOut {
+            neighbours = append(neighbours, neighbour)
+        }
+        return true
+    }
+    count := maxInt(lv.filterContext.Before, lv.filterContext.After)
+    lv.walkNeighbours(event, true, count, collect)
+    lv.walkNeighbours(event, false, count, collect)
+    return neighbours
+}
+
+// updateContext recomputes whether the filtered out events are still in the context of a matching event
+func (lv *LogView) updateContext(neighbours []*logEventLine) {
+    for _, neighbour := range neighbours {
+        neighbour.inContext = lv.isInContext(neighbour)
+    }
+}
+
+// walkNeighbours calls fn for the events preceding or following the event, up to count of them or further while they
+// are within the context time window, until fn returns false
+func (lv *LogView) walkNeighbours(event *logEventLine, backward bool, count int, fn func(neighbour *logEventLine) bool) {
+    window := lv.filterContext.Window
+    if event.Timestamp.IsZero() {
+        window = 0
+    }
+    neighbour := event
+    for n := 1; ; n++ {
+        if backward {
+            for neighbour = neighbour.previous; neighbour != nil && neighbour.order > 1; neighbour = neighbour.previous {
+            }
+        } else {
+            for neighbour = neighbour.next; neighbour != nil && neighbour.order > 1; neighbour = neighbour.next {
+            }
+        }
+        if neighbour == nil {
+            return
+        }
+        if n > count && (window <= 0 || absDuration(neighbour.Timestamp.Sub(event.Timestamp)) > window) {
+            return
+        }
+        if !fn(neighbour) {
+            return
+        }
+    }
+}
+
+// decorateContextLine dims the line of a context event, and underlines the last line of a group of displayed events
+// followed by events that are not displayed, from the first column up to the column to
+func (lv *LogView) decorateContextLine(screen tcell.Screen, from, to int, y int, event *logEventLine) {
+    if !lv.hasFilterContext() {
+        return
+    }
+    dim := event.filteredOut && event.inContext
+    separator := event.next != nil && event.next.order <= 1 && !lv.isVisible(event.next)
+    if !dim && !separator {
+        return
+    }
+    for x := from; x < to; x++ {
+        r, combining, style, _ := screen.GetContent(x, y)
+        screen.SetContent(x, y, r, combining, style.Dim(dim).Underline(separator))
+    }
+}
+
+// isShown checks whether events of the level and the source are not hidden
+func isShown(hiddenLevels [LogLevelAll]bool, hiddenSources map[string]bool, level LogLevel, source string) bool {
+    if level < LogLevelAll && hiddenLevels[level] {
+        return false
+    }
+    return len(hiddenSources) == 0 || !hiddenSources[source]
+}
+
+// matchesFilters checks whether the event matches all the filters
+func matchesFilters(filters []EventFilter, event *LogEvent) bool {
+    for _, filter := range filters {
+        if !filter.Predicate(event) {
+            return false
+        }
+    }
+    return true
+}
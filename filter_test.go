@@ -0,0 +1,198 @@
+package clogviewr
+
+import (
+    "fmt"
+    "github.com/gdamore/tcell/v2"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestLogView_Filters(t *testing.T) {
+    lv := NewLogView()
+    lv.pageHeight = 5
+    lv.SetHighlightCurrentEvent(true)
+    lv.AppendEvents(randomEvents(30, time.Now()))
+
+    lv.PushFilter(EventFilter{Name: "ends with 5", Predicate: func(event *LogEvent) bool {
+        return strings.HasSuffix(event.Message, "5")
+    }})
+    lv.ScrollToTop()
+    if lv.GetCurrentEvent().EventID != "e5" {
+        t.Errorf("Expected first visible event to be e5, got %s", lv.GetCurrentEvent().EventID)
+    }
+    lv.SelectNextEvent()
+    if lv.GetCurrentEvent().EventID != "e15" {
+        t.Errorf("Scrolling must skip filtered out events, got %s", lv.GetCurrentEvent().EventID)
+    }
+
+    lv.PushFilter(EventFilter{Name: "e2x", Predicate: func(event *LogEvent) bool {
+        return strings.HasPrefix(event.EventID, "e2")
+    }})
+    if lv.GetCurrentEvent().EventID != "e25" {
+        t.Errorf("Expected current event to move to e25, got %s", lv.GetCurrentEvent().EventID)
+    }
+    lv.AppendEvent(NewLogEvent("e205", "Event #205"))
+    lv.AppendEvent(NewLogEvent("e35", "Event #35"))
+    matches := lv.FindTotalMatches(func(event *LogEvent) bool {
+        return true
+    })
+    if matches != 2 {
+        t.Errorf("Expected new events to be filtered, %d events match", matches)
+    }
+    if lv.ScrollToEventID("e35") {
+        t.Errorf("Filtered out events must not be scrolled to")
+    }
+    if filters := lv.GetFilters(); len(filters) != 2 || filters[1].Name != "e2x" {
+        t.Errorf("Unexpected filters: %v", filters)
+    }
+
+    if filter, ok := lv.PopFilter(); !ok || filter.Name != "e2x" {
+        t.Errorf("Expected e2x filter to be popped, got %v", filter)
+    }
+    if !lv.ScrollToEventID("e35") {
+        t.Errorf("Expected e35 to be visible after the filter is popped")
+    }
+    lv.ClearFilters()
+    lv.ScrollToEventID("e3")
+    lv.SelectNextEvent()
+    if lv.GetCurrentEvent().EventID != "e4" {
+        t.Errorf("Expected all events to be visible, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if _, ok := lv.PopFilter(); ok {
+        t.Errorf("Expected no filters to pop")
+    }
+}
+
+func TestLogView_FilterContext(t *testing.T) {
+    lv := NewLogView()
+    lv.pageHeight = 5
+    lv.SetHighlightCurrentEvent(true)
+    events := randomEvents(37, time.Now())
+    lv.AppendEvents(events[:30])
+    lv.PushFilter(EventFilter{Name: "ends with 5", Predicate: func(event *LogEvent) bool {
+        return strings.HasSuffix(event.Message, "5")
+    }})
+    lv.SetFilterContext(FilterContext{Before: 1, After: 2})
+    lv.AppendEvents(events[30:])
+
+    visible := func() string {
+        ids := make([]string, 0)
+        lv.ScrollToTop()
+        for event := lv.GetCurrentEvent(); len(ids) == 0 || ids[len(ids)-1] != event.EventID; event = lv.GetCurrentEvent() {
+            ids = append(ids, event.EventID)
+            lv.SelectNextEvent()
+        }
+        return strings.Join(ids, ",")
+    }
+    expected := "e4,e5,e6,e7,e14,e15,e16,e17,e24,e25,e26,e27,e34,e35,e36"
+    if ids := visible(); ids != expected {
+        t.Errorf("Expected events %s, got %s", expected, ids)
+    }
+    if matches := lv.FindTotalMatches(func(event *LogEvent) bool { return true }); matches != 15 {
+        t.Errorf("Expected context events to be searched, %d events match", matches)
+    }
+
+    screen := tcell.NewSimulationScreen("UTF-8")
+    screen.Init()
+    screen.SetSize(80, 5)
+    lv.pageWidth = 40
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.EventID != "e4" && event.EventID != "e5" && event.EventID != "e7" {
+            continue
+        }
+        lv.drawEvent(screen, 0, 0, event)
+        _, _, style, _ := screen.GetContent(0, 0)
+        _, _, attrs := style.Decompose()
+        if dim := attrs&tcell.AttrDim != 0; dim != (event.EventID != "e5") {
+            t.Errorf("Expected only context events to be dimmed, %s dimmed: %v", event.EventID, dim)
+        }
+        if separator := attrs&tcell.AttrUnderline != 0; separator != (event.EventID == "e7") {
+            t.Errorf("Expected a separator after e7 only, %s underlined: %v", event.EventID, separator)
+        }
+        _, _, style, _ = screen.GetContent(lv.pageWidth, 0)
+        if _, _, attrs = style.Decompose(); attrs&(tcell.AttrDim|tcell.AttrUnderline) != 0 {
+            t.Errorf("Expected the line of %s to be decorated within the page width only", event.EventID)
+        }
+    }
+
+    lv.SetFilterContext(FilterContext{Window: 1500 * time.Millisecond})
+    expected = "e4,e5,e6,e14,e15,e16,e24,e25,e26,e34,e35,e36"
+    if ids := visible(); ids != expected {
+        t.Errorf("Expected events %s within the time window, got %s", expected, ids)
+    }
+    lv.SetFilterContext(FilterContext{})
+    if ids := visible(); ids != "e5,e15,e25,e35" {
+        t.Errorf("Expected only matching events without context, got %s", ids)
+    }
+}
+
+func TestLogView_FilterContextUpdated(t *testing.T) {
+    lv := NewLogView()
+    lv.PushFilter(EventFilter{Name: "ends with 5", Predicate: func(event *LogEvent) bool {
+        return strings.HasSuffix(event.Message, "5")
+    }})
+    lv.SetFilterContext(FilterContext{Before: 1, After: 2})
+    lv.AppendEvents(randomEvents(10, time.Now()))
+    lv.SetMaxEvents(4)
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if lv.isVisible(event) {
+            t.Errorf("Expected %s to be hidden once the matching event is evicted", event.EventID)
+        }
+    }
+
+    lv = NewLogView()
+    lv.SetConcatenateEvents(true)
+    lv.SetNewEventMatchingRegex(`^[^\s]`)
+    lv.PushFilter(EventFilter{Name: "without skip", Predicate: func(event *LogEvent) bool {
+        return !strings.Contains(event.Message, "skip")
+    }})
+    lv.SetFilterContext(FilterContext{Before: 1})
+    lv.AppendEvents([]*LogEvent{NewLogEvent("1", "skip"), NewLogEvent("2", "Line 2")})
+    if !lv.isVisible(lv.firstEvent) {
+        t.Fatalf("Expected 1 to be visible as context of 2")
+    }
+    lv.AppendEvent(NewLogEvent("3", " skip"))
+    if lv.isVisible(lv.firstEvent) || lv.isVisible(lv.lastEvent) {
+        t.Errorf("Expected no visible events once the concatenated event stops matching")
+    }
+}
+
+func TestLogView_LevelsAndSources(t *testing.T) {
+    lv := NewLogView()
+    lv.pageHeight = 5
+    lv.SetHighlightCurrentEvent(true)
+    events := randomEvents(12, time.Now())
+    for i, event := range events {
+        event.Level = LogLevel(i % 3)
+        event.Source = fmt.Sprintf("s%d", i%2)
+    }
+    lv.AppendEvents(events)
+    all := func(event *LogEvent) bool { return true }
+
+    lv.SetLevelVisible(LogLevelError, false)
+    if lv.IsLevelVisible(LogLevelError) || !lv.IsLevelVisible(LogLevelWarning) {
+        t.Errorf("Expected only errors to be hidden")
+    }
+    if matches := lv.FindTotalMatches(all); matches != 8 {
+        t.Errorf("Expected 8 events without errors, got %d", matches)
+    }
+    lv.SetSourceVisible("s1", false)
+    if matches := lv.FindTotalMatches(all); matches != 4 {
+        t.Errorf("Expected 4 events of s0 without errors, got %d", matches)
+    }
+    lv.ScrollToTop()
+    lv.SelectNextEvent()
+    if lv.GetCurrentEvent().EventID != "e4" {
+        t.Errorf("Scrolling must skip hidden events, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if hidden := lv.GetHiddenSources(); len(hidden) != 1 || hidden[0] != "s1" {
+        t.Errorf("Expected s1 to be hidden, got %v", hidden)
+    }
+
+    lv.ShowAllSources()
+    lv.SetLevelVisible(LogLevelError, true)
+    if matches := lv.FindTotalMatches(all); matches != 12 {
+        t.Errorf("Expected all events to be visible, got %d", matches)
+    }
+}
@@ -23,14 +23,16 @@ func TestLogView_colorizeGroups(t *testing.T) {
 
     msg := " Два wordoслова 11 møøsè"
     event := &logEventLine{
-        EventID: "1",
-        Runes:   []rune(msg),
+        storedEvent: &storedEvent{
+            EventID: "1",
+            message: msg,
+        },
     }
 
     lv.colorize(event)
 
     getSpan := func(i int) string {
-        return string(event.Runes[event.styleSpans[i].start:event.styleSpans[i].end])
+        return event.message[event.styleSpans[i].start:event.styleSpans[i].end]
     }
 
     expected := map[int]string{
@@ -68,7 +70,7 @@ func TestLogView_colorizeRealWorld1(t *testing.T) {
     lv.SetHighlightPattern(highlightRe)
 
     lv.colorize(&logEventLine{
-        Runes: []rune(line),
+        storedEvent: &storedEvent{message: line},
     })
 }
 
@@ -88,7 +90,7 @@ func TestLogView_colorizeDraw(t *testing.T) {
     lv.SetHighlightPattern(highlightRe)
 
     event := &logEventLine{
-        Runes: []rune(line),
+        storedEvent: &storedEvent{message: line},
     }
     lv.colorize(event)
 
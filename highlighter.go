@@ -9,15 +9,41 @@ import (
     "strings"
 )
 
-func (lv *LogView) defaultStyleEvent(event *logEventLine) *logEventLine {
-    event.styleSpans = []styledSpan{
-        {
-            start: 0,
-            end:   len(event.Runes),
-            style: lv.defaultStyle,
-        },
-    }
-    return event
+// HighlightRule sets the background colour of events matching its predicate. Name describes the rule to the user.
+// Pattern is the pattern the predicate was parsed from, see ParsePattern, only rules with a pattern are saved in
+// views and sessions.
+type HighlightRule struct {
+    Name      string
+    Pattern   string
+    Predicate func(event *LogEvent) bool
+    Color     tcell.Color
+}
+
+// AddHighlightRule adds a rule that highlights matching events. If an event matches several rules, the first one
+// added wins. Rules take precedence over level highlighting.
+func (lv *LogView) AddHighlightRule(rule HighlightRule) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.highlightRules = append(lv.highlightRules, rule)
+    lv.recolorizeLines()
+}
+
+// ClearHighlightRules removes all the highlight rules
+func (lv *LogView) ClearHighlightRules() {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.highlightRules = nil
+    lv.recolorizeLines()
+}
+
+// GetHighlightRules returns the highlight rules in the order they were added
+func (lv *LogView) GetHighlightRules() []HighlightRule {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return append([]HighlightRule(nil), lv.highlightRules...)
 }
 
 type captureGroup struct {
@@ -44,55 +70,72 @@ func isInt(text string) bool {
     return err == nil
 }
 
+// colorize calculates style spans of the event message. Events without highlighted groups get no spans,
+// they are drawn with the base style.
 func (lv *LogView) colorize(event *logEventLine) *logEventLine {
-    if event.order != 0 {
+    if event.order > 1 {
         panic(fmt.Errorf("cannot colorize wrapped line"))
     }
-    defaultStyle := lv.defaultStyle
-    useSpecialBg := false
-    if lv.highlightLevels && event.Level != LogLevelInfo {
-        useSpecialBg = true
-        if event.Level == LogLevelWarning {
-            defaultStyle = defaultStyle.Background(lv.warningBgColor)
-        } else {
-            defaultStyle = defaultStyle.Background(lv.errorBgColor)
+    event.highlightRule = 0
+    if len(lv.highlightRules) > 0 {
+        logEvent := event.AsLogEvent()
+        for i, rule := range lv.highlightRules {
+            if rule.Predicate(logEvent) {
+                event.highlightRule = i + 1
+                break
+            }
         }
     }
-    if lv.highlightingEnabled && lv.highlightPattern != nil {
-        text := event.message()
-        match, err := lv.highlightPattern.FindStringMatch(text)
-        if err != nil || match == nil {
-            return lv.defaultStyleEvent(event)
-        }
-        groups := make([]captureGroup, 0)
-        for match != nil {
-            for _, gr := range match.Groups() {
-                if len(gr.Captures) > 0 && !isInt(gr.Name) {
-                    groups = append(groups, captureGroup{
-                        Capture: gr.Capture,
-                        name:    gr.Name,
-                    })
-                }
-            }
-            match, err = lv.highlightPattern.FindNextMatch(match)
-            if err != nil {
-                return lv.defaultStyleEvent(event)
+    event.styleSpans = nil
+    if !lv.highlightingEnabled || lv.highlightPattern == nil {
+        return event
+    }
+    text := event.message
+    match, err := lv.highlightPattern.FindStringMatch(text)
+    if err != nil || match == nil {
+        return event
+    }
+    groups := make([]captureGroup, 0)
+    for match != nil {
+        for _, gr := range match.Groups() {
+            if len(gr.Captures) > 0 && !isInt(gr.Name) {
+                groups = append(groups, captureGroup{
+                    Capture: gr.Capture,
+                    name:    gr.Name,
+                })
             }
         }
-        sort.Sort(captureGroupSorter(groups))
-        event.styleSpans = lv.buildSpans([]rune(text), groups, defaultStyle, useSpecialBg)
-    } else {
-        event.styleSpans = []styledSpan{
-            {
-                start: 0,
-                end:   len(event.Runes),
-                style: defaultStyle,
-            },
+        match, err = lv.highlightPattern.FindNextMatch(match)
+        if err != nil {
+            return event
         }
     }
+    if len(groups) == 0 {
+        return event
+    }
+    sort.Sort(captureGroupSorter(groups))
+    useSpecialBg := event.highlightRule > 0 || lv.highlightLevels && event.Level != LogLevelInfo
+    event.styleSpans = lv.buildSpans(text, groups, lv.baseStyle(event), useSpecialBg)
     return event
 }
 
+// baseStyle returns the style of the event text that is not highlighted
+func (lv *LogView) baseStyle(event *logEventLine) tcell.Style {
+    if event.highlightRule > 0 && event.highlightRule <= len(lv.highlightRules) {
+        return lv.defaultStyle.Background(lv.highlightRules[event.highlightRule-1].Color)
+    }
+    if !lv.highlightLevels {
+        return lv.defaultStyle
+    }
+    switch event.Level {
+    case LogLevelWarning:
+        return lv.defaultStyle.Background(lv.warningBgColor)
+    case LogLevelError:
+        return lv.defaultStyle.Background(lv.errorBgColor)
+    }
+    return lv.defaultStyle
+}
+
 func (lv *LogView) groupNameToStyle(colorName string) tcell.Style {
     style := lv.defaultStyle
     colorPair := strings.Split(strings.ToLower(colorName), "_")
@@ -107,22 +150,30 @@ func (lv *LogView) groupNameToStyle(colorName string) tcell.Style {
     return style
 }
 
-func (lv *LogView) buildSpans(text []rune, groups []captureGroup, defaultStyle tcell.Style, useDefaultBg bool) []styledSpan {
+// buildSpans converts capture groups to style spans. Groups are indexed in runes, while spans use byte offsets
+// into the text.
+func (lv *LogView) buildSpans(text string, groups []captureGroup, defaultStyle tcell.Style, useDefaultBg bool) []styledSpan {
+    offsets := runeOffsets(text)
+    offset := func(index int) int {
+        return offsets[minInt(index, len(offsets)-1)]
+    }
+
     currentPos := 0
     spans := make([]styledSpan, 0)
 
     _, dbg, _ := defaultStyle.Decompose()
 
     for _, group := range groups {
-        if group.Index != currentPos {
-            runeLen := len(text[currentPos:group.Index])
-            beforeSpan := styledSpan{
+        start := offset(group.Index)
+        if start < currentPos { // nested or overlapping group
+            continue
+        }
+        if start != currentPos {
+            spans = append(spans, styledSpan{
                 start: currentPos,
-                end:   currentPos + runeLen,
+                end:   start,
                 style: defaultStyle,
-            }
-            spans = append(spans, beforeSpan)
-            currentPos = currentPos + runeLen
+            })
         }
 
         style := lv.groupNameToStyle(group.name)
@@ -131,21 +182,19 @@ func (lv *LogView) buildSpans(text []rune, groups []captureGroup, defaultStyle t
             style = style.Background(dbg)
         }
 
-        matched := styledSpan{
-            start: currentPos,
-            end:   currentPos + group.Length,
+        currentPos = offset(group.Index + group.Length)
+        spans = append(spans, styledSpan{
+            start: start,
+            end:   currentPos,
             style: style,
-        }
-        spans = append(spans, matched)
-        currentPos += group.Length
+        })
     }
     if currentPos < len(text) {
-        afterSpan := styledSpan{
+        spans = append(spans, styledSpan{
             start: currentPos,
-            end:   len(text) + 1,
+            end:   len(text),
             style: defaultStyle,
-        }
-        spans = append(spans, afterSpan)
+        })
     }
     return spans
 }
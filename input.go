@@ -55,6 +55,18 @@ type Key struct {
     MoveNextPage      []string
 
     ShowContextMenu []string
+
+    ToggleSelection []string
+
+    CopyMessage   []string
+    CopyEvent     []string
+    CopySelection []string
+
+    ToggleBookmark   []string
+    NextBookmark     []string
+    PreviousBookmark []string
+    SetMark          []string
+    JumpToMark       []string
 }
 
 // Keys defines the keyboard shortcuts of an application.
@@ -85,6 +97,18 @@ var Keys = Key{
     MoveNextPage:      []string{"PageDown", "Ctrl+F"},
 
     ShowContextMenu: []string{"Alt+Enter"},
+
+    ToggleSelection: []string{"v"},
+
+    CopyMessage:   []string{"y"},
+    CopyEvent:     []string{"Y"},
+    CopySelection: []string{"Ctrl+Y"},
+
+    ToggleBookmark:   []string{"b"},
+    NextBookmark:     []string{"]"},
+    PreviousBookmark: []string{"["},
+    SetMark:          []string{"m"},
+    JumpToMark:       []string{"'"},
 }
 
 // HitShortcut returns whether the EventKey provided is present in one or more
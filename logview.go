@@ -5,6 +5,7 @@ import (
     "fmt"
     "github.com/dlclark/regexp2"
     "github.com/gdamore/tcell/v2"
+    "io"
     "regexp"
     "strconv"
     "strings"
@@ -19,19 +20,58 @@ type styledSpan struct {
     style tcell.Style
 }
 
-type logEventLine struct {
-    EventID    string
-    Source     string
-    Timestamp  time.Time
-    Level      LogLevel
-    Runes      []rune
-    lineID     uint
-    Data       interface{}
-    previous   *logEventLine
-    next       *logEventLine
+// storedEvent is a log event held by the log view. It is shared by all the lines the event is wrapped into.
+type storedEvent struct {
+    EventID   string
+    Source    string
+    Timestamp time.Time
+    Level     LogLevel
+    Data      interface{}
+
+    // message is kept in UTF-8, line and span offsets are byte offsets into it
+    message string
+    // styleSpans are calculated by colorize, nil if there is nothing to highlight
     styleSpans []styledSpan
+    lineCount  uint
+    // there are newline symbols in the message. Normally there shouldn't be any, but if we've done
+    // event merging, then merged parts will be separated by newlines. We need to know if there are any
+    // so we can decide if we need to wrap them.
+    hasNewLines bool
+
+    // event was paged in from the spill file, spillIndex is its position in the file
+    fromSpill  bool
+    spillIndex int
+
+    // number of consecutive duplicate occurrences collapsed into this event, 0 if it has never been repeated
+    repeatCount uint
+
+    // id of the message template assigned by the template miner, 0 if templates are not mined
+    templateID int
+
+    // event does not match the filters and is not displayed, unless it is in the context of a matching event
+    filteredOut bool
+    inContext   bool
 
-    // start and end determine slice of LogEvent.Message this event line covers
+    // index of the highlight rule the event matches starting from 1, 0 if it matches none
+    highlightRule int
+
+    // event is bookmarked, marks are the names of vim-style marks set on the event
+    bookmarked bool
+    marks      string
+
+    // firstLine is the line the event starts on, deleted is set once the event is no longer in the log view
+    firstLine *logEventLine
+    deleted   bool
+}
+
+// logEventLine is a single line of the log view, a view of the whole event or a part of it if the event is wrapped
+type logEventLine struct {
+    *storedEvent
+
+    previous *logEventLine
+    next     *logEventLine
+
+    // start and end determine slice of the message this event line covers
     // for unwrapped string this will be the whole length of the message starting at position 0
     // for wrapped strings each line with order != 0 will cover its portion of main event message
     start int
@@ -40,12 +80,7 @@ type logEventLine struct {
     // order indicate whether the single log event is split over multiple lines because of wrapping
     // if the event is not split, then order will be 0
     // otherwise the first line will have the order value of 1, the next line is 2 and so on
-    order     int
-    lineCount uint
-    // there are newline symbols in the message. Normally there shouldn't be any, but if we've done
-    // event merging, then merged parts will be separated by newlines. We need to know if there are any
-    // so we can decide if we need to wrap them.
-    hasNewLines bool
+    order int
 }
 
 func (e logEventLine) AsLogEvent() *LogEvent {
@@ -54,40 +89,83 @@ func (e logEventLine) AsLogEvent() *LogEvent {
         Source:    e.Source,
         Timestamp: e.Timestamp,
         Level:     e.Level,
-        Message:   string(e.Runes),
+        Message:   e.message,
         Data:      e.Data,
     }
 }
 
-func (e logEventLine) message() string {
-    return string(e.Runes)
+// text returns the part of the message displayed on this line
+func (e logEventLine) text() string {
+    return e.message[e.start:e.end]
 }
 
 func (e logEventLine) getLineCount() uint {
     return e.lineCount
 }
 
+// copy creates another line of the same event
 func (e *logEventLine) copy() *logEventLine {
-    eventCopy := &logEventLine{
-        EventID:     e.EventID,
-        Source:      e.Source,
-        Timestamp:   e.Timestamp,
-        Level:       e.Level,
-        Runes:       e.Runes,
-        lineID:      e.lineID,
+    return &logEventLine{
+        storedEvent: e.storedEvent,
         previous:    e.previous,
         next:        e.next,
-        styleSpans:  e.styleSpans,
         start:       e.start,
         end:         e.end,
         order:       e.order,
-        lineCount:   e.lineCount,
-        hasNewLines: e.hasNewLines,
-        Data:        e.Data,
     }
-    return eventCopy
 }
 
+// FloodPolicy defines how LogView ingests events during log storms.
+//
+// When more than MaxRate events are appended within a second, Info events above that rate are sampled: one of every
+// SampleEvery events is kept and the rest are dropped. If SampleEvery is less than 2 all of them are dropped.
+// Warning and Error events are always kept. At the end of each second with dropped events, a synthetic warning
+// event "N events dropped" is inserted so the user knows what was skipped. Its event id starts with
+// DroppedEventIDPrefix.
+//
+// MaxRate of zero disables flood protection.
+type FloodPolicy struct {
+    MaxRate     int
+    SampleEvery int
+}
+
+// DroppedEventIDPrefix starts the ids of the synthetic events inserted by the flood policy, event ids starting with
+// a NUL character are reserved for them
+const DroppedEventIDPrefix = "\x00dropped-"
+
+// floodState keeps track of the ingestion rate and of events dropped by the flood policy
+type floodState struct {
+    windowStart  time.Time
+    windowCount  int
+    dropped      uint
+    totalDropped uint64
+    markerCount  uint
+    lastDropped  bool
+    lastTime     time.Time
+    // the marker of events dropped in the last second of a storm is inserted by the timer
+    flushTimer *time.Timer
+}
+
+// RetentionPolicy limits the events retained by LogView in addition to the event limit.
+//
+// - MaxBytes caps the total size of retained event messages in bytes
+//
+// - MaxAge keeps only the events not older than MaxAge relative to the newest event
+//
+// Zero value means no limit. The newest event is always retained.
+type RetentionPolicy struct {
+    MaxBytes int
+    MaxAge   time.Duration
+}
+
+// OnEventsEvicted is an event type that is fired when events are evicted from the log view by
+// the event limit or the retention policy
+type OnEventsEvicted func(events []*LogEvent)
+
+// OnEventsAppended is an event type that is fired with appended events that are displayed by the log view. An event
+// continued by following lines is fired again with the lines concatenated.
+type OnEventsAppended func(events []*LogEvent)
+
 // OnCurrentChanged is an event time that is fired when current log event is changed
 type OnCurrentChanged func(current *LogEvent)
 
@@ -104,11 +182,65 @@ type LogView struct {
     eventCount uint
     eventLimit uint
 
+    // limits on the number of events per source, sources without a limit use defaultSourceLimit
+    sourceLimits       map[string]uint
+    defaultSourceLimit uint
+    sourceCounts       map[string]uint
+    // events of each source oldest first, so that the oldest events of a source are evicted without a walk through
+    // the log. Paged in events are neither counted nor queued. Events are only queued while a source limit is set,
+    // sourceEvents is nil otherwise.
+    sourceEvents map[string][]*storedEvent
+
+    floodPolicy FloodPolicy
+    flood       floodState
+
+    retention     RetentionPolicy
+    retainedBytes int
+    evicted       []*LogEvent
+    onEvicted     OnEventsEvicted
+    appended      []*LogEvent
+    onAppended    OnEventsAppended
+
+    // evicted events are written to spill store, if enabled
+    spill    *spillStore
+    spillErr error
+
+    // lines of the open file are paged in instead of spilled events, if a file is open
+    file           *fileSource
+    fileErr        error
+    onFileProgress OnFileProgress
+    // position in the open file restored by LoadSession, it is scrolled to once the file is indexed up to it
+    filePosition *sessionFile
+
     newEventMatcher   *regexp.Regexp
     concatenateEvents bool
 
+    // consecutive duplicate events are collapsed into one with a repeat counter
+    collapseDuplicates bool
+    maskDuplicates     bool
+
+    // event messages are clustered into templates, only events of templateFilter are displayed if it is not 0
+    templateMiner  *TemplateMiner
+    templateFilter int
+
+    // only events matching all the filters, and events in their context, are displayed
+    filters       []EventFilter
+    filterContext FilterContext
+
+    // events of hidden levels and sources are not displayed
+    hiddenLevels  [LogLevelAll]bool
+    hiddenSources map[string]bool
+
+    // occurrences of the active search are overlaid with searchMatchStyle, matches of the last drawn event are kept
+    activeSearch        *Search
+    searchMatchStyle    tcell.Style
+    searchMatchesOf     *storedEvent
+    searchMatchesLength int
+    searchMatches       [][]int
+
     highlightingEnabled bool
     highlightPattern    *regexp2.Regexp
+    highlightRules      []HighlightRule
 
     highlightLevels bool
     warningBgColor  tcell.Color
@@ -117,6 +249,22 @@ type LogView struct {
     highlightCurrent bool
     currentBgColor   tcell.Color
 
+    // selection spans all events from the anchor to the current event
+    selectionAnchor  *logEventLine
+    selectionBgColor tcell.Color
+    drawSelected     bool
+
+    // copy actions write OSC 52 sequences to the clipboard writer, if there is one
+    clipboard io.Writer
+
+    // number of events with a bookmark or a mark, the gutter is drawn while there are any
+    markedEvents  int
+    bookmarkStyle tcell.Style
+    // bookmarks and marks of events that are only in the spill file or the open file, by position in the store
+    storeMarks map[int]storeMark
+    // the next key names the mark to set or to jump to
+    pendingMark markAction
+
     sourceStyle    tcell.Style
     timestampStyle tcell.Style
 
@@ -188,10 +336,17 @@ func NewLogView() *LogView {
         highlightingEnabled: true,
         defaultStyle:        defaultStyle,
         currentBgColor:      tcell.ColorDimGray,
+        selectionBgColor:    tcell.ColorDarkSlateGray,
+        searchMatchStyle:    tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow),
+        sourceLimits:        make(map[string]uint),
+        sourceCounts:        make(map[string]uint),
+        storeMarks:          make(map[int]storeMark),
+        hiddenSources:       make(map[string]bool),
         warningBgColor:      tcell.ColorSaddleBrown,
         errorBgColor:        tcell.ColorIndianRed,
         sourceStyle:         defaultStyle.Foreground(tcell.ColorDarkGoldenrod),
         timestampStyle:      defaultStyle.Foreground(tcell.ColorDarkOrange),
+        bookmarkStyle:       defaultStyle.Foreground(tcell.ColorDodgerBlue).Bold(true),
         screenCoords:        make([]int, 2),
         concatenateEvents:   false,
         newEventMatcher:     regexp.MustCompile(`^[^\s]`),
@@ -224,11 +379,7 @@ func (lv *LogView) Clear() {
     lv.Lock()
     defer lv.Unlock()
 
-    lv.firstEvent = nil
-    lv.lastEvent = nil
-    lv.current = nil
-    lv.top = nil
-    lv.eventCount = 0
+    lv.clear()
 }
 
 // GetEventCount returns number of events in the log view
@@ -240,6 +391,7 @@ func (lv *LogView) GetEventCount() uint {
 
 // SetMaxEvents sets a maximum number of events that log view will hold
 func (lv *LogView) SetMaxEvents(limit uint) {
+    defer lv.fireOnEvicted()
     lv.Lock()
     defer lv.Unlock()
 
@@ -255,6 +407,32 @@ func (lv *LogView) GetMaxEvents() uint {
     return lv.eventLimit
 }
 
+// SetFloodPolicy sets the ingestion policy applied to appended events when they arrive faster than the policy rate
+func (lv *LogView) SetFloodPolicy(policy FloodPolicy) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.floodPolicy = policy
+    lv.flood.windowStart = time.Time{}
+    lv.flood.windowCount = 0
+}
+
+// GetFloodPolicy returns the current ingestion policy
+func (lv *LogView) GetFloodPolicy() FloodPolicy {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.floodPolicy
+}
+
+// GetDroppedEventCount returns the total number of events dropped by the flood policy
+func (lv *LogView) GetDroppedEventCount() uint64 {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.flood.totalDropped
+}
+
 // SetLineWrap enables/disables the line wrap. Disabling line wrap may increase performance
 func (lv *LogView) SetLineWrap(enabled bool) {
     lv.Lock()
@@ -293,6 +471,88 @@ func (lv *LogView) IsConcatenateEventsEnabled() bool {
     return lv.concatenateEvents
 }
 
+// SetCollapseDuplicates enables or disables collapsing of consecutive duplicate events.
+//
+// If enabled, an event with the same source, level and message as the last event is not appended, instead
+// the repeat counter of the last event is incremented and displayed as a ×N badge.
+func (lv *LogView) SetCollapseDuplicates(enabled bool) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.collapseDuplicates = enabled
+}
+
+// IsCollapseDuplicatesEnabled returns the status of duplicate event collapsing
+func (lv *LogView) IsCollapseDuplicatesEnabled() bool {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.collapseDuplicates
+}
+
+// SetMaskDuplicates enables or disables masking of numbers and UUIDs when messages are compared for collapsing,
+// so that i.e. "retry 1 of 5" and "retry 2 of 5" are treated as duplicates
+func (lv *LogView) SetMaskDuplicates(enabled bool) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.maskDuplicates = enabled
+}
+
+// IsMaskDuplicatesEnabled returns the status of number and UUID masking for duplicate event collapsing
+func (lv *LogView) IsMaskDuplicatesEnabled() bool {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.maskDuplicates
+}
+
+// SetTemplateMiner sets the miner that clusters appended event messages into templates. Events that are already
+// in the log view are added to the miner. Setting nil stops mining and clears the template filter.
+func (lv *LogView) SetTemplateMiner(miner *TemplateMiner) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.templateMiner = miner
+    lv.templateFilter = 0
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.order > 1 {
+            continue
+        }
+        event.templateID = 0
+        if miner != nil {
+            event.templateID = miner.Add(event.AsLogEvent())
+        }
+    }
+}
+
+// GetTemplateMiner returns the template miner of the log view
+func (lv *LogView) GetTemplateMiner() *TemplateMiner {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.templateMiner
+}
+
+// SetTemplateFilter displays only events of the template with given id. Zero id displays all events.
+// Scrolling, searching and selection skip events that are not displayed.
+func (lv *LogView) SetTemplateFilter(templateID int) {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.templateFilter = templateID
+    lv.revealCurrent()
+}
+
+// GetTemplateFilter returns the id of the template displayed events belong to, 0 if events are not filtered
+func (lv *LogView) GetTemplateFilter() int {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.templateFilter
+}
+
 // SetNewEventMatcher sets the regular expression to use for detecting continuation events.
 //
 // If event message matches provided regular expression it is treated as a new event, otherwise it is appended to
@@ -366,6 +626,7 @@ func (lv *LogView) SetCurrentBgColor(color tcell.Color) {
 //
 // To disable limit set it to zero.
 func (lv *LogView) SetEventLimit(limit uint) {
+    defer lv.fireOnEvicted()
     lv.Lock()
     defer lv.Unlock()
 
@@ -374,6 +635,106 @@ func (lv *LogView) SetEventLimit(limit uint) {
     lv.ensureEventLimit()
 }
 
+// SetSourceEventLimit sets the maximum number of events of the source the log view will hold, so that a noisy
+// source does not evict the history of quiet ones. Oldest events of the source are evicted first.
+// Zero limit removes the limit of the source, default source limit applies then.
+func (lv *LogView) SetSourceEventLimit(source string, limit uint) {
+    defer lv.fireOnEvicted()
+    lv.Lock()
+    defer lv.Unlock()
+
+    if limit == 0 {
+        delete(lv.sourceLimits, source)
+    } else {
+        lv.sourceLimits[source] = limit
+    }
+    lv.ensureEventLimit()
+}
+
+// GetSourceEventLimit returns the maximum number of events of the source, 0 if the source has no own limit
+func (lv *LogView) GetSourceEventLimit(source string) uint {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.sourceLimits[source]
+}
+
+// SetDefaultSourceEventLimit sets the maximum number of events per source for sources without their own limit.
+// Zero disables the default limit.
+func (lv *LogView) SetDefaultSourceEventLimit(limit uint) {
+    defer lv.fireOnEvicted()
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.defaultSourceLimit = limit
+    lv.ensureEventLimit()
+}
+
+// GetDefaultSourceEventLimit returns the maximum number of events per source for sources without their own limit
+func (lv *LogView) GetDefaultSourceEventLimit() uint {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.defaultSourceLimit
+}
+
+// GetSourceEventCounts returns the number of events the log view holds per source, the counts source limits apply
+// to. Events paged in from the spill file or the open file are not counted.
+func (lv *LogView) GetSourceEventCounts() map[string]uint {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    counts := make(map[string]uint, len(lv.sourceCounts))
+    for source, count := range lv.sourceCounts {
+        counts[source] = count
+    }
+    return counts
+}
+
+// SetRetentionPolicy sets the limits on total size and age of events retained by the log view.
+// Events exceeding the limits are evicted starting from the oldest one, the same way as with the event limit.
+func (lv *LogView) SetRetentionPolicy(policy RetentionPolicy) {
+    defer lv.fireOnEvicted()
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.retention = policy
+    lv.ensureEventLimit()
+}
+
+// GetRetentionPolicy returns the limits on total size and age of retained events
+func (lv *LogView) GetRetentionPolicy() RetentionPolicy {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.retention
+}
+
+// GetRetainedBytes returns the total size of messages of all events in the log view
+func (lv *LogView) GetRetainedBytes() int {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.retainedBytes
+}
+
+// SetOnEvicted sets a listener that will be called with events evicted by the event limit or the retention policy
+func (lv *LogView) SetOnEvicted(listener OnEventsEvicted) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.onEvicted = listener
+}
+
+// SetOnAppended sets a listener that will be called with appended events that are displayed, events hidden by
+// filters or hidden levels and sources are not reported
+func (lv *LogView) SetOnAppended(listener OnEventsAppended) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.onAppended = listener
+}
+
 // RefreshHighlights forces recalculation of highlight patterns for all events in the log view.
 // LogView calculates highlight spans once for each event when the event is appended. Any changes in highlighting
 // will not be applied to the events that are already in the log view.
@@ -476,6 +837,47 @@ func (lv *LogView) IsHighlightCurrentEventEnabled() bool {
     return lv.highlightCurrent
 }
 
+// SetSelectionBgColor sets the background color to highlight selected events
+func (lv *LogView) SetSelectionBgColor(color tcell.Color) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.selectionBgColor = color
+}
+
+// StartSelection starts selecting events from the current event. Selection spans all events from the current event
+// at the moment of this call to the current event as it changes.
+func (lv *LogView) StartSelection() {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.startSelection()
+}
+
+// ClearSelection clears selection of events
+func (lv *LogView) ClearSelection() {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.selectionAnchor = nil
+}
+
+// HasSelection returns whether there are selected events
+func (lv *LogView) HasSelection() bool {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.selectionAnchor != nil
+}
+
+// GetSelection returns selected events in order they appear in the log view
+func (lv *LogView) GetSelection() []*LogEvent {
+    lv.Lock()
+    defer lv.Unlock()
+
+    return lv.selectedEvents()
+}
+
 // GetCurrentEvent returns the currently selected event
 func (lv *LogView) GetCurrentEvent() *LogEvent {
     lv.RLock()
@@ -493,33 +895,143 @@ func (lv *LogView) GetCurrentEvent() *LogEvent {
 //
 // If no such event can be found it will return nil
 func (lv *LogView) FindMatchingEvent(lastEventId string, predicate func(event *LogEvent) bool) *LogEvent {
+    return lv.findMatchingEvent(lastEventId, predicate, nil)
+}
+
+// findMatchingEvent is FindMatchingEvent that gives up, returning nil, once stop is closed
+func (lv *LogView) findMatchingEvent(lastEventId string, predicate func(event *LogEvent) bool, stop <-chan struct{}) *LogEvent {
+    lv.Lock()
+    visible := lv.visiblePredicate(predicate)
+    file := lv.file
+    lv.Unlock()
+    if file != nil {
+        // the file is streamed without holding the lock, so that the log view can be drawn meanwhile
+        if match, searched := findInFile(file, lastEventId, visible, stop); searched {
+            if match != nil {
+                return match
+            }
+            lastEventId = ""
+        }
+    }
+
     lv.Lock()
     defer lv.Unlock()
 
+    if file == nil && lv.spill != nil {
+        if match, searched := lv.findSpilled(lastEventId, visible, stop); searched {
+            if match != nil {
+                return match
+            }
+            lastEventId = ""
+        }
+    }
+
     event := lv.findByEventId(lastEventId)
-    if event.previous != nil && event.next != nil {
+    if event != nil && event.previous != nil && event.next != nil {
         event = event.next
     }
-    for event != nil {
-        logEvent := event.AsLogEvent()
-        if predicate(logEvent) {
-            return logEvent
+    for event != nil && !isStopped(stop) {
+        if event.order <= 1 && !event.fromSpill && lv.isVisible(event) {
+            logEvent := event.AsLogEvent()
+            if predicate(logEvent) {
+                return logEvent
+            }
         }
         event = event.next
     }
     return nil
 }
 
-func (lv *LogView) FindTotalMatches(predicate func(event *LogEvent) bool) int {
+// FindPreviousMatchingEvent searches backwards for a event that matches a given predicate
+// First event to be matched is the one before the event id equal to lastEventId. If the lastEventId is an empty
+// string, the search will start from the last event in the log view.
+//
+// If no such event can be found it will return nil
+func (lv *LogView) FindPreviousMatchingEvent(lastEventId string, predicate func(event *LogEvent) bool) *LogEvent {
+    return lv.findPreviousMatchingEvent(lastEventId, predicate, nil)
+}
+
+// findPreviousMatchingEvent is FindPreviousMatchingEvent that gives up, returning nil, once stop is closed
+func (lv *LogView) findPreviousMatchingEvent(lastEventId string, predicate func(event *LogEvent) bool, stop <-chan struct{}) *LogEvent {
+    lv.Lock()
+    visible := lv.visiblePredicate(predicate)
+    file := lv.file
+    lv.Unlock()
+    if file != nil {
+        // the file is streamed without holding the lock, so that the log view can be drawn meanwhile
+        to := file.count()
+        if lastEventId != "" {
+            to = file.indexOf(lastEventId)
+        }
+        match, _ := findPreviousInStore(file, to, visible, stop)
+        return match
+    }
+
     lv.Lock()
     defer lv.Unlock()
 
+    event := lv.lastEvent
+    if lastEventId != "" {
+        for event != nil && (event.EventID != lastEventId || event.fromSpill) {
+            event = event.previous
+        }
+    }
+    if lv.spill == nil && event == nil {
+        return nil
+    }
+    to := 0
+    if event != nil || lastEventId == "" {
+        if lastEventId != "" {
+            event = event.previous
+        }
+        for ; event != nil && !isStopped(stop); event = event.previous {
+            if event.order <= 1 && !event.fromSpill && lv.isVisible(event) {
+                logEvent := event.AsLogEvent()
+                if predicate(logEvent) {
+                    return logEvent
+                }
+            }
+        }
+        if lv.spill == nil {
+            return nil
+        }
+        // spilled events are older than the events in memory
+        to = lv.spill.count()
+    } else {
+        to = lv.spillIndexOf(lastEventId)
+    }
+    match, err := findPreviousInStore(lv.spill, to, visible, stop)
+    if err != nil {
+        lv.spillFailed(err)
+    }
+    return match
+}
+
+func (lv *LogView) FindTotalMatches(predicate func(event *LogEvent) bool) int {
+    return lv.countMatches(predicate, nil)
+}
+
+// countMatches is FindTotalMatches that gives up once stop is closed, returning the matches counted so far
+func (lv *LogView) countMatches(predicate func(event *LogEvent) bool, stop <-chan struct{}) int {
+    lv.Lock()
+    visible := lv.visiblePredicate(predicate)
+    file := lv.file
+    lv.Unlock()
     matches := 0
+    if file != nil {
+        matches += countInFile(file, visible, stop)
+    }
+
+    lv.Lock()
+    defer lv.Unlock()
+
+    if file == nil && lv.spill != nil {
+        matches += lv.countSpilled(visible, stop)
+    }
     event := lv.findByEventId("")
 
-    for event != nil {
-        logEvent := event.AsLogEvent()
-        if predicate(logEvent) {
+    for event != nil && !isStopped(stop) {
+        if event.order <= 1 && !event.fromSpill && lv.isVisible(event) && predicate(event.AsLogEvent()) {
             matches++
         }
         event = event.next
@@ -527,6 +1039,61 @@ func (lv *LogView) FindTotalMatches(predicate func(event *LogEvent) bool) int {
     return matches
 }
 
+// FindAllMatches returns the displayed events matching the predicate, oldest first, and their number. Only the last
+// limit matches are returned if limit is greater than 0.
+func (lv *LogView) FindAllMatches(predicate func(event *LogEvent) bool, limit int) ([]*LogEvent, int) {
+    matches := make([]*LogEvent, 0)
+    total := 0
+    add := func(event *LogEvent) {
+        total++
+        matches = append(matches, event)
+        // trimmed in batches to avoid copying the matches on every event
+        if limit > 0 && len(matches) >= 2*limit {
+            matches = append(matches[:0], matches[len(matches)-limit:]...)
+        }
+    }
+
+    lv.forEachMatch(predicate, add)
+    if limit > 0 && len(matches) > limit {
+        matches = matches[len(matches)-limit:]
+    }
+    return matches, total
+}
+
+// forEachMatch calls fn with the displayed events matching the predicate, oldest first, including events of the open
+// file or spilled events
+func (lv *LogView) forEachMatch(predicate func(event *LogEvent) bool, fn func(event *LogEvent)) {
+    lv.Lock()
+    visible := lv.visiblePredicate(predicate)
+    file := lv.file
+    lv.Unlock()
+    collect := func(_ int, event *LogEvent) bool {
+        if visible(event) {
+            fn(event)
+        }
+        return true
+    }
+    if file != nil {
+        _ = file.search(0, file.count(), collect)
+    }
+
+    lv.Lock()
+    defer lv.Unlock()
+
+    if file == nil && lv.spill != nil {
+        if err := lv.spill.scan(0, lv.spill.count(), collect); err != nil {
+            lv.spillFailed(err)
+        }
+    }
+    for event := lv.findByEventId(""); event != nil; event = event.next {
+        if event.order <= 1 && !event.fromSpill && lv.isVisible(event) {
+            if logEvent := event.AsLogEvent(); predicate(logEvent) {
+                fn(logEvent)
+            }
+        }
+    }
+}
+
 // GetFirstEvent returns the first event in the log view
 func (lv *LogView) GetFirstEvent() *LogEvent {
     lv.Lock()
@@ -587,6 +1154,7 @@ func (lv *LogView) Draw(screen tcell.Screen) {
     if lv.isHeaderPossible() {
         lv.pageWidth -= lv.headerWidth()
     }
+    lv.pageWidth -= lv.gutterWidth()
     if (width != lv.lastWidth || height != lv.lastHeight && lv.wrap) || lv.forceWrap {
         lv.forceWrap = false
         lv.rewrapLines()
@@ -596,15 +1164,30 @@ func (lv *LogView) Draw(screen tcell.Screen) {
         }
     }
     lv.lastWidth, lv.lastHeight = width, height
+    if lv.filePosition != nil {
+        lv.restoreFilePosition()
+    } else if lv.file != nil && lv.firstEvent == nil {
+        // first lines of the file have been indexed since it was opened
+        lv.scrollToStart()
+    }
 
     line := y
 
-    top := lv.top
-    for top != nil && line < y+height {
-        lv.drawEvent(screen, x, line, top)
-        line++
-        top = top.next
+    selectionFirst, selectionLast, selected := lv.selectionBounds()
+    for event := lv.top; event != nil && line < y+height; event = event.next {
+        if event == selectionFirst {
+            selected = true
+        }
+        if lv.isVisible(event) {
+            lv.drawSelected = selected
+            lv.drawEvent(screen, x, line, event)
+            line++
+        }
+        if event == selectionLast {
+            selected = false
+        }
     }
+    lv.drawSelected = false
     for line < y+height {
         lv.clearLine(screen, x, line)
         line++
@@ -614,21 +1197,25 @@ func (lv *LogView) Draw(screen tcell.Screen) {
 // AppendEvent appends an event to the log view
 // If possible use AppendEvents to add multiple events at once
 func (lv *LogView) AppendEvent(logEvent *LogEvent) {
+    defer lv.fireOnAppended()
+    defer lv.fireOnEvicted()
     defer lv.fireOnCurrentChange(lv.current)
     lv.Lock()
     defer lv.Unlock()
 
-    lv.append(logEvent)
+    lv.ingest(logEvent)
 }
 
 // AppendEvents appends multiple events in a single batch improving performance
 func (lv *LogView) AppendEvents(events []*LogEvent) {
+    defer lv.fireOnAppended()
+    defer lv.fireOnEvicted()
     defer lv.fireOnCurrentChange(lv.current)
     lv.Lock()
     defer lv.Unlock()
 
     for _, e := range events {
-        lv.append(e)
+        lv.ingest(e)
     }
 }
 
@@ -716,45 +1303,69 @@ func (lv *LogView) ScrollToTimestamp(timestamp time.Time) bool {
     lv.Lock()
     defer lv.Unlock()
 
+    if store := lv.store(); store != nil {
+        if index := store.indexOfTimestamp(timestamp); index >= 0 {
+            lv.pageIn(index-spillPageSize/2, index+spillPageSize/2)
+        }
+    }
     event := lv.firstEvent
-    for event != nil && event.Timestamp.Before(timestamp) {
+    for event != nil && (event.Timestamp.Before(timestamp) || !lv.isVisible(event)) {
         event = event.next
     }
     if event == nil {
         return false
     }
+    lv.following = false
     lv.top = event
     lv.current = event
     lv.adjustTop()
-    for i := 0; i < lv.pageHeight/4; i++ { // scroll a little bit back
-        if lv.top.previous != nil {
-            lv.top = lv.top.previous
-        }
-    }
+    lv.top = lv.atOffset(lv.top, -lv.pageHeight/4) // scroll a little bit back
+    lv.fillSpillGaps(lv.top, lv.pageHeight)
     return true
 }
 
 // ScrollToEventID scrolls to the first event with a matching eventID
 // If no such event is found it will not scroll and return false.
 //
-// Current event will be updated to the found event
+// Current event will be updated to the found event. Following stops if the event had to be paged in, otherwise
+// the paged in events would be evicted again as the view follows new events.
 func (lv *LogView) ScrollToEventID(eventID string) bool {
+    lv.RLock()
+    file := lv.file
+    inMemory := lv.findByEventId(eventID) != nil
+    lv.RUnlock()
+    fileIndex := -1
+    if file != nil && !inMemory {
+        // the file is scanned without holding the lock, so that the log view can be drawn meanwhile
+        fileIndex = file.indexOf(eventID)
+    }
+
     defer lv.fireOnCurrentChange(lv.current)
     lv.Lock()
     defer lv.Unlock()
 
     event := lv.findByEventId(eventID)
-    if event == nil {
+    if store := lv.store(); event == nil && store != nil {
+        index := fileIndex
+        if lv.file == nil {
+            index = store.indexOf(eventID)
+        } else if lv.file != file {
+            index = -1
+        }
+        if index >= 0 {
+            lv.pageIn(index-spillPageSize/2, index+spillPageSize/2)
+            event = lv.findByEventId(eventID)
+            lv.following = lv.following && event == nil
+        }
+    }
+    if event == nil || !lv.isVisible(event) {
         return false
     }
     lv.top = event
     lv.current = event
     lv.adjustTop()
-    for i := 0; i < lv.pageHeight/4; i++ {
-        if lv.top.previous != nil {
-            lv.top = lv.top.previous
-        }
-    }
+    lv.top = lv.atOffset(lv.top, -lv.pageHeight/4)
+    lv.fillSpillGaps(lv.top, lv.pageHeight)
     return true
 }
 
@@ -847,10 +1458,19 @@ func (lv *LogView) GetTimestampFormat() string {
 func (lv *LogView) InputHandler() func(event *tcell.EventKey, setFocus func(p gui.Primitive)) {
     return lv.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p gui.Primitive)) {
         defer lv.fireOnCurrentChange(lv.current)
+        // copied text is written once the lock is released
+        var clip func() (string, error)
+        defer func() {
+            if clip != nil {
+                _ = lv.copy(clip)
+            }
+        }()
         lv.Lock()
         defer lv.Unlock()
 
-        if HitShortcut(event, Keys.MoveFirst, Keys.MoveFirst2) {
+        if lv.pendingMark != markNone {
+            lv.handleMarkKey(event)
+        } else if HitShortcut(event, Keys.MoveFirst, Keys.MoveFirst2) {
             lv.scrollToStart()
         } else if HitShortcut(event, Keys.MoveLast, Keys.MoveLast2) {
             lv.scrollToEnd()
@@ -862,6 +1482,28 @@ func (lv *LogView) InputHandler() func(event *tcell.EventKey, setFocus func(p gu
             lv.scrollPageUp()
         } else if HitShortcut(event, Keys.MoveNextPage) {
             lv.scrollPageDown()
+        } else if HitShortcut(event, Keys.ToggleSelection) {
+            if lv.selectionAnchor == nil {
+                lv.startSelection()
+            } else {
+                lv.selectionAnchor = nil
+            }
+        } else if HitShortcut(event, Keys.CopyMessage) && lv.current != nil {
+            clip = lv.currentMessageText
+        } else if HitShortcut(event, Keys.CopyEvent) && lv.current != nil {
+            clip = lv.currentEventText
+        } else if HitShortcut(event, Keys.CopySelection) && lv.selectionAnchor != nil {
+            clip = lv.selectionText
+        } else if HitShortcut(event, Keys.ToggleBookmark) && lv.current != nil {
+            lv.setBookmark(lv.current, !lv.current.bookmarked)
+        } else if HitShortcut(event, Keys.NextBookmark) {
+            lv.scrollToBookmark(false)
+        } else if HitShortcut(event, Keys.PreviousBookmark) {
+            lv.scrollToBookmark(true)
+        } else if HitShortcut(event, Keys.SetMark) {
+            lv.pendingMark = markSet
+        } else if HitShortcut(event, Keys.JumpToMark) {
+            lv.pendingMark = markJump
         }
     })
 }
@@ -894,70 +1536,255 @@ func (lv *LogView) MouseHandler() func(action gui.MouseAction, event *tcell.Even
             consumed = true
         }
 
-        return
-    })
+        return
+    })
+}
+
+// SetOnCurrentChange sets a listener that will be called every time the current event is changed
+//
+// If current event highlighting is disabled, listener will not be called.
+func (lv *LogView) SetOnCurrentChange(listener OnCurrentChanged) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.onCurrentChanged = listener
+}
+
+// *******************************
+// internal implementation details
+
+func (lv *LogView) fireOnCurrentChange(oldCurrent *logEventLine) {
+    if oldCurrent != lv.current && lv.onCurrentChanged != nil && lv.highlightCurrent {
+        lv.onCurrentChanged(lv.current.AsLogEvent())
+    }
+}
+
+func (lv *LogView) fireOnEvicted() {
+    lv.Lock()
+    events := lv.evicted
+    listener := lv.onEvicted
+    lv.evicted = nil
+    lv.Unlock()
+
+    if listener != nil && len(events) > 0 {
+        listener(events)
+    }
+}
+
+func (lv *LogView) fireOnAppended() {
+    lv.Lock()
+    events := lv.appended
+    listener := lv.onAppended
+    lv.appended = nil
+    lv.Unlock()
+
+    if listener != nil && len(events) > 0 {
+        listener(events)
+    }
+}
+
+func (lv *LogView) clear() {
+    lv.firstEvent = nil
+    lv.lastEvent = nil
+    lv.current = nil
+    lv.top = nil
+    lv.selectionAnchor = nil
+    lv.eventCount = 0
+    lv.retainedBytes = 0
+    lv.appended = nil
+    lv.markedEvents = 0
+    lv.storeMarks = make(map[int]storeMark)
+    lv.sourceCounts = make(map[string]uint)
+    lv.sourceEvents = nil
+    lv.filePosition = nil
+    lv.flood.dropped = 0
+    // templates describe the events of the log view
+    if lv.templateMiner != nil {
+        lv.templateMiner.Clear()
+    }
+    lv.templateFilter = 0
+    if lv.spill != nil {
+        if err := lv.spill.reset(); err != nil {
+            lv.spillFailed(err)
+        }
+    }
+}
+
+// ingest appends the event unless it has to be dropped by the flood policy
+func (lv *LogView) ingest(logEvent *LogEvent) {
+    if lv.admit(logEvent) {
+        lv.append(logEvent)
+    }
 }
 
-// SetOnCurrentChange sets a listener that will be called every time the current event is changed
-//
-// If current event highlighting is disabled, listener will not be called.
-func (lv *LogView) SetOnCurrentChange(listener OnCurrentChanged) {
+// admit applies the flood policy to the event, it returns false if the event has to be dropped
+func (lv *LogView) admit(logEvent *LogEvent) bool {
+    if lv.floodPolicy.MaxRate <= 0 {
+        return true
+    }
+    if lv.isContinuation(logEvent) {
+        // continuation lines share the fate of the event they belong to
+        return !lv.flood.lastDropped
+    }
+
+    now := time.Now()
+    if now.Sub(lv.flood.windowStart) >= time.Second {
+        lv.flood.windowStart = now
+        lv.flood.windowCount = 0
+        lv.insertDroppedMarker()
+    }
+    lv.flood.windowCount++
+    lv.flood.lastDropped = false
+
+    over := lv.flood.windowCount - lv.floodPolicy.MaxRate
+    if over <= 0 || logEvent.Level != LogLevelInfo {
+        return true
+    }
+    if lv.floodPolicy.SampleEvery > 1 && over%lv.floodPolicy.SampleEvery == 0 {
+        return true
+    }
+
+    lv.flood.lastDropped = true
+    lv.flood.lastTime = logEvent.Timestamp
+    lv.flood.dropped++
+    lv.flood.totalDropped++
+    if lv.flood.dropped == 1 {
+        lv.flood.flushTimer = time.AfterFunc(lv.flood.windowStart.Add(time.Second).Sub(now), lv.flushDroppedEvents)
+    }
+    return false
+}
+
+// flushDroppedEvents inserts the marker of dropped events once their window is over, even if no event follows
+func (lv *LogView) flushDroppedEvents() {
+    defer lv.fireOnAppended()
+    defer lv.fireOnEvicted()
+    defer lv.fireOnCurrentChange(lv.current)
     lv.Lock()
     defer lv.Unlock()
 
-    lv.onCurrentChanged = listener
+    if time.Since(lv.flood.windowStart) >= time.Second {
+        lv.insertDroppedMarker()
+    }
 }
 
-// *******************************
-// internal implementation details
+// insertDroppedMarker appends a synthetic event accounting for the events dropped since the last marker
+func (lv *LogView) insertDroppedMarker() {
+    if lv.flood.dropped == 0 {
+        return
+    }
+    lv.flood.markerCount++
+    marker := &LogEvent{
+        EventID:   fmt.Sprintf("%s%d", DroppedEventIDPrefix, lv.flood.markerCount),
+        Timestamp: lv.flood.lastTime,
+        Level:     LogLevelWarning,
+        Message:   fmt.Sprintf("%d events dropped", lv.flood.dropped),
+    }
+    lv.flood.dropped = 0
+    lv.append(marker)
+}
 
-func (lv *LogView) fireOnCurrentChange(oldCurrent *logEventLine) {
-    if oldCurrent != lv.current && lv.onCurrentChanged != nil && lv.highlightCurrent {
-        lv.onCurrentChanged(lv.current.AsLogEvent())
+// isContinuation returns true if the event would be concatenated to the last event
+func (lv *LogView) isContinuation(logEvent *LogEvent) bool {
+    return lv.concatenateEvents && lv.newEventMatcher != nil && lv.lastEvent != nil &&
+        !lv.newEventMatcher.MatchString(logEvent.Message)
+}
+
+// isDuplicate checks whether the event repeats the last event and can be collapsed into it
+func (lv *LogView) isDuplicate(logEvent *LogEvent) bool {
+    if !lv.collapseDuplicates || lv.lastEvent == nil || lv.isContinuation(logEvent) {
+        return false
     }
+    last := lv.lastEvent
+    if last.Source != logEvent.Source || last.Level != logEvent.Level {
+        return false
+    }
+    if lv.maskDuplicates {
+        return maskVariables(last.message) == maskVariables(logEvent.Message)
+    }
+    return last.message == logEvent.Message
+}
+
+// incrementRepeatCount increments the repeat counter of the last event
+func (lv *LogView) incrementRepeatCount() {
+    count := lv.lastEvent.repeatCount
+    if count == 0 {
+        count = 1
+    }
+    lv.lastEvent.repeatCount = count + 1
 }
 
 func (lv *LogView) append(logEvent *LogEvent) {
     var event *logEventLine
+    var neighbours []*logEventLine
 
-    if !lv.concatenateEvents || lv.newEventMatcher == nil || lv.newEventMatcher.MatchString(logEvent.Message) || lv.lastEvent == nil {
-        // defensive copy of Log event
-        event = &logEventLine{
-            EventID:     logEvent.EventID,
-            Source:      logEvent.Source,
-            Timestamp:   logEvent.Timestamp,
-            Data:        logEvent.Data,
-            Level:       logEvent.Level,
-            Runes:       []rune(logEvent.Message),
-            lineCount:   1,
-            lineID:      lv.eventCount + 1,
-            start:       0,
-            order:       0,
-            end:         utf8.RuneCountInString(logEvent.Message),
-            hasNewLines: strings.Contains(logEvent.Message, "\n"),
-        }
+    templateID := 0
+    if lv.templateMiner != nil && !lv.isContinuation(logEvent) {
+        templateID = lv.templateMiner.Add(logEvent)
+    }
+    if lv.isDuplicate(logEvent) {
+        lv.incrementRepeatCount()
+        return
+    }
+    if !lv.isContinuation(logEvent) {
+        event = lv.newEventLine(logEvent)
+        event.templateID = templateID
         lv.insertAfter(lv.lastEvent, event, true)
     } else {
         event = lv.lastEvent
-        event.Runes = append(event.Runes, []rune("\n"+logEvent.Message)...)
+        event.message += "\n" + logEvent.Message
+        lv.retainedBytes += len(logEvent.Message) + 1
         event.hasNewLines = event.hasNewLines || strings.Contains(logEvent.Message, "\n")
         event = lv.mergeWrappedLines(event)
+        if !event.filteredOut {
+            // the concatenated event may no longer match
+            neighbours = lv.contextNeighbours(event)
+        }
     }
 
     // process event
+    lv.filterEvent(event)
+    if event.filteredOut {
+        lv.updateContext(neighbours)
+    }
     lv.colorize(event)
     lv.calculateWrap(event)
+    if lv.onAppended != nil && lv.isVisible(event) {
+        lv.appended = append(lv.appended, event.AsLogEvent())
+    }
 
     lv.ensureEventLimit()
 
     // if we're in following mode and have enough events to fill the page then update the top position
     if lv.following && lv.eventCount >= uint(lv.pageHeight) {
-        lv.top = lv.atOffset(lv.lastEvent, -lv.pageHeight+1)
-        lv.current = event
+        lv.top = lv.atOffset(lv.lastLine(), -lv.pageHeight+1)
+        if lv.isVisible(event) {
+            lv.current = event
+        }
+    }
+}
+
+// newEventLine creates a new unwrapped event line with a defensive copy of log event
+func (lv *LogView) newEventLine(logEvent *LogEvent) *logEventLine {
+    line := &logEventLine{
+        storedEvent: &storedEvent{
+            EventID:     logEvent.EventID,
+            Source:      logEvent.Source,
+            Timestamp:   logEvent.Timestamp,
+            Data:        logEvent.Data,
+            Level:       logEvent.Level,
+            message:     logEvent.Message,
+            lineCount:   1,
+            hasNewLines: strings.Contains(logEvent.Message, "\n"),
+        },
+        start: 0,
+        order: 0,
+        end:   len(logEvent.Message),
     }
+    line.firstLine = line
+    return line
 }
 
-// atOffset finds event that is at given offset from the starting event
+// atOffset finds event that is at given offset from the starting event, counting only visible events
 // offset can be positive or negative
 // if first or last event is reached then it is returned
 func (lv *LogView) atOffset(start *logEventLine, offset int) *logEventLine {
@@ -973,14 +1800,16 @@ func (lv *LogView) atOffset(start *logEventLine, offset int) *logEventLine {
         steps = -offset
     }
     for steps > 0 {
-        if (current == lv.firstEvent && offset < 0) || (current == lv.lastEvent && offset > 0) {
-            break
-        }
+        var next *logEventLine
         if offset < 0 {
-            current = current.previous
+            next = lv.previousVisible(current)
         } else {
-            current = current.next
+            next = lv.nextVisible(current)
+        }
+        if next == nil {
+            break
         }
+        current = next
         steps--
     }
     return current
@@ -991,7 +1820,7 @@ func (lv *LogView) atOffset(start *logEventLine, offset int) *logEventLine {
 // new event lines with order >= 1 are created and inserted in the log list
 // last event is returned
 func (lv *LogView) calculateWrap(event *logEventLine) *logEventLine {
-    if !lv.wrap || lv.pageWidth == 0 || (len(event.Runes) <= lv.pageWidth && !event.hasNewLines) {
+    if !lv.wrap || lv.pageWidth == 0 || (!event.hasNewLines && utf8.RuneCountInString(event.message) <= lv.pageWidth) {
         if event.order != 0 { // no wrapping needed, but the line is wrapped
             event = lv.mergeWrappedLines(event)
         }
@@ -1001,38 +1830,39 @@ func (lv *LogView) calculateWrap(event *logEventLine) *logEventLine {
         event = lv.mergeWrappedLines(event)
     }
 
-    lineLength := len(event.Runes)
     start := 0
-    end := 0
+    width := 0
     events := make([]*logEventLine, 0)
 
-    for end < lineLength {
-        if end-start == lv.pageWidth || event.Runes[end] == '\n' { // wrap here
+    for pos, r := range event.message {
+        if width == lv.pageWidth || r == '\n' { // wrap here
+            end := pos
+            if r == '\n' { // newline stays at the end of the line
+                end++
+            }
             currentEvent := event.copy()
-            currentEvent.hasNewLines = false
             currentEvent.start = start
-            if event.Runes[end] == '\n' {
-                end = end + 1
-                currentEvent.hasNewLines = true
-            }
             currentEvent.end = end
+            events = append(events, currentEvent)
 
             start = end
-            events = append(events, currentEvent)
-        } else {
-            end++
+            width = 0
+            if r == '\n' {
+                continue
+            }
         }
+        width++
     }
-    if end > start { // add final piece
+    if len(event.message) > start { // add final piece
         currentEvent := event.copy()
         currentEvent.start = start
-        currentEvent.end = end
+        currentEvent.end = len(event.message)
         events = append(events, currentEvent)
     }
     for i, r := range events {
         r.order = i + 1
-        r.lineCount = uint(len(events))
     }
+    event.lineCount = uint(len(events))
     return lv.replaceEvent(event, events)
 }
 
@@ -1043,6 +1873,13 @@ func findFirstWrappedLine(event *logEventLine) *logEventLine {
     return event
 }
 
+func findLastWrappedLine(event *logEventLine) *logEventLine {
+    for event.next != nil && event.next.order > 1 {
+        event = event.next
+    }
+    return event
+}
+
 // mergeWrappedLines will delete all extra lines for an event
 // if the event order is == 0, it will return the event
 // otherwise it will fine the first event, change its order to 0
@@ -1056,7 +1893,7 @@ func (lv *LogView) mergeWrappedLines(event *logEventLine) *logEventLine {
     event.order = 0
     event.start = 0
     event.lineCount = 1
-    event.end = len(event.Runes)
+    event.end = len(event.message)
     next := event.next
     if next == lv.lastEvent {
         lv.lastEvent = event
@@ -1107,14 +1944,67 @@ func (lv *LogView) insertAfter(node *logEventLine, new *logEventLine, adjustLine
     }
     if adjustLineCount {
         lv.eventCount++
+        lv.retainedBytes += eventSize(new)
+        lv.countSource(new)
     }
     return new
 }
 
+// insertFirst inserts the event before the first event of the log view
+func (lv *LogView) insertFirst(new *logEventLine) *logEventLine {
+    if lv.firstEvent == nil {
+        return lv.insertAfter(nil, new, true)
+    }
+    new.next = lv.firstEvent
+    lv.firstEvent.previous = new
+    lv.firstEvent = new
+    lv.eventCount++
+    lv.retainedBytes += eventSize(new)
+    lv.countSource(new)
+    return new
+}
+
+// countSource counts the event of its source and queues it for eviction by the source limit, unless it is paged in
+func (lv *LogView) countSource(event *logEventLine) {
+    if event.fromSpill {
+        return
+    }
+    lv.sourceCounts[event.Source]++
+    if lv.sourceEvents != nil {
+        lv.sourceEvents[event.Source] = append(lv.sourceEvents[event.Source], event.storedEvent)
+    }
+}
+
+// uncountSource removes the deleted event from the counts, and the events of its source that are no longer in the
+// log view from the head of the source queue. Events are usually deleted oldest first, so the queue stays short.
+func (lv *LogView) uncountSource(event *logEventLine) {
+    if event.fromSpill {
+        return
+    }
+    if lv.sourceCounts[event.Source]--; lv.sourceCounts[event.Source] == 0 {
+        delete(lv.sourceCounts, event.Source)
+    }
+    event.deleted = true
+    queue := lv.sourceEvents[event.Source]
+    for len(queue) > 0 && queue[0].deleted {
+        queue = queue[1:]
+    }
+    if len(queue) == 0 {
+        delete(lv.sourceEvents, event.Source)
+    } else {
+        lv.sourceEvents[event.Source] = queue
+    }
+}
+
 func (lv *LogView) deleteEvent(event *logEventLine, adjustLineCount bool) {
     if event == nil {
         return
     }
+    var neighbours []*logEventLine
+    if adjustLineCount {
+        // neighbours get closer to each other and may lose the deleted match
+        neighbours = lv.contextNeighbours(event)
+    }
     if event.next != nil {
         event.next.previous = event.previous
     }
@@ -1128,22 +2018,32 @@ func (lv *LogView) deleteEvent(event *logEventLine, adjustLineCount bool) {
         lv.lastEvent = event.previous
     }
     if event == lv.top {
-        if event.next == nil {
-            lv.top = event.previous
+        if event.previous == nil {
+            lv.top = event.next
         } else {
             lv.top = event.previous
         }
     }
     if event == lv.current {
-        if event.next == nil {
-            lv.current = event.previous
+        if event.previous == nil {
+            lv.current = event.next
         } else {
             lv.current = event.previous
         }
     }
+    if event == lv.selectionAnchor {
+        lv.selectionAnchor = nil
+    }
+    if adjustLineCount && (event.bookmarked || event.marks != "") {
+        // bookmarks and marks go away with the event, unless they are kept for the store
+        lv.updateMarkedEvents(-1)
+    }
     if adjustLineCount {
         lv.eventCount--
+        lv.retainedBytes -= eventSize(event)
+        lv.uncountSource(event)
     }
+    lv.updateContext(neighbours)
 }
 
 // replaceEvent chains all the events in the replacement slice and
@@ -1166,6 +2066,7 @@ func (lv *LogView) replaceEvent(toReplace *logEventLine, replacement []*logEvent
     }
     replacement[0].previous = toReplace.previous
     replacement[lastI].next = toReplace.next
+    replacement[0].firstLine = replacement[0]
 
     if toReplace == lv.firstEvent {
         lv.firstEvent = replacement[0]
@@ -1179,6 +2080,9 @@ func (lv *LogView) replaceEvent(toReplace *logEventLine, replacement []*logEvent
     if toReplace == lv.top {
         lv.top = replacement[0]
     }
+    if toReplace == lv.selectionAnchor {
+        lv.selectionAnchor = replacement[0]
+    }
     if lv.current == toReplace {
         lv.current = replacement[lastI]
     }
@@ -1203,7 +2107,10 @@ func (lv *LogView) unwrapLines() {
 func (lv *LogView) recolorizeLines() {
     event := lv.firstEvent
     for event != nil {
-        lv.colorize(event)
+        if event.order <= 1 {
+            // lines of a wrapped event share the highlighting
+            lv.colorize(event)
+        }
         event = event.next
     }
 }
@@ -1223,6 +2130,10 @@ func (lv *LogView) rewrapLines() {
 
 // drawEvent draws single event on a single line
 func (lv *LogView) drawEvent(screen tcell.Screen, x int, y int, event *logEventLine) {
+    lineStart := x
+    if lv.markedEvents > 0 {
+        x = lv.printGutter(screen, x, y, event)
+    }
     if lv.showSource && lv.isHeaderPossible() {
         if event.order <= 1 {
             x = lv.printSource(screen, x, y, event) + 1
@@ -1243,6 +2154,25 @@ func (lv *LogView) drawEvent(screen tcell.Screen, x int, y int, event *logEventL
     } else {
         lv.printLogLineNoHighlights(screen, x, y, event)
     }
+
+    if event.repeatCount > 1 && (event.next == nil || event.next.order <= 1) {
+        lv.printRepeatCount(screen, x, y, event)
+    }
+    lv.decorateContextLine(screen, lineStart, x+lv.pageWidth, y, event)
+}
+
+// printRepeatCount prints the ×N badge after the last line of a collapsed event, or at the right edge if the line is full
+func (lv *LogView) printRepeatCount(screen tcell.Screen, x int, y int, event *logEventLine) {
+    badge := fmt.Sprintf("×%d", event.repeatCount)
+    width := utf8.RuneCountInString(badge)
+    badgeX := minInt(x+utf8.RuneCountInString(event.text())+1, x+lv.pageWidth-width)
+    style := lv.defaultStyle.Foreground(tcell.ColorYellow).Bold(true)
+    if bg, ok := lv.highlightBackground(event); ok {
+        style = style.Background(bg)
+    }
+    for i, c := range []rune(badge) {
+        screen.SetCell(badgeX+i, y, style, c)
+    }
 }
 
 func (lv *LogView) printSource(screen tcell.Screen, x int, y int, event *logEventLine) int {
@@ -1252,11 +2182,9 @@ func (lv *LogView) printSource(screen tcell.Screen, x int, y int, event *logEven
     } else {
         source = fmt.Sprintf("%"+strconv.Itoa(lv.sourceClipLength)+"v", event.Source)
     }
-    var style tcell.Style
-    if lv.highlightCurrent && event == lv.current {
-        style = lv.defaultStyle.Background(lv.currentBgColor)
-    } else {
-        style = lv.sourceStyle
+    style := lv.sourceStyle
+    if bg, ok := lv.highlightBackground(event); ok {
+        style = lv.defaultStyle.Background(bg)
     }
 
     lv.printSpecial(screen, x, y, event, source, style)
@@ -1266,11 +2194,9 @@ func (lv *LogView) printSource(screen tcell.Screen, x int, y int, event *logEven
 
 func (lv *LogView) printTimestamp(screen tcell.Screen, x int, y int, event *logEventLine) int {
     ts := event.Timestamp.Format(lv.timestampFormat)
-    var style tcell.Style
-    if lv.highlightCurrent && event == lv.current {
-        style = lv.defaultStyle.Background(lv.currentBgColor)
-    } else {
-        style = lv.timestampStyle
+    style := lv.timestampStyle
+    if bg, ok := lv.highlightBackground(event); ok {
+        style = lv.defaultStyle.Background(bg)
     }
     return lv.printSpecial(screen, x, y, event, ts, style)
 }
@@ -1278,10 +2204,9 @@ func (lv *LogView) printTimestamp(screen tcell.Screen, x int, y int, event *logE
 func (lv *LogView) printSpecial(screen tcell.Screen, x int, y int, event *logEventLine, ts string, style tcell.Style) int {
     printString(screen, x, y, ts, style)
 
-    if lv.highlightCurrent && event == lv.current {
-        style = lv.defaultStyle.Background(lv.currentBgColor)
-    } else {
-        style = lv.defaultStyle
+    style = lv.defaultStyle
+    if bg, ok := lv.highlightBackground(event); ok {
+        style = style.Background(bg)
     }
     printString(screen, x+len(ts)+1, y, "|", style)
 
@@ -1289,36 +2214,44 @@ func (lv *LogView) printSpecial(screen tcell.Screen, x int, y int, event *logEve
 }
 
 func (lv *LogView) printLogLine(screen tcell.Screen, x int, y int, event *logEventLine) {
+    spans := event.styleSpans
     // find first styled span for the event
     spanIndex := 0
-    for spanIndex < len(event.styleSpans) {
-        if event.styleSpans[spanIndex].start <= event.start && event.styleSpans[spanIndex].end >= event.start {
-            break
-        }
+    for spanIndex < len(spans) && spans[spanIndex].end <= event.start {
         spanIndex++
     }
-    if spanIndex == len(event.styleSpans) { // no colorization needed
-        lv.printLogLineNoHighlights(screen, x, y, event)
-        return
+    matches := lv.searchMatchesFor(event)
+    matchIndex := 0
+    baseStyle := lv.baseStyle(event)
+    bg, overrideBg := lv.highlightBackground(event)
+    if overrideBg { // overwrite bg color for current or selected event
+        baseStyle = baseStyle.Background(bg)
     }
-    textPos := event.start
     i := x
-    var style tcell.Style
-    for textPos < event.end {
-        style = event.styleSpans[spanIndex].style
-        if lv.highlightCurrent && event == lv.current { // overwrite bg color for current selected event
-            style = style.Background(lv.currentBgColor)
-        }
-        screen.SetCell(i, y, style, event.Runes[textPos])
-        i++
-        textPos++
-        if textPos >= event.styleSpans[spanIndex].end {
+    for pos, r := range event.text() {
+        pos += event.start
+        for spanIndex < len(spans) && spans[spanIndex].end <= pos {
             spanIndex++
         }
+        style := baseStyle
+        if spanIndex < len(spans) && spans[spanIndex].start <= pos {
+            style = spans[spanIndex].style
+            if overrideBg {
+                style = style.Background(bg)
+            }
+        }
+        for matchIndex < len(matches) && matches[matchIndex][1] <= pos {
+            matchIndex++
+        }
+        if matchIndex < len(matches) && matches[matchIndex][0] <= pos {
+            style = lv.searchMatchStyle
+        }
+        screen.SetCell(i, y, style, r)
+        i++
     }
 
     for i <= x+lv.pageWidth+5 {
-        screen.SetCell(i, y, style, ' ')
+        screen.SetCell(i, y, baseStyle, ' ')
         i++
     }
 
@@ -1327,11 +2260,21 @@ func (lv *LogView) printLogLine(screen tcell.Screen, x int, y int, event *logEve
 func (lv *LogView) printLogLineNoHighlights(screen tcell.Screen, x int, y int, event *logEventLine) {
     i := x
     style := lv.defaultStyle
-    if lv.highlightCurrent && event == lv.current { // overwrite bg color for current selected event
-        style = style.Background(lv.currentBgColor)
-    }
-    for pos := event.start; pos < event.end; pos++ {
-        screen.SetCell(i, y, style, event.Runes[pos])
+    if bg, ok := lv.highlightBackground(event); ok { // overwrite bg color for current or selected event
+        style = style.Background(bg)
+    }
+    matches := lv.searchMatchesFor(event)
+    matchIndex := 0
+    for pos, r := range event.text() {
+        pos += event.start
+        for matchIndex < len(matches) && matches[matchIndex][1] <= pos {
+            matchIndex++
+        }
+        if matchIndex < len(matches) && matches[matchIndex][0] <= pos {
+            screen.SetCell(i, y, lv.searchMatchStyle, r)
+        } else {
+            screen.SetCell(i, y, style, r)
+        }
         i++
         if i >= lv.pageWidth {
             break
@@ -1343,6 +2286,17 @@ func (lv *LogView) printLogLineNoHighlights(screen tcell.Screen, x int, y int, e
     }
 }
 
+// highlightBackground returns the background color that overrides styles of the current and selected events
+func (lv *LogView) highlightBackground(event *logEventLine) (tcell.Color, bool) {
+    if lv.highlightCurrent && event == lv.current {
+        return lv.currentBgColor, true
+    }
+    if lv.drawSelected {
+        return lv.selectionBgColor, true
+    }
+    return tcell.ColorDefault, false
+}
+
 func (lv *LogView) clearLine(screen tcell.Screen, x, line int) {
     style := lv.defaultStyle
     i := x
@@ -1353,31 +2307,138 @@ func (lv *LogView) clearLine(screen tcell.Screen, x, line int) {
 }
 
 func (lv *LogView) ensureEventLimit() {
-    if lv.eventLimit == 0 {
+    for lv.isOverLimit() {
+        if lv.store() != nil && !lv.following && lv.firstLine() == findFirstWrappedLine(lv.top) {
+            // do not evict spilled history that is being viewed
+            break
+        }
+        lv.evict(lv.firstEvent, true)
+    }
+    lv.queueSources()
+    if lv.sourceEvents == nil {
+        return
+    }
+    for source, count := range lv.sourceCounts {
+        if limit := lv.sourceLimit(source); limit > 0 && count > limit {
+            lv.evictSource(source, count-limit)
+        }
+    }
+}
+
+// queueSources builds the source queues from the events in memory when the first source limit is set, and drops them
+// when no source limit is left
+func (lv *LogView) queueSources() {
+    if len(lv.sourceLimits) == 0 && lv.defaultSourceLimit == 0 {
+        lv.sourceEvents = nil
+        return
+    }
+    if lv.sourceEvents != nil {
+        return
+    }
+    lv.sourceEvents = make(map[string][]*storedEvent)
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.order <= 1 && !event.fromSpill {
+            lv.sourceEvents[event.Source] = append(lv.sourceEvents[event.Source], event.storedEvent)
+        }
+    }
+}
+
+// sourceLimit returns the event limit of the source, 0 if it is not limited
+func (lv *LogView) sourceLimit(source string) uint {
+    if limit, ok := lv.sourceLimits[source]; ok {
+        return limit
+    }
+    return lv.defaultSourceLimit
+}
+
+// evictSource evicts given number of the oldest events of the source. The events are dropped rather than spilled,
+// because the spill file keeps events in the order they were evicted from the start of the log view.
+func (lv *LogView) evictSource(source string, count uint) {
+    for ; count > 0 && len(lv.sourceEvents[source]) > 0; count-- {
+        lv.evict(lv.sourceEvents[source][0].firstLine, false)
+    }
+}
+
+// evict deletes the event with all its wrapped lines, writing it to the spill store if it is enabled and spill is set.
+// Events are not spilled while a file is open, spilled events are not paged in then and positions in the store are
+// positions in the file.
+func (lv *LogView) evict(event *logEventLine, spill bool) {
+    if event == nil {
         return
     }
-    for lv.eventCount > lv.eventLimit {
-        if lv.firstEvent != nil && lv.firstEvent.order > 0 {
-            lv.mergeWrappedLines(lv.firstEvent)
+    if event.order > 0 {
+        event = lv.mergeWrappedLines(event)
+    }
+    spill = spill && lv.spill != nil && lv.file == nil
+    if !event.fromSpill && (spill || lv.onEvicted != nil) {
+        logEvent := event.AsLogEvent()
+        if spill {
+            if err := lv.spill.append(logEvent, event.repeatCount); err != nil {
+                lv.spillFailed(err)
+            } else {
+                lv.keepStoreMarks(event, lv.spill.count()-1)
+            }
         }
-        lv.deleteEvent(lv.firstEvent, true)
+        if lv.onEvicted != nil {
+            lv.evicted = append(lv.evicted, logEvent)
+        }
+    } else if event.fromSpill {
+        lv.keepStoreMarks(event, event.spillIndex)
+    }
+    lv.deleteEvent(event, true)
+}
+
+// isOverLimit checks whether the first event has to be evicted because of the event limit or retention policy
+func (lv *LogView) isOverLimit() bool {
+    if lv.eventLimit > 0 && lv.eventCount > lv.eventLimit {
+        return true
+    }
+    first := lv.firstEvent
+    if first == nil || first.next == nil || findFirstWrappedLine(lv.lastEvent) == first {
+        // always keep the newest event
+        return false
+    }
+    if lv.retention.MaxBytes > 0 && lv.retainedBytes > lv.retention.MaxBytes {
+        return true
+    }
+    if lv.retention.MaxAge > 0 && first.Timestamp.Before(lv.lastEvent.Timestamp.Add(-lv.retention.MaxAge)) {
+        return true
     }
+    return false
+}
+
+// eventSize returns the size of event message in bytes
+func eventSize(event *logEventLine) int {
+    return len(event.message)
 }
 
 func (lv *LogView) scrollToStart() {
-    lv.top = lv.firstEvent
-    lv.current = lv.firstEvent
+    if store := lv.store(); store != nil && store.count() > 0 {
+        lv.pageIn(0, spillPageSize)
+        lv.fillSpillGaps(lv.firstEvent, lv.pageHeight)
+    }
+    lv.top = lv.firstLine()
+    lv.current = lv.top
     lv.following = false
 }
 
 func (lv *LogView) scrollToEnd() {
-    lv.top = lv.atOffset(lv.lastEvent, -(lv.pageHeight - 1))
-    lv.current = lv.lastEvent
+    if lv.file != nil {
+        count := lv.file.count()
+        if lv.lastEvent == nil || !lv.lastEvent.fromSpill || findFirstWrappedLine(lv.lastEvent).spillIndex < count-1 {
+            lv.pageIn(count-spillPageSize, count)
+        }
+    }
+    lv.current = lv.lastLine()
+    lv.top = lv.atOffset(lv.current, -(lv.pageHeight - 1))
     lv.following = true
 }
 
 func (lv *LogView) scrollOneUp() {
     lv.following = false
+    if first := lv.firstLine(); lv.top == first || lv.current == first {
+        lv.pageInBefore(lv.firstEvent)
+    }
     // if we're at the top of page or current highlighting is off then change the top
     if lv.current == lv.top || !lv.highlightCurrent {
         lv.top = lv.atOffset(lv.top, -1)
@@ -1386,7 +2447,8 @@ func (lv *LogView) scrollOneUp() {
 }
 
 func (lv *LogView) scrollOneDown() {
-    if lv.current == lv.lastEvent {
+    lv.fillSpillGaps(lv.current, 3)
+    if lv.nextVisible(lv.current) == nil {
         lv.following = true
         return
     }
@@ -1407,17 +2469,21 @@ func (lv *LogView) adjustTop() {
 }
 
 func (lv *LogView) scrollPageUp() {
+    if lv.distance(lv.top, lv.firstLine()) < lv.pageHeight {
+        lv.pageInBefore(lv.firstEvent)
+    }
     lv.top = lv.atOffset(lv.top, -lv.pageHeight)
     lv.current = lv.atOffset(lv.current, -lv.pageHeight)
     lv.following = false
 }
 
 func (lv *LogView) scrollPageDown() {
+    lv.fillSpillGaps(lv.top, 2*lv.pageHeight)
     lv.top = lv.atOffset(lv.top, lv.pageHeight)
     lv.current = lv.atOffset(lv.current, lv.pageHeight)
-    if lv.current == lv.lastEvent {
+    if lv.nextVisible(lv.current) == nil {
         lv.following = true
-        lv.top = lv.atOffset(lv.lastEvent, -(lv.pageHeight - 1))
+        lv.top = lv.atOffset(lv.current, -(lv.pageHeight - 1))
     } else {
         lv.following = false
     }
@@ -1430,11 +2496,12 @@ func (lv *LogView) distance(start *logEventLine, target *logEventLine) int {
     distance := 0
     event := start
     for limit > 0 {
-        if event != target {
-            event = event.previous
-        } else {
+        if event == target {
             return distance
         }
+        if event = lv.previousVisible(event); event == nil {
+            return lv.pageHeight
+        }
         distance++
         limit--
     }
@@ -1488,6 +2555,133 @@ func (lv *LogView) findByEventId(eventID string) *logEventLine {
     return event
 }
 
+func (lv *LogView) startSelection() {
+    if lv.current != nil {
+        lv.selectionAnchor = findFirstWrappedLine(lv.current)
+    }
+}
+
+// selectedEvents returns selected events as log events
+func (lv *LogView) selectedEvents() []*LogEvent {
+    events := make([]*LogEvent, 0)
+    first, last, _ := lv.selectionBounds()
+    for event := first; event != nil; event = event.next {
+        if event.order <= 1 && lv.isVisible(event) {
+            events = append(events, event.AsLogEvent())
+        }
+        if event == last {
+            break
+        }
+    }
+    return events
+}
+
+// selectionBounds returns the first line of the first selected event, the last line of the last selected event
+// and whether the top line is selected
+func (lv *LogView) selectionBounds() (*logEventLine, *logEventLine, bool) {
+    if lv.selectionAnchor == nil || lv.current == nil {
+        return nil, nil, false
+    }
+    current := findFirstWrappedLine(lv.current)
+    // selection goes down from the anchor to the current event or the other way around
+    for _, bounds := range [][]*logEventLine{{lv.selectionAnchor, current}, {current, lv.selectionAnchor}} {
+        first, last := bounds[0], findLastWrappedLine(bounds[1])
+        topSelected := false
+        for event := first; event != nil; event = event.next {
+            if event == lv.top && event != first {
+                topSelected = true
+            }
+            if event == last {
+                return first, last, topSelected
+            }
+        }
+    }
+    return nil, nil, false
+}
+
+// isVisible checks whether the event line passes the filters and is displayed
+func (lv *LogView) isVisible(event *logEventLine) bool {
+    return (!event.filteredOut || event.inContext) && isShown(lv.hiddenLevels, lv.hiddenSources, event.Level, event.Source) &&
+        (lv.templateFilter == 0 || event.templateID == lv.templateFilter)
+}
+
+// visiblePredicate wraps the search predicate so that it matches only events that are displayed. It is used for
+// spilled events and lines of the open file, events in memory are checked with isVisible, which includes the
+// context of filter matches.
+func (lv *LogView) visiblePredicate(predicate func(event *LogEvent) bool) func(event *LogEvent) bool {
+    if lv.templateFilter == 0 && len(lv.filters) == 0 && lv.hiddenLevels == [LogLevelAll]bool{} && len(lv.hiddenSources) == 0 {
+        return predicate
+    }
+    // the predicate may be called without holding the lock
+    miner, templateFilter := lv.templateMiner, lv.templateFilter
+    filters := append([]EventFilter(nil), lv.filters...)
+    hiddenLevels, hiddenSources := lv.hiddenLevels, make(map[string]bool, len(lv.hiddenSources))
+    for source := range lv.hiddenSources {
+        hiddenSources[source] = true
+    }
+    return func(event *LogEvent) bool {
+        if templateFilter != 0 && (miner == nil || miner.Match(event.Message) != templateFilter) {
+            return false
+        }
+        return isShown(hiddenLevels, hiddenSources, event.Level, event.Source) && matchesFilters(filters, event) && predicate(event)
+    }
+}
+
+// nextVisible returns the next visible line after the event or nil if there is none
+func (lv *LogView) nextVisible(event *logEventLine) *logEventLine {
+    for event = event.next; event != nil && !lv.isVisible(event); event = event.next {
+    }
+    return event
+}
+
+// previousVisible returns the previous visible line before the event or nil if there is none
+func (lv *LogView) previousVisible(event *logEventLine) *logEventLine {
+    for event = event.previous; event != nil && !lv.isVisible(event); event = event.previous {
+    }
+    return event
+}
+
+// firstLine returns the first visible line, or the first line if no line is visible
+func (lv *LogView) firstLine() *logEventLine {
+    if lv.firstEvent == nil || lv.isVisible(lv.firstEvent) {
+        return lv.firstEvent
+    }
+    if event := lv.nextVisible(lv.firstEvent); event != nil {
+        return event
+    }
+    return lv.firstEvent
+}
+
+// lastLine returns the last visible line, or the last line if no line is visible
+func (lv *LogView) lastLine() *logEventLine {
+    if lv.lastEvent == nil || lv.isVisible(lv.lastEvent) {
+        return lv.lastEvent
+    }
+    if event := lv.previousVisible(lv.lastEvent); event != nil {
+        return event
+    }
+    return lv.lastEvent
+}
+
+// revealCurrent moves the current event to the nearest visible event after filters change and scrolls to it
+func (lv *LogView) revealCurrent() {
+    if lv.current == nil {
+        return
+    }
+    if lv.following {
+        lv.scrollToEnd()
+        return
+    }
+    if !lv.isVisible(lv.current) {
+        if event := lv.nextVisible(lv.current); event != nil {
+            lv.current = event
+        } else {
+            lv.current = lv.lastLine()
+        }
+    }
+    lv.top = lv.atOffset(lv.current, -lv.pageHeight/4)
+}
+
 func (lv *LogView) isLastLine(event *logEventLine) bool {
     return lv.distance(lv.top, event) >= lv.pageHeight
 }
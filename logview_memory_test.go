@@ -0,0 +1,42 @@
+package clogviewr
+
+import (
+    "fmt"
+    "runtime"
+    "strconv"
+    "testing"
+    "time"
+)
+
+// BenchmarkLogView_Memory reports retained heap per event for 1M events of about 100 characters,
+// a third of them wrapped into two lines. It only uses the API that predates the string message layout, so that the
+// file can be copied to an older commit to measure a baseline, see the bench_memory make target.
+func BenchmarkLogView_Memory(b *testing.B) {
+    const count = 1_000_000
+    for n := 0; n < b.N; n++ {
+        var before, after runtime.MemStats
+        runtime.GC()
+        runtime.ReadMemStats(&before)
+
+        lv := NewLogView()
+        lv.SetHighlightPattern(`(?P<g1>Event)\s+(?P<g2>#\d+)`)
+        lv.pageWidth = 100
+        lv.SetLineWrap(true)
+        ts := time.Now().Add(-24 * time.Hour)
+        for i := 0; i < count; i++ {
+            suffix := ""
+            if i%3 == 0 {
+                suffix = " and some more details that do not fit in a single line"
+            }
+            event := NewLogEvent("e"+strconv.Itoa(i), fmt.Sprintf("Event #%d request GET /api/v1/items/%d completed with status 200 in %dms%s", i, i, i%1000, suffix))
+            event.Source = "api"
+            event.Timestamp = ts.Add(time.Duration(i) * time.Millisecond)
+            lv.AppendEvent(event)
+        }
+
+        runtime.GC()
+        runtime.ReadMemStats(&after)
+        b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/count, "bytes/event")
+        runtime.KeepAlive(lv)
+    }
+}
@@ -2,7 +2,9 @@ package clogviewr
 
 import (
     "github.com/gdamore/tcell/v2"
+    "path/filepath"
     "strconv"
+    "strings"
     "testing"
     "time"
 )
@@ -104,6 +106,176 @@ func TestLogView_SetMaxEventsAfter(t *testing.T) {
     }
 }
 
+func TestLogView_SourceEventLimit(t *testing.T) {
+    lv := NewLogView()
+    lv.SetSourceEventLimit("db", 5)
+    lv.SetDefaultSourceEventLimit(20)
+    events := randomEvents(100, time.Now().Add(-24*time.Hour))
+    for i, event := range events {
+        switch {
+        case i%10 == 0:
+            event.Source = "auth"
+        case i%10 == 1:
+            event.Source = "db"
+        default:
+            event.Source = "api"
+        }
+    }
+    lv.AppendEvents(events)
+
+    counts := lv.GetSourceEventCounts()
+    if counts["auth"] != 10 || counts["db"] != 5 || counts["api"] != 20 || lv.EventCount() != 35 {
+        t.Errorf("Invalid per source counts: %v", counts)
+    }
+    if lv.firstEvent.EventID != "e0" {
+        t.Errorf("Quiet source must keep its history, first event is %s", lv.firstEvent.EventID)
+    }
+    if found := lv.findByEventId("e51"); found == nil || found.previous.Source != "auth" {
+        t.Errorf("Expected the oldest db events to be evicted")
+    }
+
+    lv.SetSourceEventLimit("db", 0)
+    lv.SetDefaultSourceEventLimit(0)
+    lv.AppendEvents(randomEvents(10, time.Now()))
+    if lv.GetSourceEventCounts()[""] != 10 {
+        t.Errorf("Unlimited source must keep all events")
+    }
+    if lv.sourceEvents != nil {
+        t.Errorf("Expected the source queues to be dropped with the last source limit")
+    }
+}
+
+func TestLogView_SourceEventLimitQueues(t *testing.T) {
+    lv := NewLogView()
+    if err := lv.SetSpillFile(filepath.Join(t.TempDir(), "spill.log")); err != nil {
+        t.Fatalf("Failed to create spill file: %v", err)
+    }
+    defer lv.SetSpillFile("")
+    lv.SetMaxEvents(20)
+    lv.SetLineWrap(true)
+    lv.pageWidth = 4
+    events := randomEvents(100, time.Now().Add(-24*time.Hour))
+    for i, event := range events {
+        if i%2 == 0 {
+            event.Source = "db"
+        }
+    }
+    lv.AppendEvents(events[:50])
+    if lv.sourceEvents != nil {
+        t.Errorf("Events must not be queued without a source limit")
+    }
+    lv.SetDefaultSourceEventLimit(100)
+    if queued := len(lv.sourceEvents["db"]); queued != 10 {
+        t.Errorf("Expected the source queues to be built when a source limit is set, %d queued", queued)
+    }
+    lv.AppendEvents(events[50:])
+    if queued := len(lv.sourceEvents["db"]); queued != 10 {
+        t.Errorf("Events evicted by the event limit must leave the source queue, %d queued", queued)
+    }
+
+    // paged in events are not counted, so they are not evicted by the source limit again
+    lv.ScrollToEventID("e10")
+    lv.SetSourceEventLimit("db", 4)
+    counts := lv.GetSourceEventCounts()
+    if counts["db"] != 4 || counts[""] != 10 || lv.findByEventId("e10") == nil {
+        t.Errorf("Invalid per source counts with paged in events: %v", counts)
+    }
+    if found := lv.findByEventId("e93"); found == nil || found.next.order <= 1 || found.previous.EventID != "e92" {
+        t.Errorf("Expected the oldest wrapped db events to be evicted")
+    }
+}
+
+func TestLogView_SourceEventLimitSpill(t *testing.T) {
+    lv := NewLogView()
+    if err := lv.SetSpillFile(filepath.Join(t.TempDir(), "spill.log")); err != nil {
+        t.Fatalf("Failed to create spill file: %v", err)
+    }
+    defer lv.SetSpillFile("")
+    evicted := 0
+    lv.SetOnEvicted(func(events []*LogEvent) {
+        evicted += len(events)
+    })
+    lv.SetMaxEvents(20)
+    lv.SetSourceEventLimit("db", 4)
+    events := randomEvents(100, time.Now().Add(-24*time.Hour))
+    for i, event := range events {
+        if i%2 == 0 {
+            event.Source = "db"
+        }
+    }
+    lv.AppendEvents(events)
+
+    // events evicted by the source limit are dropped, so the spill file stays in the order of the events
+    var previous time.Time
+    err := lv.spill.scan(0, lv.spill.count(), func(index int, event *LogEvent) bool {
+        if event.Source == "db" || event.Timestamp.Before(previous) {
+            t.Errorf("Unexpected spilled event %s of source %s", event.EventID, event.Source)
+        }
+        previous = event.Timestamp
+        return true
+    })
+    if err != nil {
+        t.Fatalf("Failed to scan spill file: %v", err)
+    }
+    if lv.spill.count() != 34 || evicted != 80 {
+        t.Errorf("Expected 34 spilled and 80 evicted events, got %d spilled and %d evicted", lv.spill.count(), evicted)
+    }
+    if !lv.ScrollToTimestamp(events[31].Timestamp) || lv.GetCurrentEvent().EventID != "e31" {
+        t.Errorf("Expected to scroll to spilled event e31, got %v", lv.GetCurrentEvent())
+    }
+    for event := lv.firstEvent; event.next != nil; event = event.next {
+        if event.next.Timestamp.Before(event.Timestamp) {
+            t.Errorf("Event %s is displayed after %s", event.next.EventID, event.EventID)
+        }
+    }
+}
+
+func TestLogView_RetentionMaxAge(t *testing.T) {
+    lv := NewLogView()
+    evicted := make([]*LogEvent, 0)
+    lv.SetOnEvicted(func(events []*LogEvent) {
+        evicted = append(evicted, events...)
+    })
+    lv.SetRetentionPolicy(RetentionPolicy{MaxAge: 10 * time.Second})
+    lv.AppendEvents(randomEvents(100, time.Now().Add(-24*time.Hour)))
+
+    if lv.EventCount() != 11 || lv.firstEvent.EventID != "e89" {
+        t.Errorf("Expected 11 events starting with e89, got %d starting with %s", lv.EventCount(), lv.firstEvent.EventID)
+    }
+    if len(evicted) != 89 || evicted[0].EventID != "e0" || evicted[88].EventID != "e88" {
+        t.Errorf("Expected 89 evicted events, got %d", len(evicted))
+    }
+}
+
+func TestLogView_RetentionMaxBytes(t *testing.T) {
+    lv := NewLogView()
+    lv.AppendEvents(randomEvents(100, time.Now().Add(-24*time.Hour)))
+    // messages of the last 10 events "Event #90" .. "Event #99" are 9 bytes each
+    lv.SetRetentionPolicy(RetentionPolicy{MaxBytes: 90})
+
+    if lv.EventCount() != 10 || lv.firstEvent.EventID != "e90" || lv.GetRetainedBytes() != 90 {
+        t.Errorf("Expected 10 events starting with e90, got %d starting with %s, %d bytes",
+            lv.EventCount(), lv.firstEvent.EventID, lv.GetRetainedBytes())
+    }
+
+    lv.AppendEvent(NewLogEvent("big", strings.Repeat("x", 200)))
+    if lv.EventCount() != 1 || lv.firstEvent.EventID != "big" {
+        t.Errorf("The newest event must always be retained, got %d events", lv.EventCount())
+    }
+}
+
+func TestLogView_RetentionEvictsCurrent(t *testing.T) {
+    lv := NewLogView()
+    lv.AppendEvents(randomEvents(10, time.Now().Add(-24*time.Hour)))
+    lv.SetFollowing(false)
+    lv.ScrollToEventID("e0")
+    lv.SetRetentionPolicy(RetentionPolicy{MaxBytes: 72})
+
+    if lv.GetCurrentEvent() == nil || lv.GetCurrentEvent().EventID != "e1" || lv.top == nil || lv.top.EventID != "e1" {
+        t.Errorf("Expected the top and current event to move to the next event when the first one is evicted, got %v", lv.GetCurrentEvent())
+    }
+}
+
 func TestLogView_Highlighting(t *testing.T) {
     lv := NewLogView()
     lv.SetHighlightPattern(`(?P<ts>\d{2}:\d{2}:\d{2}.\d{3})\s+\[(?P<thread>.*)\]\s+(?P<level>\S+)\s+(?P<class>[a-zA-Z0-9_.]+).*(?:in (?P<elapsed>\d+)ms)?`)
@@ -141,6 +313,33 @@ func TestLogView_ConcatenateEvents(t *testing.T) {
     }
 }
 
+func TestLogView_CollapseDuplicates(t *testing.T) {
+    lv := NewLogView()
+    lv.SetCollapseDuplicates(true)
+
+    lv.AppendEvent(NewLogEvent("1", "connection refused, retry 1"))
+    lv.AppendEvent(NewLogEvent("2", "connection refused, retry 1"))
+    lv.AppendEvent(NewLogEvent("3", "connection refused, retry 1"))
+    lv.AppendEvent(NewLogEvent("4", "connection refused, retry 2"))
+
+    if lv.EventCount() != 2 || lv.firstEvent.repeatCount != 3 || lv.lastEvent.repeatCount != 0 {
+        t.Errorf("Expected 2 events with the first repeated 3 times, got %d events", lv.EventCount())
+    }
+
+    lv.SetMaskDuplicates(true)
+    lv.AppendEvent(NewLogEvent("5", "connection refused, retry 3"))
+    if lv.EventCount() != 2 || lv.lastEvent.repeatCount != 2 {
+        t.Errorf("Expected masked duplicate to be collapsed, got %d events", lv.EventCount())
+    }
+
+    event := NewLogEvent("6", "connection refused, retry 3")
+    event.Level = LogLevelError
+    lv.AppendEvent(event)
+    if lv.EventCount() != 3 {
+        t.Errorf("Events with different levels must not be collapsed")
+    }
+}
+
 func TestLogView_WrapEvent(t *testing.T) {
     lv := NewLogView()
     lv.pageWidth = 20
@@ -158,15 +357,15 @@ func TestLogView_WrapEvent(t *testing.T) {
     }
 
     e := lv.firstEvent.next.next
-    if string(e.Runes[e.start:e.end]) != "Line is wide але it " || e.lineCount != 3 || e.order != 1 {
+    if e.text() != "Line is wide але it " || e.lineCount != 3 || e.order != 1 {
         t.Errorf("Invalid first line")
     }
     e = e.next
-    if string(e.Runes[e.start:e.end]) != "has a\n" || e.order != 2 {
+    if e.text() != "has a\n" || e.order != 2 {
         t.Errorf("Invalid second line")
     }
     e = e.next
-    if string(e.Runes[e.start:e.end]) != "new line" || e.order != 3 {
+    if e.text() != "new line" || e.order != 3 {
         t.Errorf("Invalid third line")
     }
 }
@@ -183,16 +382,20 @@ func TestLogView_replaceEvents(t *testing.T) {
 
     ne := lv.replaceEvent(toReplace, []*logEventLine{
         {
-            EventID:   "2",
-            order:     1,
-            lineCount: 2,
-            Runes:     []rune("Test2.1"),
+            storedEvent: &storedEvent{
+                EventID:   "2",
+                message:   "Test2.1",
+                lineCount: 2,
+            },
+            order: 1,
         },
         {
-            EventID:   "2",
-            order:     2,
-            lineCount: 2,
-            Runes:     []rune("Test2.2"),
+            storedEvent: &storedEvent{
+                EventID:   "2",
+                message:   "Test2.2",
+                lineCount: 2,
+            },
+            order: 2,
         },
     })
 
@@ -200,7 +403,7 @@ func TestLogView_replaceEvents(t *testing.T) {
         t.Errorf("Should not change event count")
     }
     ne = ne.previous
-    if ne.order != 1 && string(ne.Runes) != "Test2.1" && ne.previous != lv.firstEvent {
+    if ne.order != 1 && ne.message != "Test2.1" && ne.previous != lv.firstEvent {
         t.Errorf("Invalid first replacement event")
     }
     if lv.current != ne {
@@ -210,7 +413,7 @@ func TestLogView_replaceEvents(t *testing.T) {
         t.Errorf("First event's next must point to the new replacement")
     }
     ne = ne.next
-    if ne.order != 2 && string(ne.Runes) != "Test2.2" && ne.next != lv.lastEvent {
+    if ne.order != 2 && ne.message != "Test2.2" && ne.next != lv.lastEvent {
         t.Errorf("Invalid first replacement event")
     }
     if lv.lastEvent.previous != ne {
@@ -229,16 +432,20 @@ func TestLogView_replaceLastEvent(t *testing.T) {
 
     ne := lv.replaceEvent(toReplace, []*logEventLine{
         {
-            EventID:   "2",
-            order:     1,
-            lineCount: 2,
-            Runes:     []rune("Test2.1"),
+            storedEvent: &storedEvent{
+                EventID:   "2",
+                message:   "Test2.1",
+                lineCount: 2,
+            },
+            order: 1,
         },
         {
-            EventID:   "2",
-            order:     2,
-            lineCount: 2,
-            Runes:     []rune("Test2.2"),
+            storedEvent: &storedEvent{
+                EventID:   "2",
+                message:   "Test2.2",
+                lineCount: 2,
+            },
+            order: 2,
         },
     })
 
@@ -246,7 +453,7 @@ func TestLogView_replaceLastEvent(t *testing.T) {
         t.Errorf("Should not change event count")
     }
     ne = ne.previous
-    if ne.order != 1 && string(ne.Runes) != "Test2.1" && ne.previous != lv.firstEvent {
+    if ne.order != 1 && ne.message != "Test2.1" && ne.previous != lv.firstEvent {
         t.Errorf("Invalid first replacement event")
     }
     if lv.current != ne {
@@ -256,7 +463,7 @@ func TestLogView_replaceLastEvent(t *testing.T) {
         t.Errorf("First event's next must point to the new replacement")
     }
     ne = ne.next
-    if ne.order != 2 && string(ne.Runes) != "Test2.2" && ne.next != nil {
+    if ne.order != 2 && ne.message != "Test2.2" && ne.next != nil {
         t.Errorf("Invalid first replacement event")
     }
     if lv.lastEvent != ne {
@@ -275,16 +482,20 @@ func TestLogView_replaceFirstEvent(t *testing.T) {
 
     ne := lv.replaceEvent(toReplace, []*logEventLine{
         {
-            EventID:   "2",
-            order:     1,
-            lineCount: 2,
-            Runes:     []rune("Test2.1"),
+            storedEvent: &storedEvent{
+                EventID:   "2",
+                message:   "Test2.1",
+                lineCount: 2,
+            },
+            order: 1,
         },
         {
-            EventID:   "2",
-            order:     2,
-            lineCount: 2,
-            Runes:     []rune("Test2.2"),
+            storedEvent: &storedEvent{
+                EventID:   "2",
+                message:   "Test2.2",
+                lineCount: 2,
+            },
+            order: 2,
         },
     })
 
@@ -292,7 +503,7 @@ func TestLogView_replaceFirstEvent(t *testing.T) {
         t.Errorf("Should not change event count")
     }
     ne = ne.previous
-    if ne.order != 1 && string(ne.Runes) != "Test2.1" && ne.previous != nil {
+    if ne.order != 1 && ne.message != "Test2.1" && ne.previous != nil {
         t.Errorf("Invalid first replacement event")
     }
     if lv.current != ne {
@@ -302,7 +513,7 @@ func TestLogView_replaceFirstEvent(t *testing.T) {
         t.Errorf("First event must point to the new replacement")
     }
     ne = ne.next
-    if ne.order != 2 && string(ne.Runes) != "Test2.2" && ne.next != lv.lastEvent {
+    if ne.order != 2 && ne.message != "Test2.2" && ne.next != lv.lastEvent {
         t.Errorf("Invalid first replacement event")
     }
     if lv.lastEvent != ne {
@@ -320,7 +531,7 @@ func TestLogView_mergeWrappedLines(t *testing.T) {
     }
     e := lv.firstEvent.next
     e1 := lv.mergeWrappedLines(e)
-    if string(e1.Runes) != "This is a rather long event and it should be wrapped" {
+    if e1.message != "This is a rather long event and it should be wrapped" {
         t.Errorf("Invalid text in unwrapped event")
     }
     if lv.firstEvent != e1 && lv.lastEvent != e1 {
@@ -349,6 +560,72 @@ func TestLogView_FindMatchingEvent(t *testing.T) {
     }
 }
 
+func TestLogView_FloodPolicy(t *testing.T) {
+    lv := NewLogView()
+    lv.SetFloodPolicy(FloodPolicy{MaxRate: 10})
+
+    ts := time.Now()
+    events := randomEvents(100, ts)
+    events[50].Level = LogLevelError
+    lv.AppendEvents(events)
+
+    if lv.EventCount() != 11 || lv.GetDroppedEventCount() != 89 {
+        t.Errorf("Expected 11 events and 89 dropped, got %d events and %d dropped", lv.EventCount(), lv.GetDroppedEventCount())
+    }
+    if lv.lastEvent.EventID != "e50" {
+        t.Errorf("Error event must always be kept, last event is %s", lv.lastEvent.EventID)
+    }
+
+    // next window
+    lv.flood.windowStart = lv.flood.windowStart.Add(-2 * time.Second)
+    lv.AppendEvent(NewLogEvent("next", "Next event"))
+
+    marker := lv.lastEvent.previous
+    if marker.message != "89 events dropped" || marker.Level != LogLevelWarning {
+        t.Errorf("Expected dropped events marker, got '%s'", marker.message)
+    }
+    if lv.EventCount() != 13 {
+        t.Errorf("Expected 13 events, got %d", lv.EventCount())
+    }
+}
+
+func TestLogView_FloodPolicyFlush(t *testing.T) {
+    lv := NewLogView()
+    lv.SetFloodPolicy(FloodPolicy{MaxRate: 10})
+    lv.AppendEvents(randomEvents(30, time.Now()))
+    if lv.flood.flushTimer == nil {
+        t.Fatalf("Expected the dropped events marker to be scheduled")
+    }
+    lv.flood.flushTimer.Stop()
+
+    // the storm is over, no event follows
+    lv.flushDroppedEvents()
+    if lv.EventCount() != 10 {
+        t.Errorf("Expected the marker to wait for the end of the window, got %d events", lv.EventCount())
+    }
+    lv.flood.windowStart = lv.flood.windowStart.Add(-2 * time.Second)
+    lv.flushDroppedEvents()
+    marker := lv.lastEvent
+    if marker.message != "20 events dropped" || marker.EventID != DroppedEventIDPrefix+"1" {
+        t.Errorf("Expected dropped events marker, got %s '%s'", marker.EventID, marker.message)
+    }
+    lv.flushDroppedEvents()
+    if lv.EventCount() != 11 {
+        t.Errorf("Expected a single marker, got %d events", lv.EventCount())
+    }
+}
+
+func TestLogView_FloodPolicySampling(t *testing.T) {
+    lv := NewLogView()
+    lv.SetFloodPolicy(FloodPolicy{MaxRate: 10, SampleEvery: 10})
+
+    lv.AppendEvents(randomEvents(110, time.Now()))
+
+    if lv.EventCount() != 20 || lv.GetDroppedEventCount() != 90 {
+        t.Errorf("Expected 20 events and 90 dropped, got %d events and %d dropped", lv.EventCount(), lv.GetDroppedEventCount())
+    }
+}
+
 func BenchmarkLogView(b *testing.B) {
     screen := tcell.NewSimulationScreen("UTF-8")
     lv := NewLogView()
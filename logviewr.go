@@ -7,8 +7,12 @@ import (
     "github.com/alexj212/gox"
     "github.com/gdamore/tcell/v2"
     "log"
+    "os"
+    "sort"
+    "strconv"
     "strings"
     "sync"
+    "sync/atomic"
     "time"
 )
 
@@ -26,26 +30,55 @@ type UI struct {
     detailsModal    *cview.Modal
     helpModal       *cview.Modal
     inputField      *cview.InputField
+    templateView    *TemplateView
+    searchResults   *SearchResultsView
+    statsView       *StatsView
+    sourcePicker    *cview.List
     focusManager    *cview.FocusManager
     cmdExecFunc     CmdExecFunc
     mode            AppMode
+    // shownMode mirrors mode for listeners of the log view, which may be called by commands holding the lock
+    shownMode       int32
     cmdHistory      []string
     cmdHistoryPos   int
+    commands        map[string]CmdExecFunc
+
+    replay        *Replay
+    templateMiner *TemplateMiner
 
     lastSearch           string
     lastSearchEventIDHit string
+    lastSearchBackward   bool
+
+    // named views are saved to viewsPath, currentView is the index of the view switched to last
+    views       []View
+    viewsPath   string
+    currentView int
+
+    incrementalSearchEnabled bool
+    incrementalSearch        *incrementalSearch
 
     detailsGenerator func(evt *LogEvent) (text string)
     sync.RWMutex
 }
 
+// highlightRuleColors are used in turn for highlight rules added without a colour
+var highlightRuleColors = []tcell.Color{tcell.ColorNavy, tcell.ColorDarkGreen, tcell.ColorPurple, tcell.ColorTeal, tcell.ColorOlive}
+
 const (
     ExitModal AppMode = iota
     InfoDialogModal
     CommandEntry
     LogViewer
+    TemplateList
+    SourcePicker
+    SearchResults
+    StatsTable
 )
 
+// levelToggleKeys toggle the visibility of levels in the log viewer
+var levelToggleKeys = map[string]LogLevel{"I": LogLevelInfo, "W": LogLevelWarning, "E": LogLevelError}
+
 func (ui *UI) IsLogViewerVisible() bool {
     ui.RLock()
     defer ui.RUnlock()
@@ -64,16 +97,46 @@ func (ui *UI) IsCommandEntryVisible() bool {
     return ui.mode == CommandEntry
 }
 
+func (ui *UI) IsTemplateListVisible() bool {
+    ui.RLock()
+    defer ui.RUnlock()
+    return ui.mode == TemplateList
+}
+
+func (ui *UI) IsSourcePickerVisible() bool {
+    ui.RLock()
+    defer ui.RUnlock()
+    return ui.mode == SourcePicker
+}
+
+func (ui *UI) IsSearchResultsVisible() bool {
+    ui.RLock()
+    defer ui.RUnlock()
+    return ui.mode == SearchResults
+}
+
+func (ui *UI) IsStatsTableVisible() bool {
+    ui.RLock()
+    defer ui.RUnlock()
+    return ui.mode == StatsTable
+}
+
 func (ui *UI) IsExitModalVisible() bool {
     ui.RLock()
     defer ui.RUnlock()
     return ui.mode == ExitModal
 }
 
+// setMode sets the mode of the UI, the UI must be locked
+func (ui *UI) setMode(mode AppMode) {
+    ui.mode = mode
+    atomic.StoreInt32(&ui.shownMode, int32(mode))
+}
+
 func (ui *UI) ShowExitModal() {
     ui.Lock()
     defer ui.Unlock()
-    ui.mode = ExitModal
+    ui.setMode(ExitModal)
     ui.exitModal.SetVisible(true)
     ui.app.SetRoot(ui.exitModal, true)
     ui.app.QueueUpdateDraw(func() {})
@@ -107,7 +170,7 @@ func (ui *UI) ShowDetailsModal(title, message string) {
     ui.Lock()
     defer ui.Unlock()
 
-    ui.mode = InfoDialogModal
+    ui.setMode(InfoDialogModal)
 
     ui.detailsModal.SetTitle(title)
     ui.detailsModal.SetText(message)
@@ -115,15 +178,75 @@ func (ui *UI) ShowDetailsModal(title, message string) {
     ui.app.QueueUpdateDraw(func() {})
 }
 
+// ShowTemplates shows the list of message templates, selecting a template filters the log view to its events.
+// Templates are mined from the first time the list is shown on, starting with the events in memory.
+func (ui *UI) ShowTemplates() {
+    ui.Lock()
+    defer ui.Unlock()
+
+    if ui.templateMiner == nil {
+        ui.startTemplateMining()
+    }
+    ui.setMode(TemplateList)
+    ui.app.SetRoot(ui.templateView, true)
+    ui.app.SetFocus(ui.templateView)
+    ui.app.QueueUpdateDraw(func() {})
+}
+
+// ShowSourcePicker shows the list of sources with their event counts, selecting a source shows or hides its events
+func (ui *UI) ShowSourcePicker() {
+    ui.Lock()
+    defer ui.Unlock()
+
+    ui.setMode(SourcePicker)
+    ui.refreshSourcePicker()
+    ui.app.SetRoot(ui.sourcePicker, true)
+    ui.app.SetFocus(ui.sourcePicker)
+    ui.app.QueueUpdateDraw(func() {})
+}
+
+// ShowSearchResults shows the list of events matching the last search, selecting an event scrolls the log view to it
+func (ui *UI) ShowSearchResults() {
+    ui.Lock()
+    defer ui.Unlock()
+
+    ui.setMode(SearchResults)
+    ui.app.SetRoot(ui.searchResults, true)
+    ui.app.SetFocus(ui.searchResults)
+    ui.app.QueueUpdateDraw(func() {})
+}
+
+// ShowStats shows the table of the last stats, selecting a count filters the log view to its events
+func (ui *UI) ShowStats() {
+    ui.Lock()
+    defer ui.Unlock()
+
+    ui.setMode(StatsTable)
+    ui.app.SetRoot(ui.statsView, true)
+    ui.app.SetFocus(ui.statsView)
+    ui.app.QueueUpdateDraw(func() {})
+}
+
+// ToggleLevel shows or hides events of the level
+func (ui *UI) ToggleLevel(level LogLevel) {
+    visible := !ui.logView.IsLevelVisible(level)
+    ui.logView.SetLevelVisible(level, visible)
+    if visible {
+        ui.SetStatusViewText(fmt.Sprintf("Showing %s events", level))
+    } else {
+        ui.SetStatusViewText(fmt.Sprintf("Hiding %s events, %s shows them again", level, strings.ToUpper(level.String()[:1])))
+    }
+}
+
 func (ui *UI) ShowInputField() {
     ui.Lock()
     defer ui.Unlock()
 
-    ui.mode = CommandEntry
+    ui.setMode(CommandEntry)
     ui.inputField.SetText("")
     ui.inputField.SetVisible(true)
     ui.focusManager.Focus(ui.inputField)
-    ui.SetStatusViewText("Enter a command: :top, :1, :bottom, /<expression>, exit etc.")
+    ui.SetStatusViewText("Enter a command: :top, :bottom, :<n>, :+n, :-n, :50%, :@15:04:05, :-5m, :#<id>, :replay <file> [timestamp layout], :replay <pause|resume|step|speed|stop>, :save <file>, :load <file> [timestamp layout], :export <format> <file> [selection|filtered], :copy [message|event|selection], :dedup <on|off|mask>, :templates [off], :open <file> [timestamp layout], :filter [pop|clear], :context [-B n] [-A n] [n] [30s]|off, :sources [all], :view [save|delete] [name], V next view, I/W/E toggle levels, S picks sources, :results [off], :stats by <source|level|field> [pattern], :bookmarks [clear], b bookmarks, [ ] previous/next bookmark, m<letter> sets a mark, '<letter> jumps to it, :count <pattern>, :hl [color] <pattern>|off, :nohl, /<expression>, ?<expression> to search backwards (n/N repeat), /\\rxicswSD <text> for regexp, case, whole word, source and data options, \\q <query> for a query, &<text> to filter, & to pop a filter, exit etc.")
     ui.app.SetRoot(ui.logScreenLayout, true)
     ui.app.QueueUpdateDraw(func() {})
 
@@ -134,7 +257,7 @@ func (ui *UI) ShowLogViewer() {
     ui.Lock()
     defer ui.Unlock()
 
-    ui.mode = LogViewer
+    ui.setMode(LogViewer)
 
     ui.SetStatusViewText("")
     ui.inputField.SetVisible(false)
@@ -153,7 +276,11 @@ func wrap(f func()) func(ev *tcell.EventKey) *tcell.EventKey {
 
 // CreateAppUI creates base UI layout
 func CreateAppUI() *UI {
-    ui := &UI{}
+    ui := &UI{
+        commands:                 make(map[string]CmdExecFunc),
+        incrementalSearchEnabled: true,
+        currentView:              -1,
+    }
 
     ui.app = cview.NewApplication()
     ui.app.EnableMouse(true)
@@ -162,11 +289,76 @@ func CreateAppUI() *UI {
     ui.logView.SetBorder(false)
     ui.logView.SetLineWrap(false)
 
+    // copied text reaches the terminal between screen updates
+    clipboard := NewTerminalClipboard(nil)
+    ui.logView.SetClipboardWriter(clipboard)
+    ui.app.SetBeforeDrawFunc(func(_ tcell.Screen) bool {
+        _ = clipboard.Flush()
+        return false
+    })
+    ui.incrementalSearch = newIncrementalSearch(ui.logView, ui.SetStatusViewText, func(f func()) {
+        ui.app.QueueUpdateDraw(f)
+    })
+
     ui.histogram = NewLogVelocityView(1 * time.Second)
 
+    ui.searchResults = NewSearchResultsView()
+    ui.searchResults.SetBorder(true)
+    ui.searchResults.SetOnResultSelected(func(event *LogEvent) {
+        ui.ShowLogViewer()
+        if !ui.logView.ScrollToEventID(event.EventID) {
+            ui.SetStatusViewText(fmt.Sprintf("EventID: %s is no longer in the log view", event.EventID))
+            return
+        }
+        // the result stays in view as matching events arrive
+        ui.logView.SetFollowing(false)
+        ui.lastSearchEventIDHit = event.EventID
+        ui.SetStatusViewText(fmt.Sprintf("EventID: %s, n/N continue the search from here", event.EventID))
+    })
+    ui.statsView = NewStatsView()
+    ui.statsView.SetBorder(true)
+    ui.statsView.SetOnStatsSelected(func(stats *Stats, count StatsCount) {
+        pattern := stats.FilterPattern(count.Key)
+        predicate, err := ParsePattern(pattern)
+        ui.ShowLogViewer()
+        if err != nil {
+            ui.SetStatusViewText(err.Error())
+            return
+        }
+        ui.logView.PushFilter(EventFilter{Name: pattern, Pattern: pattern, Predicate: predicate})
+        ui.SetStatusViewText(fmt.Sprintf("Showing %d events of %s %s, & pops the filter", count.Count, stats.By, cview.Escape(count.Key)))
+    })
+    ui.logView.SetOnAppended(func(events []*LogEvent) {
+        // events are also appended by commands holding the lock, i.e. :replay step, so the mode is read without it
+        if ui.searchResults.AddEvents(events) && AppMode(atomic.LoadInt32(&ui.shownMode)) == SearchResults {
+            ui.app.QueueUpdateDraw(func() {})
+        }
+    })
+
+    ui.logView.SetOnFileProgress(func(progress FileProgress) {
+        ui.app.QueueUpdateDraw(func() {
+            ui.SetStatusViewText(fileProgressStatus(progress))
+        })
+    })
+
+    ui.sourcePicker = cview.NewList()
+    ui.sourcePicker.SetBorder(true)
+    ui.sourcePicker.SetTitle("Sources, Enter shows or hides a source")
+    ui.sourcePicker.ShowSecondaryText(false)
+    ui.sourcePicker.SetSelectedFunc(func(index int, item *cview.ListItem) {
+        if source, ok := item.GetReference().(string); ok {
+            ui.logView.SetSourceVisible(source, !ui.logView.IsSourceVisible(source))
+        } else {
+            ui.logView.ShowAllSources()
+        }
+        ui.refreshSourcePicker()
+        ui.SetStatusViewText("")
+    })
+
     ui.inputField = cview.NewInputField()
     ui.inputField.SetLabel("")
     ui.inputField.SetFieldWidth(0)
+    ui.inputField.SetChangedFunc(ui.handleCommandChanged)
 
     ui.statusView = cview.NewTextView()
     ui.statusView.SetText("")
@@ -244,12 +436,13 @@ func CreateAppUI() *UI {
     inputHandler.SetKey(tcell.ModNone, tcell.KeyESC, func(ev *tcell.EventKey) *tcell.EventKey {
 
         if ui.IsCommandEntryVisible() {
+            ui.incrementalSearch.cancel()
             ui.inputField.SetText("")
             ui.ShowLogViewer()
             return nil
         }
 
-        if ui.IsInfoDialogModalVisible() {
+        if ui.IsInfoDialogModalVisible() || ui.IsTemplateListVisible() || ui.IsSourcePickerVisible() || ui.IsSearchResultsVisible() || ui.IsStatsTableVisible() {
             ui.ShowLogViewer()
             return nil
         }
@@ -307,6 +500,48 @@ func CreateAppUI() *UI {
         return ev
     })
 
+    searchNext := func(reverse bool) func(ev *tcell.EventKey) *tcell.EventKey {
+        return func(ev *tcell.EventKey) *tcell.EventKey {
+            if !ui.IsLogViewerVisible() {
+                return ev
+            }
+            ui.Lock()
+            defer ui.Unlock()
+
+            ui.HandleSearchNext(reverse)
+            return nil
+        }
+    }
+    for key, level := range levelToggleKeys {
+        level := level
+        inputHandler.Set(key, func(ev *tcell.EventKey) *tcell.EventKey {
+            if !ui.IsLogViewerVisible() {
+                return ev
+            }
+            ui.ToggleLevel(level)
+            return nil
+        })
+    }
+    inputHandler.Set("S", func(ev *tcell.EventKey) *tcell.EventKey {
+        if !ui.IsLogViewerVisible() {
+            return ev
+        }
+        ui.ShowSourcePicker()
+        return nil
+    })
+    inputHandler.Set("V", func(ev *tcell.EventKey) *tcell.EventKey {
+        if !ui.IsLogViewerVisible() {
+            return ev
+        }
+        ui.Lock()
+        defer ui.Unlock()
+
+        ui.NextView()
+        return nil
+    })
+    inputHandler.Set("n", searchNext(false))
+    inputHandler.Set("N", searchNext(true))
+
     inputHandler.SetKey(tcell.ModNone, tcell.KeyEnter, func(ev *tcell.EventKey) *tcell.EventKey {
 
         if ui.IsCommandEntryVisible() {
@@ -327,12 +562,48 @@ func CreateAppUI() *UI {
             return nil
         }
 
+        if ui.IsTemplateListVisible() || ui.IsSourcePickerVisible() || ui.IsSearchResultsVisible() || ui.IsStatsTableVisible() {
+            return ev
+        }
+
         return nil
     })
 
-    ui.app.SetInputCapture(inputHandler.Capture)
+    ui.app.SetInputCapture(func(ev *tcell.EventKey) *tcell.EventKey {
+        if ui.IsLogViewerVisible() && ui.logView.IsMarkPending() {
+            // the key names a mark, it is handled by the log view
+            return ev
+        }
+        return inputHandler.Capture(ev)
+    })
     ui.cmdExecFunc = ui.defaultCommandHandler
+    ui.RegisterCommand("replay", ui.HandleReplay)
+    ui.RegisterCommand("save", ui.HandleSaveSession)
+    ui.RegisterCommand("load", ui.HandleLoadSession)
+    ui.RegisterCommand("export", ui.HandleExport)
+    ui.RegisterCommand("copy", ui.HandleCopy)
+    ui.RegisterCommand("dedup", ui.HandleDedup)
+    ui.RegisterCommand("templates", ui.HandleTemplates)
+    ui.RegisterCommand("open", ui.HandleOpen)
+    ui.RegisterCommand("filter", ui.HandleFilterCommand)
+    ui.RegisterCommand("count", ui.HandleCount)
+    ui.RegisterCommand("hl", ui.HandleHighlight)
+    ui.RegisterCommand("nohl", ui.HandleClearSearch)
+    ui.RegisterCommand("context", ui.HandleContext)
+    ui.RegisterCommand("sources", ui.HandleSources)
+    ui.RegisterCommand("view", ui.HandleView)
+    ui.RegisterCommand("results", ui.HandleResults)
+    ui.RegisterCommand("stats", ui.HandleStats)
+    ui.RegisterCommand("bookmarks", ui.HandleBookmarks)
+    var viewsErr error
+    if path, err := DefaultViewsPath(); err == nil {
+        viewsErr = ui.SetViewsPath(path)
+    }
     ui.ShowLogViewer()
+    if viewsErr != nil {
+        // views are not saved over a file that could not be loaded
+        ui.SetStatusViewText(fmt.Sprintf("Unable to load views: %v", viewsErr))
+    }
     return ui
 }
 
@@ -376,8 +647,11 @@ func (ui *UI) SetHighlightPattern(pattern string) {
     ui.logView.SetHighlightPattern(pattern)
 }
 
+// AppendEvent appends an event to the log view and counts it in the velocity view, including events
+// collapsed as duplicates or dropped by flood protection
 func (ui *UI) AppendEvent(event *LogEvent) {
     ui.logView.AppendEvent(event)
+    ui.histogram.AppendLogEvent(event)
 }
 
 func (ui *UI) SetAnchor(lastTime time.Time) {
@@ -388,10 +662,346 @@ func (ui *UI) SetExecuteCmdFunc(f CmdExecFunc) {
     ui.cmdExecFunc = f
 }
 
+// RegisterCommand registers a named command that is executed by HandleCommand. Command receives the rest of
+// the command line as an argument.
+func (ui *UI) RegisterCommand(name string, f CmdExecFunc) {
+    ui.commands[name] = f
+}
+
+// HandleCommand executes a registered command, i.e. "replay pause".
+// It returns false if the first word of the command line is not a registered command.
+func (ui *UI) HandleCommand(cmd string) bool {
+    fields := strings.SplitN(strings.TrimSpace(cmd), " ", 2)
+    f, ok := ui.commands[fields[0]]
+    if !ok {
+        return false
+    }
+    args := ""
+    if len(fields) > 1 {
+        args = strings.TrimSpace(fields[1])
+    }
+    ui.inputField.SetText("")
+    f(args)
+    return true
+}
+
+// StartReplay starts replaying recorded events in the log view, stopping any previous replay.
+// Velocity view anchor follows the replay clock. Replay can be controlled with the replay command.
+func (ui *UI) StartReplay(events []*LogEvent) *Replay {
+    if ui.replay != nil {
+        ui.replay.Stop()
+    }
+    ui.logView.Clear()
+    ui.searchResults.SetResults(nil, nil, 0)
+    ui.histogram.Clear()
+    ui.replay = NewReplay(events,
+        func(event *LogEvent) {
+            ui.logView.AppendEvent(event)
+            ui.histogram.AppendLogEvent(event)
+        },
+        func(clock time.Time) {
+            ui.histogram.SetAnchor(clock)
+            ui.app.QueueUpdateDraw(func() {})
+        })
+    ui.replay.Start()
+    return ui.replay
+}
+
+// defaultReplayTimestampLayout is the layout of the timestamps leading the lines of files replayed without a layout
+const defaultReplayTimestampLayout = "2006-01-02 15:04:05"
+
+// HandleReplay executes replay commands: pause, resume, step [count], speed <multiplier>, stop, start and status.
+// Any other first argument is a file to replay: <file> [timestamp layout], the layout defaults to
+// defaultReplayTimestampLayout.
+func (ui *UI) HandleReplay(s string) {
+    args := strings.Fields(s)
+    if len(args) == 0 {
+        args = []string{"status"}
+    }
+    switch args[0] {
+    case "pause", "resume", "start", "stop", "step", "speed", "status":
+    default:
+        ui.replayFile(args[0], strings.Join(args[1:], " "))
+        return
+    }
+    if ui.replay == nil {
+        ui.SetStatusViewText("No replay in progress, :replay <file> [timestamp layout] starts one")
+        return
+    }
+    switch args[0] {
+    case "pause":
+        ui.replay.Pause()
+    case "resume":
+        ui.replay.Resume()
+    case "start":
+        ui.replay.Start()
+    case "stop":
+        ui.replay.Stop()
+    case "step":
+        count := 1
+        if len(args) > 1 {
+            n, err := strconv.Atoi(args[1])
+            if err != nil || n < 1 {
+                ui.SetStatusViewText(fmt.Sprintf("invalid step count: %s", args[1]))
+                return
+            }
+            count = n
+        }
+        ui.replay.Step(count)
+    case "speed":
+        if len(args) < 2 {
+            ui.SetStatusViewText("replay speed requires a multiplier, i.e. :replay speed 2")
+            return
+        }
+        speed, err := strconv.ParseFloat(strings.TrimSuffix(args[1], "x"), 64)
+        if err == nil {
+            err = ui.replay.SetSpeed(speed)
+        }
+        if err != nil {
+            ui.SetStatusViewText(fmt.Sprintf("invalid replay speed: %s", args[1]))
+            return
+        }
+    case "status":
+    default:
+        ui.SetStatusViewText(fmt.Sprintf("unknown replay command: %s", args[0]))
+        return
+    }
+    ui.SetStatusViewText(ui.replayStatus())
+}
+
+// replayFile reads the events of the file in the background and starts replaying them
+func (ui *UI) replayFile(path string, layout string) {
+    if layout == "" {
+        layout = defaultReplayTimestampLayout
+    }
+    ui.SetStatusViewText(fmt.Sprintf("Reading %s", path))
+    go func() {
+        var events []*LogEvent
+        f, err := os.Open(path)
+        if err == nil {
+            events, err = ReadEvents(f, TimestampLineParser(layout))
+            _ = f.Close()
+        }
+        ui.app.QueueUpdateDraw(func() {
+            if err != nil {
+                ui.SetStatusViewText(fmt.Sprintf("Unable to replay file: %v", err))
+                return
+            }
+            ui.lastSearchEventIDHit = ""
+            ui.StartReplay(events)
+            ui.SetStatusViewText(ui.replayStatus())
+        })
+    }()
+}
+
+// HandleSaveSession saves the log view session to a file
+func (ui *UI) HandleSaveSession(path string) {
+    if path == "" {
+        ui.SetStatusViewText("save requires a file name, i.e. :save investigation.session")
+        return
+    }
+    f, err := os.Create(path)
+    if err == nil {
+        err = ui.logView.SaveSession(f)
+        if closeErr := f.Close(); err == nil {
+            err = closeErr
+        }
+    }
+    if err != nil {
+        ui.SetStatusViewText(fmt.Sprintf("Unable to save session: %v", err))
+        return
+    }
+    ui.SetStatusViewText(fmt.Sprintf("Session saved to %s%s", path, ui.unsavedPatternsNote()))
+}
+
+// HandleLoadSession replaces the log view state with a session loaded from a file: <file> [timestamp layout].
+// Lines of the file the session was saved with open are parsed with TimestampLineParser if the layout is given,
+// like with HandleOpen.
+func (ui *UI) HandleLoadSession(s string) {
+    args := strings.Fields(s)
+    if len(args) == 0 {
+        ui.SetStatusViewText("load requires a file name, i.e. :load investigation.session")
+        return
+    }
+    path := args[0]
+    var parser LineParser
+    if len(args) > 1 {
+        parser = TimestampLineParser(strings.Join(args[1:], " "))
+    }
+    f, err := os.Open(path)
+    if err == nil {
+        err = ui.logView.LoadSessionWithParser(f, parser)
+        _ = f.Close()
+    }
+    if err != nil {
+        ui.SetStatusViewText(fmt.Sprintf("Unable to load session: %v", err))
+        return
+    }
+    ui.lastSearchEventIDHit = ""
+    ui.searchResults.SetResults(nil, nil, 0)
+    ui.SetStatusViewText(fmt.Sprintf("Session loaded from %s, %d events", path, ui.logView.GetEventCount()))
+}
+
+// HandleOpen opens a log file without loading it into memory: <file> [timestamp layout]. Lines of the file are
+// parsed with TimestampLineParser if the layout of their leading timestamp is given.
+func (ui *UI) HandleOpen(s string) {
+    args := strings.Fields(s)
+    if len(args) == 0 {
+        ui.SetStatusViewText("open requires a file name, i.e. :open server.log 2006-01-02 15:04:05")
+        return
+    }
+    var parser LineParser
+    if len(args) > 1 {
+        parser = TimestampLineParser(strings.Join(args[1:], " "))
+    }
+    if err := ui.logView.OpenFile(args[0], parser); err != nil {
+        ui.SetStatusViewText(fmt.Sprintf("Unable to open file: %v", err))
+        return
+    }
+    ui.lastSearchEventIDHit = ""
+    ui.searchResults.SetResults(nil, nil, 0)
+    ui.SetStatusViewText(fmt.Sprintf("Opened %s, indexing", args[0]))
+}
+
+// HandleExport exports events to a file: <text|json|csv|ansi|html> <file> [selection|filtered]
+func (ui *UI) HandleExport(s string) {
+    args := strings.Fields(s)
+    if len(args) < 2 || len(args) > 3 {
+        ui.SetStatusViewText("export requires a format and a file name, i.e. :export json events.jsonl [selection|filtered]")
+        return
+    }
+    format, err := ParseExportFormat(args[0])
+    if err != nil {
+        ui.SetStatusViewText(err.Error())
+        return
+    }
+    scope := ExportAll
+    if len(args) == 3 {
+        switch args[2] {
+        case "selection":
+            if !ui.logView.HasSelection() {
+                ui.SetStatusViewText("Nothing is selected, press v to start a selection")
+                return
+            }
+            scope = ExportSelection
+        case "filtered":
+            scope = ExportFiltered
+        default:
+            ui.SetStatusViewText(fmt.Sprintf("unknown export scope: %s", args[2]))
+            return
+        }
+    }
+    f, err := os.Create(args[1])
+    if err == nil {
+        err = ui.logView.Export(f, format, scope)
+        if closeErr := f.Close(); err == nil {
+            err = closeErr
+        }
+    }
+    if err != nil {
+        ui.SetStatusViewText(fmt.Sprintf("Unable to export events: %v", err))
+        return
+    }
+    ui.SetStatusViewText(fmt.Sprintf("Events exported to %s", args[1]))
+}
+
+// HandleCopy copies the current event message, the current event with its header or the selected events to the
+// terminal clipboard. Without an argument it copies the selection, if there is one, or the current event message.
+func (ui *UI) HandleCopy(s string) {
+    what := strings.TrimSpace(s)
+    if what == "" {
+        what = "message"
+        if ui.logView.HasSelection() {
+            what = "selection"
+        }
+    }
+    var err error
+    switch what {
+    case "message":
+        err = ui.logView.CopyCurrentMessage()
+    case "event":
+        err = ui.logView.CopyCurrentEvent()
+    case "selection":
+        err = ui.logView.CopySelection()
+    default:
+        err = fmt.Errorf("unknown copy target: %s", what)
+    }
+    if err != nil {
+        ui.SetStatusViewText(fmt.Sprintf("Unable to copy: %v", err))
+        return
+    }
+    ui.SetStatusViewText(fmt.Sprintf("Copied %s to clipboard", what))
+}
+
+// HandleDedup switches collapsing of consecutive duplicate events: on, off, or mask to also ignore numbers and UUIDs
+func (ui *UI) HandleDedup(s string) {
+    switch strings.TrimSpace(s) {
+    case "on":
+        ui.logView.SetCollapseDuplicates(true)
+        ui.logView.SetMaskDuplicates(false)
+    case "mask":
+        ui.logView.SetCollapseDuplicates(true)
+        ui.logView.SetMaskDuplicates(true)
+    case "off":
+        ui.logView.SetCollapseDuplicates(false)
+    default:
+        ui.SetStatusViewText("dedup requires on, off or mask, i.e. :dedup mask")
+        return
+    }
+    ui.SetStatusViewText(fmt.Sprintf("Duplicate collapsing is %s", strings.TrimSpace(s)))
+}
+
+// HandleTemplates shows the list of message templates, or clears the template filter with off
+func (ui *UI) HandleTemplates(s string) {
+    switch strings.TrimSpace(s) {
+    case "":
+        // commands are executed under the UI lock, so the list is shown once the command is done
+        ui.app.QueueUpdateDraw(ui.ShowTemplates)
+    case "off":
+        ui.logView.SetTemplateFilter(0)
+        ui.SetStatusViewText("Showing all events")
+    default:
+        ui.SetStatusViewText(fmt.Sprintf("unknown templates command: %s", s))
+    }
+}
+
+// startTemplateMining sets a template miner to the log view, unless it has one restored with a session, and creates
+// the template view
+func (ui *UI) startTemplateMining() {
+    ui.templateMiner = ui.logView.GetTemplateMiner()
+    if ui.templateMiner == nil {
+        ui.templateMiner = NewTemplateMiner()
+        ui.logView.SetTemplateMiner(ui.templateMiner)
+    }
+    ui.templateView = NewTemplateView(ui.templateMiner)
+    ui.templateView.SetBorder(true)
+    ui.templateView.SetTitle("Templates")
+    ui.templateView.SetOnTemplateSelected(func(template LogTemplate) {
+        ui.logView.SetTemplateFilter(template.ID)
+        ui.ShowLogViewer()
+        ui.SetStatusViewText(fmt.Sprintf("Showing %d events of template: %s (:templates off to show all)", template.Count, template.Pattern))
+    })
+}
+
+func (ui *UI) replayStatus() string {
+    pos, total := ui.replay.Position()
+    state := "playing"
+    if !ui.replay.IsRunning() {
+        state = "stopped"
+    } else if ui.replay.IsPaused() {
+        state = "paused"
+    }
+    return fmt.Sprintf("replay %s at %s, speed %vx, %d/%d events", state,
+        ui.replay.Clock().Format("15:04:05.000"), ui.replay.GetSpeed(), pos, total)
+}
+
 func (ui *UI) handleCommandEntered(cmd string) {
     ui.Lock()
     defer ui.Unlock()
 
+    if origin, ok := ui.incrementalSearch.finish(cmd); ok {
+        ui.lastSearchEventIDHit = origin
+    }
     if cmd != "" {
         ui.cmdHistory = append(ui.cmdHistory, cmd)
     }
@@ -414,6 +1024,30 @@ func (ui *UI) GetCommandHistory() []string {
 }
 
 func (ui *UI) HandleSearch(s string) {
+    ui.search(s, false)
+}
+
+// HandleSearchBackward searches backwards for the pattern, from the last match towards older events
+func (ui *UI) HandleSearchBackward(s string) {
+    ui.search(s, true)
+}
+
+// HandleSearchNext repeats the last search in its direction, or in the opposite direction if reverse is true,
+// like n and N in less
+func (ui *UI) HandleSearchNext(reverse bool) {
+    if ui.lastSearch == "" {
+        ui.SetStatusViewText("No previous search, enter /pattern or ?pattern")
+        return
+    }
+    pattern, err := ParseSearch(ui.lastSearch)
+    if err != nil {
+        ui.SetStatusViewText(err.Error())
+        return
+    }
+    ui.runSearch(pattern, ui.lastSearchBackward != reverse)
+}
+
+func (ui *UI) search(s string, backward bool) {
 
     ui.SetStatusViewText(fmt.Sprintf("handleSearch: %s", s))
     ui.inputField.SetText("")
@@ -427,27 +1061,49 @@ func (ui *UI) HandleSearch(s string) {
     }
 
     ui.lastSearch = s
+    ui.lastSearchBackward = backward
 
-    hits := ui.logView.FindTotalMatches(func(event *LogEvent) bool {
-        if strings.Contains(event.Message, s) {
-            return true
-        }
-
-        return false
-    })
-
-    event := ui.logView.FindMatchingEvent(ui.lastSearchEventIDHit,
-        func(event *LogEvent) bool {
-            if strings.Contains(event.Message, s) {
-                return true
-            }
+    pattern, err := ParseSearch(s)
+    if err != nil {
+        ui.SetStatusViewText(err.Error())
+        return
+    }
+    ui.logView.SetActiveSearch(pattern)
+    ui.runSearch(pattern, backward)
+}
 
-            return false
+// runSearch finds the next match after the last one in the direction, wrapping around the ends of the log view
+func (ui *UI) runSearch(pattern *Search, backward bool) {
+    lastHit := ui.lastSearchEventIDHit
+    search := func() (*LogEvent, int, bool) {
+        hits := ui.logView.FindTotalMatches(pattern.Match)
+        event, wrapped := ui.logView.findWrapping(lastHit, pattern, backward, nil)
+        return event, hits, wrapped
+    }
+    if !ui.logView.IsFileOpen() {
+        event, hits, wrapped := search()
+        ui.showSearchResult(pattern.String(), event, hits, wrapped, backward)
+        return
+    }
+    // files are streamed in the background, the status shows the search progress meanwhile
+    go func() {
+        event, hits, wrapped := search()
+        ui.app.QueueUpdateDraw(func() {
+            ui.showSearchResult(pattern.String(), event, hits, wrapped, backward)
         })
+    }()
+}
 
+func (ui *UI) showSearchResult(s string, event *LogEvent, hits int, wrapped bool, backward bool) {
     if event != nil {
         ui.lastSearchEventIDHit = event.EventID
-        ui.SetStatusViewText(fmt.Sprintf("Found EventID: %s total maches: %d", event.EventID, hits))
+        notice := ""
+        if wrapped && backward {
+            notice = "search hit TOP, continuing at BOTTOM | "
+        } else if wrapped {
+            notice = "search hit BOTTOM, continuing at TOP | "
+        }
+        ui.SetStatusViewText(fmt.Sprintf("%sFound EventID: %s total maches: %d", notice, event.EventID, hits))
         ui.logView.ScrollToEventID(event.EventID)
         ui.logView.RefreshHighlights()
         return
@@ -459,24 +1115,618 @@ func (ui *UI) HandleSearch(s string) {
 
 }
 
-func (ui *UI) HandleGotoLine(s string) {
+// SetIncrementalSearch enables or disables searching as /pattern or ?pattern is typed in the command entry
+func (ui *UI) SetIncrementalSearch(enabled bool) {
+    ui.incrementalSearchEnabled = enabled
+}
 
-    if s == "top" || s == "1" {
-        ui.logView.ScrollToTop()
-        ui.inputField.SetText("")
+// handleCommandChanged starts, updates or stops the incremental search as the command entry changes
+func (ui *UI) handleCommandChanged(text string) {
+    // the text is also changed by commands holding the lock, so the UI is not locked here
+    if !ui.incrementalSearchEnabled && (strings.HasPrefix(text, "/") || strings.HasPrefix(text, "?")) {
+        return
+    }
+    ui.incrementalSearch.update(text)
+}
+
+// HandleResults shows every event matching the last search, or clears the results with off. Matching events
+// appended later are added to the results.
+func (ui *UI) HandleResults(s string) {
+    switch strings.TrimSpace(s) {
+    case "":
+    case "off":
+        ui.searchResults.SetResults(nil, nil, 0)
+        ui.SetStatusViewText("Search results cleared")
+        return
+    default:
+        ui.SetStatusViewText(fmt.Sprintf("unknown results command: %s", s))
+        return
+    }
+    if ui.lastSearch == "" {
+        ui.SetStatusViewText("No previous search, enter /pattern or ?pattern")
+        return
+    }
+    pattern, err := ParseSearch(ui.lastSearch)
+    if err != nil {
+        ui.SetStatusViewText(err.Error())
+        return
+    }
+    if current := ui.searchResults.GetSearch(); current != nil && current.String() == pattern.String() {
+        // the results are already updated live
+        ui.app.QueueUpdateDraw(ui.ShowSearchResults)
+        return
+    }
+    collect := func() {
+        events, total := ui.logView.FindAllMatches(pattern.Match, defaultSearchResultsLimit)
+        ui.searchResults.SetResults(pattern, events, total)
+    }
+    if !ui.logView.IsFileOpen() {
+        collect()
+        // commands are executed under the UI lock, so the results are shown once the command is done
+        ui.app.QueueUpdateDraw(ui.ShowSearchResults)
+        return
+    }
+    ui.SetStatusViewText(fmt.Sprintf("Collecting matches of %s", pattern))
+    go func() {
+        collect()
+        ui.app.QueueUpdateDraw(ui.ShowSearchResults)
+    }()
+}
+
+// HandleStats counts events by source, level or a path in the event data: by <source|level|field> [pattern]. Only
+// events matching the pattern are counted if it is given, i.e. \q after:10:00 before:11:00 for a time range.
+func (ui *UI) HandleStats(s string) {
+    args := strings.SplitN(strings.TrimSpace(s), " ", 3)
+    if len(args) < 2 || args[0] != "by" {
+        ui.SetStatusViewText("stats requires what to count by, i.e. :stats by source, :stats by http.status \\q level>=warn")
+        return
+    }
+    var predicate func(event *LogEvent) bool
+    if len(args) > 2 && strings.TrimSpace(args[2]) != "" {
+        var err error
+        if predicate, err = ParsePattern(strings.TrimSpace(args[2])); err != nil {
+            ui.SetStatusViewText(err.Error())
+            return
+        }
+    }
+    aggregate := func() error {
+        stats, err := ui.logView.Aggregate(args[1], predicate)
+        if err != nil {
+            return err
+        }
+        ui.statsView.SetStats(stats)
+        return nil
+    }
+    if !ui.logView.IsFileOpen() {
+        if err := aggregate(); err != nil {
+            ui.SetStatusViewText(err.Error())
+            return
+        }
+        // commands are executed under the UI lock, so the table is shown once the command is done
+        ui.app.QueueUpdateDraw(ui.ShowStats)
+        return
+    }
+    ui.SetStatusViewText(fmt.Sprintf("Counting events by %s", args[1]))
+    go func() {
+        err := aggregate()
+        ui.app.QueueUpdateDraw(func() {
+            if err != nil {
+                ui.SetStatusViewText(err.Error())
+                return
+            }
+            ui.ShowStats()
+        })
+    }()
+}
+
+// HandleBookmarks lists bookmarked events and marks, or removes all of them with clear
+func (ui *UI) HandleBookmarks(s string) {
+    switch strings.TrimSpace(s) {
+    case "":
+    case "clear":
+        ui.logView.ClearBookmarks()
+        ui.SetStatusViewText("Bookmarks and marks removed")
+        return
+    default:
+        ui.SetStatusViewText(fmt.Sprintf("unknown bookmarks command: %s", s))
+        return
+    }
+    bookmarks := ui.logView.GetBookmarks()
+    marks := ui.logView.GetMarks()
+    if len(bookmarks) == 0 && len(marks) == 0 {
+        ui.SetStatusViewText("No bookmarks, b bookmarks the current event, m<letter> sets a mark")
+        return
+    }
+    names := make([]string, 0, len(marks))
+    for name := range marks {
+        names = append(names, string(name))
+    }
+    sort.Strings(names)
+    describe := func(marker string, event *LogEvent) string {
+        return fmt.Sprintf("%s %s %s %s\n", marker, event.Timestamp.Format("15:04:05"), cview.Escape(event.EventID), cview.Escape(event.Message))
+    }
+    text := ""
+    for _, name := range names {
+        text += describe(name, marks[[]rune(name)[0]])
+    }
+    for _, event := range bookmarks {
+        text += describe(string(bookmarkMarker), event)
+    }
+    // commands are executed under the UI lock, so the list is shown once the command is done
+    ui.app.QueueUpdateDraw(func() {
+        ui.ShowDetailsModal(fmt.Sprintf("%d bookmarks, %d marks", len(bookmarks), len(marks)), text)
+    })
+}
+
+// HandleClearSearch cancels the search, its matches are no longer highlighted
+func (ui *UI) HandleClearSearch(s string) {
+    ui.lastSearch = ""
+    ui.lastSearchEventIDHit = ""
+    ui.logView.SetActiveSearch(nil)
+    ui.searchResults.SetResults(nil, nil, 0)
+    ui.SetStatusViewText("Search cleared")
+}
+
+// HandleCount shows the number of events matching the pattern
+func (ui *UI) HandleCount(s string) {
+    if s == "" {
+        ui.SetStatusViewText("count requires a pattern, i.e. :count \\q level>=warn source:api*")
+        return
+    }
+    predicate, err := ParsePattern(s)
+    if err != nil {
+        ui.SetStatusViewText(err.Error())
+        return
+    }
+    ui.SetStatusViewText(fmt.Sprintf("%d events match %s", ui.logView.FindTotalMatches(predicate), s))
+}
+
+// HandleHighlight highlights events matching the pattern: [color] <pattern>, or removes all the highlight rules
+// with off
+func (ui *UI) HandleHighlight(s string) {
+    if s == "off" {
+        ui.logView.ClearHighlightRules()
+        ui.SetStatusViewText("Highlight rules removed")
+        return
+    }
+    rules := ui.logView.GetHighlightRules()
+    color := highlightRuleColors[len(rules)%len(highlightRuleColors)]
+    if fields := strings.SplitN(s, " ", 2); len(fields) == 2 {
+        if c, ok := tcell.ColorNames[strings.ToLower(fields[0])]; ok {
+            color = c
+            s = fields[1]
+        }
+    }
+    if s == "" {
+        ui.SetStatusViewText("hl requires a pattern, i.e. :hl red \\q level=error, or off")
+        return
+    }
+    predicate, err := ParsePattern(s)
+    if err != nil {
+        ui.SetStatusViewText(err.Error())
+        return
+    }
+    ui.logView.AddHighlightRule(HighlightRule{Name: s, Pattern: s, Predicate: predicate, Color: color})
+    ui.SetStatusViewText(fmt.Sprintf("%d highlight rules, :hl off removes them", len(rules)+1))
+}
+
+// HandleFilter pushes a filter that hides events not containing the text, or pops the last filter if the text
+// is empty
+func (ui *UI) HandleFilter(s string) {
+    ui.inputField.SetText("")
+    if s == "" {
+        ui.popFilter()
+        return
+    }
+    predicate, err := ParsePattern(s)
+    if err != nil {
+        ui.SetStatusViewText(err.Error())
+        return
+    }
+    ui.logView.PushFilter(EventFilter{Name: s, Pattern: s, Predicate: predicate})
+    ui.SetStatusViewText("Filter added, & pops the last filter")
+}
+
+// HandleFilterCommand manages the filter stack: pop removes the last filter, clear removes all of them,
+// any other text is pushed as a new filter
+func (ui *UI) HandleFilterCommand(s string) {
+    switch s {
+    case "":
+        if len(ui.logView.GetFilters()) == 0 {
+            ui.SetStatusViewText("No filters, &<text> adds a filter")
+        } else {
+            ui.SetStatusViewText("Filters are shown on the left, :filter pop or :filter clear removes them")
+        }
+    case "pop":
+        ui.popFilter()
+    case "clear":
+        ui.logView.ClearFilters()
+        ui.SetStatusViewText("All filters removed")
+    default:
+        ui.HandleFilter(s)
+    }
+}
+
+func (ui *UI) popFilter() {
+    if filter, ok := ui.logView.PopFilter(); ok {
+        ui.SetStatusViewText(fmt.Sprintf("Filter removed: %s", filter.Name))
+    } else {
+        ui.SetStatusViewText("No filters to remove")
+    }
+}
+
+// filterStatus describes the active filters, and hidden levels and sources, for the status bar
+func (ui *UI) filterStatus() string {
+    status := ""
+    if filters := ui.logView.GetFilters(); len(filters) > 0 {
+        names := make([]string, len(filters))
+        for i, filter := range filters {
+            names[i] = cview.Escape(filter.Name)
+        }
+        status = fmt.Sprintf("[blue]filter: %s", strings.Join(names, " & "))
+        if context := ui.logView.GetFilterContext(); !context.IsEmpty() {
+            status += fmt.Sprintf(", context %s", context)
+        }
+        status += "[-] | "
+    }
+    hidden := make([]string, 0)
+    for _, level := range []LogLevel{LogLevelInfo, LogLevelWarning, LogLevelError} {
+        if !ui.logView.IsLevelVisible(level) {
+            hidden = append(hidden, level.String())
+        }
+    }
+    for _, source := range ui.logView.GetHiddenSources() {
+        hidden = append(hidden, cview.Escape(source))
+    }
+    if len(hidden) > 0 {
+        status += fmt.Sprintf("[blue]hidden: %s[-] | ", strings.Join(hidden, ", "))
+    }
+    return status
+}
+
+// SetViewsPath loads named views from the file, views are saved to it too. By default views are kept in
+// the file returned by DefaultViewsPath. Views are not saved if the file cannot be loaded.
+func (ui *UI) SetViewsPath(path string) error {
+    views, err := LoadViews(path)
+    if err != nil {
+        return err
+    }
+    ui.views = views
+    ui.viewsPath = path
+    ui.currentView = -1
+    return nil
+}
+
+// GetViews returns the named views
+func (ui *UI) GetViews() []View {
+    return append([]View(nil), ui.views...)
+}
+
+// HandleView manages named views: save <name> saves the current settings of the log view, delete <name> deletes
+// a view, any other name switches to the view, and no name lists the views
+func (ui *UI) HandleView(s string) {
+    fields := strings.Fields(s)
+    switch {
+    case len(fields) == 0:
+        if len(ui.views) == 0 {
+            ui.SetStatusViewText("No views, :view save <name> saves the filters, highlighting and columns as a view")
+            return
+        }
+        names := make([]string, len(ui.views))
+        for i, view := range ui.views {
+            names[i] = view.Name
+        }
+        ui.SetStatusViewText(fmt.Sprintf("Views: %s, :view <name> or V switches views", strings.Join(names, ", ")))
+    case fields[0] == "save" && len(fields) == 2:
+        view := ui.logView.CaptureView(fields[1])
+        views := append([]View(nil), ui.views...)
+        if i := ui.findView(view.Name); i >= 0 {
+            views[i] = view
+        } else {
+            views = append(views, view)
+        }
+        ui.saveViews(views, fmt.Sprintf("View %s saved%s", view.Name, ui.unsavedPatternsNote()))
+    case fields[0] == "delete" && len(fields) == 2:
+        i := ui.findView(fields[1])
+        if i < 0 {
+            ui.SetStatusViewText(fmt.Sprintf("Unknown view: %s", fields[1]))
+            return
+        }
+        views := append(append([]View(nil), ui.views[:i]...), ui.views[i+1:]...)
+        ui.saveViews(views, fmt.Sprintf("View %s deleted", fields[1]))
+    case len(fields) == 1:
+        i := ui.findView(fields[0])
+        if i < 0 {
+            ui.SetStatusViewText(fmt.Sprintf("Unknown view: %s", fields[0]))
+            return
+        }
+        ui.switchView(i)
+    default:
+        ui.SetStatusViewText("view requires a name, i.e. :view errors, :view save errors or :view delete errors")
+    }
+}
+
+// NextView switches to the view after the current one
+func (ui *UI) NextView() {
+    if len(ui.views) == 0 {
+        ui.SetStatusViewText("No views, :view save <name> saves the filters, highlighting and columns as a view")
+        return
+    }
+    ui.switchView((ui.currentView + 1) % len(ui.views))
+}
+
+func (ui *UI) switchView(i int) {
+    view := ui.views[i]
+    if err := ui.logView.ApplyView(view); err != nil {
+        ui.SetStatusViewText(err.Error())
+        return
+    }
+    ui.currentView = i
+    ui.SetStatusViewText(fmt.Sprintf("View %s (%d/%d)", view.Name, i+1, len(ui.views)))
+}
+
+func (ui *UI) findView(name string) int {
+    for i, view := range ui.views {
+        if view.Name == name {
+            return i
+        }
+    }
+    return -1
+}
+
+// saveViews writes the views to the views file and keeps them if they are written
+// unsavedPatternsNote tells about the filters and highlight rules without a pattern, which are not saved
+func (ui *UI) unsavedPatternsNote() string {
+    unsaved := unsavedPatterns(ui.logView.GetFilters(), ui.logView.GetHighlightRules())
+    if unsaved == 0 {
+        return ""
+    }
+    return fmt.Sprintf(", %d filters and highlight rules without a pattern are left out", unsaved)
+}
+
+func (ui *UI) saveViews(views []View, message string) {
+    if ui.viewsPath == "" {
+        ui.SetStatusViewText("Views cannot be saved, no views file is loaded")
+        return
+    }
+    if err := SaveViews(ui.viewsPath, views); err != nil {
+        ui.SetStatusViewText(fmt.Sprintf("Failed to save views: %v", err))
+        return
+    }
+    ui.views = views
+    if ui.currentView >= len(views) {
+        ui.currentView = -1
+    }
+    ui.SetStatusViewText(message)
+}
+
+// HandleSources shows the source picker, or shows all the sources with all
+func (ui *UI) HandleSources(s string) {
+    switch strings.TrimSpace(s) {
+    case "":
+        // commands are executed under the UI lock, so the list is shown once the command is done
+        ui.app.QueueUpdateDraw(ui.ShowSourcePicker)
+    case "all":
+        ui.logView.ShowAllSources()
+        ui.SetStatusViewText("Showing all sources")
+    default:
+        ui.SetStatusViewText(fmt.Sprintf("unknown sources command: %s", s))
+    }
+}
+
+// refreshSourcePicker lists the sources of the events in the log view and the hidden sources, by event count
+func (ui *UI) refreshSourcePicker() {
+    counts := ui.logView.GetSourceEventCounts()
+    for _, source := range ui.logView.GetHiddenSources() {
+        counts[source] += 0
+    }
+    sources := make([]string, 0, len(counts))
+    for source := range counts {
+        sources = append(sources, source)
+    }
+    sort.Slice(sources, func(i, j int) bool {
+        if counts[sources[i]] != counts[sources[j]] {
+            return counts[sources[i]] > counts[sources[j]]
+        }
+        return sources[i] < sources[j]
+    })
+
+    selected := ui.sourcePicker.GetCurrentItemIndex()
+    ui.sourcePicker.Clear()
+    ui.sourcePicker.AddItem(cview.NewListItem("  show all sources"))
+    for _, source := range sources {
+        mark := "●"
+        if !ui.logView.IsSourceVisible(source) {
+            mark = "○"
+        }
+        name := source
+        if name == "" {
+            name = "(no source)"
+        }
+        item := cview.NewListItem(fmt.Sprintf("%s %8d  %s", mark, counts[source], cview.Escape(name)))
+        item.SetReference(source)
+        ui.sourcePicker.AddItem(item)
+    }
+    if selected > 0 && selected < ui.sourcePicker.GetItemCount() {
+        ui.sourcePicker.SetCurrentItem(selected)
+    }
+}
+
+// HandleContext sets the context displayed around events matching the filters, like grep: a number of events before
+// and after (:context 3), before (-B 2) or after (-A 5) them, a time window around them (:context 30s), or off
+func (ui *UI) HandleContext(s string) {
+    context := FilterContext{}
+    fields := strings.Fields(s)
+    if len(fields) == 0 {
+        ui.SetStatusViewText("context requires a count, -B count, -A count, a time window or off, i.e. :context -B 2 -A 5 10s")
         return
     }
-    if s == "bottom" {
+    for i := 0; i < len(fields); i++ {
+        field := fields[i]
+        if field == "off" {
+            continue
+        }
+        if field == "-A" || field == "-B" || field == "-C" {
+            i++
+            if i == len(fields) {
+                ui.SetStatusViewText(fmt.Sprintf("%s requires a count", field))
+                return
+            }
+        }
+        count, err := strconv.Atoi(fields[i])
+        if err != nil || count < 0 {
+            window, err := time.ParseDuration(fields[i])
+            if err != nil || window < 0 || field != fields[i] {
+                ui.SetStatusViewText(fmt.Sprintf("Invalid context %s, expected a count or a duration like 30s", fields[i]))
+                return
+            }
+            context.Window = window
+            continue
+        }
+        switch field {
+        case "-A":
+            context.After = count
+        case "-B":
+            context.Before = count
+        default:
+            context.Before, context.After = count, count
+        }
+    }
+    ui.logView.SetFilterContext(context)
+    if context.IsEmpty() {
+        ui.SetStatusViewText("Context off")
+    } else if len(ui.logView.GetFilters()) == 0 {
+        ui.SetStatusViewText(fmt.Sprintf("Context %s is shown once a filter is added with &<text>", context))
+    } else {
+        ui.SetStatusViewText(fmt.Sprintf("Showing context %s", context))
+    }
+}
+
+// HandleGotoLine moves to an event:
+//
+// - top, bottom - the first or the last event
+//
+// - 1234, +50, -10 - the event with the number, counting all events from 1, or an event after or before the current one
+//
+// - 50% - the event at the percentage of all events
+//
+// - @14:03:22, @2021-03-01T14:03 - the first event at or after the time, a time of day is on the day of the current event
+//
+// - -5m, +1h30m - the first event at or after the time relative to the current event
+//
+// - #abc - the event with the id
+func (ui *UI) HandleGotoLine(s string) {
+    s = strings.TrimSpace(s)
+    ui.inputField.SetText("")
+    switch {
+    case s == "top":
+        ui.logView.ScrollToTop()
+        return
+    case s == "bottom":
         ui.logView.ScrollToBottom()
-        ui.inputField.SetText("")
+        return
+    case strings.HasPrefix(s, "#") && len(s) > 1:
+        if !ui.logView.ScrollToEventID(s[1:]) {
+            ui.SetStatusViewText(fmt.Sprintf("No event with EventID: %s", s[1:]))
+            return
+        }
+        // like the other goto targets
+        ui.logView.SetFollowing(false)
+        return
+    case strings.HasPrefix(s, "@"):
+        reference := time.Now()
+        if current := ui.logView.GetCurrentEvent(); current != nil {
+            reference = current.Timestamp
+        }
+        timestamp, err := parseGotoTime(s[1:], reference)
+        if err != nil {
+            ui.SetStatusViewText(err.Error())
+            return
+        }
+        ui.gotoTime(timestamp)
+        return
+    case strings.HasSuffix(s, "%"):
+        percent, err := strconv.ParseFloat(s[:len(s)-1], 64)
+        if err != nil || percent < 0 || percent > 100 {
+            ui.SetStatusViewText(fmt.Sprintf("invalid percentage: %s, expected 0%% to 100%%", s))
+            return
+        }
+        ui.gotoIndex(ui.logView.ScrollToPercent(percent))
+        return
+    }
+
+    if number, err := strconv.Atoi(s); err == nil {
+        if s[0] != '+' && s[0] != '-' {
+            ui.gotoIndex(ui.logView.ScrollToIndex(number - 1))
+            return
+        }
+        ui.gotoIndex(ui.logView.ScrollByEvents(number))
+        return
+    }
+    if offset, err := time.ParseDuration(s); err == nil && (s[0] == '+' || s[0] == '-') {
+        reference := time.Now()
+        if current := ui.logView.GetCurrentEvent(); current != nil {
+            reference = current.Timestamp
+        }
+        ui.gotoTime(reference.Add(offset))
+        return
+    }
+
+    ui.SetStatusViewText(fmt.Sprintf("unknown navigate value: %s, expected top, bottom, 1234, +50, -10, 50%%, @14:03:22, -5m or #<event id>", s))
+}
+
+// gotoIndex shows the number of the event moved to
+func (ui *UI) gotoIndex(found bool) {
+    if !found {
+        ui.SetStatusViewText("No events to go to")
+        return
+    }
+    ui.SetStatusViewText(fmt.Sprintf("Event %d of %d", ui.logView.GetCurrentEventIndex()+1, ui.logView.GetTotalEventCount()))
+}
+
+func (ui *UI) gotoTime(timestamp time.Time) {
+    if !ui.logView.ScrollToTimestamp(timestamp) {
+        ui.SetStatusViewText(fmt.Sprintf("No events at or after %s", timestamp.Format("2006-01-02 15:04:05")))
         return
     }
+    ui.SetStatusViewText(fmt.Sprintf("Event %d of %d at %s", ui.logView.GetCurrentEventIndex()+1, ui.logView.GetTotalEventCount(),
+        ui.logView.GetCurrentEvent().Timestamp.Format("2006-01-02 15:04:05")))
+}
 
-    ui.SetStatusViewText(fmt.Sprintf("unknown navigate value: %s", s))
+// parseGotoTime parses a date and time, or a time of day on the day of the reference, in the formats of queries
+func parseGotoTime(value string, reference time.Time) (time.Time, error) {
+    for _, layout := range queryTimeLayouts {
+        if timestamp, err := time.ParseInLocation(layout, value, reference.Location()); err == nil {
+            return timestamp, nil
+        }
+    }
+    for _, layout := range queryTimeOfDayLayouts {
+        if clock, err := time.Parse(layout, value); err == nil {
+            midnight := time.Date(reference.Year(), reference.Month(), reference.Day(), 0, 0, 0, 0, reference.Location())
+            return midnight.Add(clock.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC))), nil
+        }
+    }
+    return time.Time{}, fmt.Errorf("invalid time %q, expected 15:04, 15:04:05, 2006-01-02 or 2006-01-02T15:04:05", value)
+}
+
+// fileProgressStatus describes the progress of indexing or searching the open file
+func fileProgressStatus(progress FileProgress) string {
+    if progress.Err != nil {
+        return progress.Err.Error()
+    }
+    operation := "Indexing"
+    if progress.Searching {
+        operation = "Searching"
+    }
+    if progress.IsComplete() {
+        return fmt.Sprintf("%s complete, %sB", operation, strings.TrimSpace(formatValue(int(progress.Total))))
+    }
+    return fmt.Sprintf("%s: %d%% of %sB", operation, 100*progress.Done/progress.Total, strings.TrimSpace(formatValue(int(progress.Total))))
 }
 
+// SetStatusViewText sets the status bar text, active filters are shown in front of it
 func (ui *UI) SetStatusViewText(message string) {
-    ui.statusView.SetText(message)
+    ui.statusView.SetText(ui.filterStatus() + message)
 }
 
 func (ui *UI) SetStatusViewTextColor(color tcell.Color) {
@@ -0,0 +1,37 @@
+package clogviewr
+
+import (
+    "testing"
+    "time"
+)
+
+func TestUI_ReplayStepWithSearchResults(t *testing.T) {
+    ui := CreateAppUI()
+    ui.SetExecuteCmdFunc(func(cmd string) {
+        ui.HandleCommand(cmd[1:])
+    })
+    events := randomEvents(3, time.Now().Add(-24*time.Hour))
+    for i, event := range events {
+        event.Timestamp = event.Timestamp.Add(time.Duration(i) * time.Hour)
+    }
+    ui.StartReplay(events).Pause()
+    defer ui.replay.Stop()
+    search, _ := ParseSearch("Event")
+    ui.searchResults.SetResults(search, nil, 0)
+    ui.ShowSearchResults()
+
+    // events appended by a command reach the appended listener while the UI is locked
+    done := make(chan struct{})
+    go func() {
+        ui.handleCommandEntered(":replay step")
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatalf("Stepping the replay with search results shown deadlocked")
+    }
+    if _, total := ui.searchResults.GetResults(); total == 0 {
+        t.Errorf("Expected the stepped event to be added to the search results")
+    }
+}
@@ -0,0 +1,160 @@
+package clogviewr
+
+// GetTotalEventCount returns the number of all events of the log view: events in memory, spilled events and lines of
+// the open file. Events hidden by filters are counted too, they have positions like any other event.
+func (lv *LogView) GetTotalEventCount() int {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    count := lv.storeCount()
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.order <= 1 && !event.fromSpill {
+            count++
+        }
+    }
+    return count
+}
+
+// GetCurrentEventIndex returns the position of the current event among all events, see GetTotalEventCount,
+// starting with 0, or -1 if there is no current event
+func (lv *LogView) GetCurrentEventIndex() int {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    if lv.current == nil {
+        return -1
+    }
+    return lv.indexOf(lv.current)
+}
+
+// ScrollToIndex scrolls to the event at the position among all events, see GetTotalEventCount, paging the event
+// in if needed. Positions out of range go to the first or the last event. An event that is not displayed is replaced
+// by the next displayed event, or by the previous one if there is none. It returns false if no event is displayed.
+//
+// Current event will be updated to the found event
+func (lv *LogView) ScrollToIndex(index int) bool {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    return lv.scrollToIndex(index, false)
+}
+
+// ScrollByEvents scrolls count positions forward, or backward if count is negative, from the current event, see
+// ScrollToIndex. An event that is not displayed is replaced by the nearest displayed event in the direction of the
+// move, so that a move backward to a hidden event does not snap forward to the current event again.
+func (lv *LogView) ScrollByEvents(count int) bool {
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    index := 0
+    if lv.current != nil {
+        index = lv.indexOf(lv.current)
+    }
+    return lv.scrollToIndex(index+count, count < 0)
+}
+
+// ScrollToPercent scrolls to the event at the percentage of all events, 0 is the first event and 100 the last one,
+// see ScrollToIndex
+func (lv *LogView) ScrollToPercent(percent float64) bool {
+    total := lv.GetTotalEventCount()
+    return lv.ScrollToIndex(int(percent / 100 * float64(total-1)))
+}
+
+// *******************************
+// internal implementation details
+
+// scrollToIndex moves to the event at the index, or the next displayed event if it is not displayed. Previous
+// displayed event is preferred if backward is true.
+func (lv *LogView) scrollToIndex(index int, backward bool) bool {
+    event := lv.eventAt(index)
+    if event == nil {
+        return false
+    }
+    if !lv.isVisible(event) {
+        next, previous := lv.nextVisible(event), lv.previousVisible(event)
+        if previous != nil {
+            previous = findFirstWrappedLine(previous)
+        }
+        if backward && previous != nil {
+            event = previous
+        } else if next != nil {
+            event = next
+        } else if previous != nil {
+            event = previous
+        } else {
+            return false
+        }
+    }
+    lv.moveTo(event)
+    return true
+}
+
+// moveTo makes the event current, a little below the top of the page, and stops following
+func (lv *LogView) moveTo(event *logEventLine) {
+    lv.following = false
+    lv.top = event
+    lv.current = event
+    lv.adjustTop()
+    lv.top = lv.atOffset(lv.top, -lv.pageHeight/4)
+    lv.fillSpillGaps(lv.top, lv.pageHeight)
+}
+
+// storeCount returns the number of events in the store events are paged in from, 0 if there is none
+func (lv *LogView) storeCount() int {
+    if store := lv.store(); store != nil {
+        return store.count()
+    }
+    return 0
+}
+
+// indexOf returns the position of the event among all events. Events of the store come first, followed by events
+// that have never been spilled.
+func (lv *LogView) indexOf(event *logEventLine) int {
+    event = findFirstWrappedLine(event)
+    if event.fromSpill {
+        return event.spillIndex
+    }
+    index := lv.storeCount()
+    for e := lv.firstEvent; e != nil && e != event; e = e.next {
+        if e.order <= 1 && !e.fromSpill {
+            index++
+        }
+    }
+    return index
+}
+
+// eventAt returns the first line of the event at the position, clamped to the events, paging it in from the store
+// if needed
+func (lv *LogView) eventAt(index int) *logEventLine {
+    count := lv.storeCount()
+    if index < 0 {
+        index = 0
+    }
+    if index < count {
+        lv.pageIn(index-spillPageSize/2, index+spillPageSize/2)
+        for event := lv.firstEvent; event != nil; event = event.next {
+            if event.fromSpill && event.order <= 1 && event.spillIndex == index {
+                return event
+            }
+        }
+        return nil
+    }
+    var last *logEventLine
+    offset := index - count
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.order <= 1 && !event.fromSpill {
+            if offset == 0 {
+                return event
+            }
+            offset--
+            last = event
+        }
+    }
+    if last == nil && count > 0 {
+        // every event is in the store
+        return lv.eventAt(count - 1)
+    }
+    return last
+}
@@ -0,0 +1,87 @@
+package clogviewr
+
+import (
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestLogView_ScrollToIndex(t *testing.T) {
+    lv := newSpillingLogView(t, 1200)
+    defer lv.SetSpillFile("")
+
+    if lv.GetTotalEventCount() != 1200 {
+        t.Fatalf("Expected 1200 events, got %d", lv.GetTotalEventCount())
+    }
+    for _, index := range []int{0, 700, 1195, 3} {
+        if !lv.ScrollToIndex(index) || lv.GetCurrentEventIndex() != index {
+            t.Errorf("Expected event %d to be current, got %d", index, lv.GetCurrentEventIndex())
+        }
+    }
+    if !lv.ScrollToIndex(5000) || lv.GetCurrentEvent().EventID != "e1199" {
+        t.Errorf("Expected positions after the last event to go to the last event, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if !lv.ScrollToPercent(50) || lv.GetCurrentEvent().EventID != "e599" {
+        t.Errorf("Expected 50%% to go to e599, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if lv.IsFollowing() {
+        t.Errorf("Expected following to be disabled")
+    }
+
+    lv.PushFilter(EventFilter{Name: "tens", Predicate: func(event *LogEvent) bool { return strings.HasSuffix(event.EventID, "0") }})
+    if !lv.ScrollToIndex(701) || lv.GetCurrentEvent().EventID != "e710" {
+        t.Errorf("Expected hidden events to be replaced by the next displayed event, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if !lv.ScrollToIndex(1199) || lv.GetCurrentEvent().EventID != "e1190" {
+        t.Errorf("Expected the previous displayed event at the end, got %s", lv.GetCurrentEvent().EventID)
+    }
+
+    // relative moves to hidden events go to the displayed event in the direction of the move
+    lv.ScrollToIndex(700)
+    if !lv.ScrollByEvents(-1) || lv.GetCurrentEvent().EventID != "e690" {
+        t.Errorf("Expected -1 to go to the previous displayed event e690, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if !lv.ScrollByEvents(1) || lv.GetCurrentEvent().EventID != "e700" {
+        t.Errorf("Expected +1 to go to the next displayed event e700, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if !lv.ScrollByEvents(-700) || lv.GetCurrentEvent().EventID != "e0" {
+        t.Errorf("Expected -700 to go to e0, got %s", lv.GetCurrentEvent().EventID)
+    }
+}
+
+func TestLogView_SpillScrollToTimestamp(t *testing.T) {
+    lv := NewLogView()
+    lv.SetHighlightCurrentEvent(true)
+    if err := lv.SetSpillFile(filepath.Join(t.TempDir(), "spill.log")); err != nil {
+        t.Fatal(err)
+    }
+    defer lv.SetSpillFile("")
+    lv.SetMaxEvents(10)
+    start := time.Now().Add(-24 * time.Hour)
+    lv.AppendEvents(randomEvents(1200, start))
+
+    if !lv.ScrollToTimestamp(start.Add(300*time.Second)) || lv.GetCurrentEvent().EventID != "e300" {
+        t.Errorf("Expected spilled e300 to be found by timestamp, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if lv.GetCurrentEventIndex() != 300 {
+        t.Errorf("Expected position 300, got %d", lv.GetCurrentEventIndex())
+    }
+}
+
+func TestParseGotoTime(t *testing.T) {
+    reference := time.Date(2022, 3, 1, 18, 30, 0, 0, time.Local)
+    cases := map[string]time.Time{
+        "14:03:22":         time.Date(2022, 3, 1, 14, 3, 22, 0, time.Local),
+        "09:15":            time.Date(2022, 3, 1, 9, 15, 0, 0, time.Local),
+        "2021-12-31T23:59": time.Date(2021, 12, 31, 23, 59, 0, 0, time.Local),
+    }
+    for value, expected := range cases {
+        if actual, err := parseGotoTime(value, reference); err != nil || !actual.Equal(expected) {
+            t.Errorf("Expected %s to be %v, got %v %v", value, expected, actual, err)
+        }
+    }
+    if _, err := parseGotoTime("noon", reference); err == nil {
+        t.Errorf("Expected invalid time to fail")
+    }
+}
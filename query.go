@@ -0,0 +1,393 @@
+package clogviewr
+
+import (
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+    "unicode"
+)
+
+// Query is a compiled query matching log events. A query is a list of terms separated by spaces, an event
+// matches the query if it matches all the terms. A term prefixed with - or ! is negated.
+//
+// Terms:
+//
+// - word or "quoted phrase" - the message contains the text, ignoring case
+//
+// - msg:text, msg=text, msg~regexp - the message contains the text ignoring case, equals the text or matches
+// the regular expression. Source (src) and id are matched the same way, except that : matches source and id
+// against a pattern with * wildcards
+//
+// - level>=warn - compares the level, any of = != > >= < <= can be used with info, warn(ing) and error (err)
+//
+// - after:10:00, before:2021-03-01T10:00:00 - the timestamp is after or at the instant, or before it.
+// Instants without a date are matched against the time of day of the event
+//
+// - user.id=42 - any other name is a path in the event data, which must be a map or JSON friendly. Values are
+// compared as numbers if both of them are numbers
+//
+// Values containing spaces must be quoted, quotes and backslashes in quoted values are escaped with a backslash.
+type Query struct {
+    text  string
+    terms []queryTerm
+}
+
+// QueryError describes an invalid query
+type QueryError struct {
+    // Pos is the byte position of the invalid term in the query
+    Pos int
+    Msg string
+}
+
+func (e *QueryError) Error() string {
+    return fmt.Sprintf("invalid query at position %d: %s", e.Pos+1, e.Msg)
+}
+
+type queryTerm struct {
+    negated bool
+    match   func(event *LogEvent) bool
+}
+
+// queryOperators are ordered so that two character operators are found first
+var queryOperators = []string{"!=", "!~", ">=", "<=", ":", "=", "~", ">", "<"}
+
+var queryLevels = map[string]LogLevel{
+    "info":    LogLevelInfo,
+    "warn":    LogLevelWarning,
+    "warning": LogLevelWarning,
+    "err":     LogLevelError,
+    "error":   LogLevelError,
+}
+
+var queryTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+var queryTimeOfDayLayouts = []string{"15:04:05.000", "15:04:05", "15:04"}
+
+// ParseQuery compiles the query. Errors are reported as QueryError with the position of the invalid term.
+func ParseQuery(query string) (*Query, error) {
+    q := &Query{text: query, terms: make([]queryTerm, 0)}
+    pos := 0
+    for {
+        for pos < len(query) && query[pos] == ' ' {
+            pos++
+        }
+        if pos >= len(query) {
+            break
+        }
+        term, next, err := parseQueryTerm(query, pos)
+        if err != nil {
+            return nil, err
+        }
+        q.terms = append(q.terms, term)
+        pos = next
+    }
+    if len(q.terms) == 0 {
+        return nil, &QueryError{Pos: 0, Msg: "query is empty"}
+    }
+    return q, nil
+}
+
+// Match checks whether the event matches all the terms of the query
+func (q *Query) Match(event *LogEvent) bool {
+    for _, term := range q.terms {
+        if term.match(event) == term.negated {
+            return false
+        }
+    }
+    return true
+}
+
+// String returns the text of the query
+func (q *Query) String() string {
+    return q.text
+}
+
+// QueryFilter creates a filter from the query, the query is its name and its pattern is \q followed by the query
+func QueryFilter(query string) (EventFilter, error) {
+    q, err := ParseQuery(query)
+    if err != nil {
+        return EventFilter{}, err
+    }
+    return EventFilter{Name: query, Pattern: `\q ` + query, Predicate: q.Match}, nil
+}
+
+// *******************************
+// internal implementation details
+
+// parseQueryTerm parses the term starting at pos and returns the position after it
+func parseQueryTerm(query string, pos int) (queryTerm, int, error) {
+    start := pos
+    term := queryTerm{}
+    if (query[pos] == '-' || query[pos] == '!') && pos+1 < len(query) && query[pos+1] != ' ' {
+        term.negated = true
+        pos++
+    }
+    if query[pos] == '"' {
+        text, next, err := parseQueryValue(query, pos)
+        if err != nil {
+            return term, 0, err
+        }
+        term.match = containsMatcher(text, func(event *LogEvent) string { return event.Message })
+        return term, next, nil
+    }
+
+    nameEnd := pos
+    for nameEnd < len(query) && isQueryNameChar(rune(query[nameEnd])) {
+        nameEnd++
+    }
+    name := strings.ToLower(query[pos:nameEnd])
+    op := ""
+    for _, candidate := range queryOperators {
+        if strings.HasPrefix(query[nameEnd:], candidate) {
+            op = candidate
+            break
+        }
+    }
+    if name == "" || op == "" {
+        // a plain word
+        end := strings.IndexByte(query[pos:], ' ')
+        if end < 0 {
+            end = len(query) - pos
+        }
+        word := query[pos : pos+end]
+        term.match = containsMatcher(word, func(event *LogEvent) string { return event.Message })
+        return term, pos + end, nil
+    }
+
+    value, next, err := parseQueryValue(query, nameEnd+len(op))
+    if err != nil {
+        return term, 0, err
+    }
+    termText := query[start:next]
+    fail := func(format string, args ...interface{}) (queryTerm, int, error) {
+        return term, 0, &QueryError{Pos: start, Msg: fmt.Sprintf("%s in %s", fmt.Sprintf(format, args...), termText)}
+    }
+    switch name {
+    case "msg", "message":
+        term.match, err = textMatcher(op, value, false, func(event *LogEvent) string { return event.Message })
+    case "src", "source":
+        term.match, err = textMatcher(op, value, true, func(event *LogEvent) string { return event.Source })
+    case "id":
+        term.match, err = textMatcher(op, value, true, func(event *LogEvent) string { return event.EventID })
+    case "level", "lvl":
+        level, ok := queryLevels[strings.ToLower(value)]
+        if !ok {
+            return fail("unknown level %q, expected info, warn or error", value)
+        }
+        term.match, err = levelMatcher(op, level)
+    case "after", "before":
+        if op != ":" && op != "=" {
+            return fail("%s expects : before the time", name)
+        }
+        term.match, err = timeMatcher(name == "after", value)
+    default:
+        term.match, err = dataMatcher(op, strings.Split(query[pos:nameEnd], "."), value)
+    }
+    if err != nil {
+        return fail("%v", err)
+    }
+    return term, next, nil
+}
+
+// parseQueryValue parses a quoted or a plain value starting at pos and returns the position after it
+func parseQueryValue(query string, pos int) (string, int, error) {
+    if pos >= len(query) || query[pos] == ' ' {
+        return "", pos, &QueryError{Pos: pos, Msg: "value is missing"}
+    }
+    if query[pos] != '"' {
+        end := strings.IndexByte(query[pos:], ' ')
+        if end < 0 {
+            return query[pos:], len(query), nil
+        }
+        return query[pos : pos+end], pos + end, nil
+    }
+    value := strings.Builder{}
+    for i := pos + 1; i < len(query); i++ {
+        switch query[i] {
+        case '\\':
+            if i+1 < len(query) {
+                i++
+                value.WriteByte(query[i])
+            }
+        case '"':
+            if i+1 < len(query) && query[i+1] != ' ' {
+                return "", 0, &QueryError{Pos: i + 1, Msg: "space expected after closing quote"}
+            }
+            return value.String(), i + 1, nil
+        default:
+            value.WriteByte(query[i])
+        }
+    }
+    return "", 0, &QueryError{Pos: pos, Msg: "closing quote is missing"}
+}
+
+func isQueryNameChar(r rune) bool {
+    return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-'
+}
+
+func containsMatcher(text string, field func(event *LogEvent) string) func(event *LogEvent) bool {
+    text = strings.ToLower(text)
+    return func(event *LogEvent) bool {
+        return strings.Contains(strings.ToLower(field(event)), text)
+    }
+}
+
+// textMatcher matches a text field. If glob is true, : matches the field against a pattern with * wildcards,
+// otherwise it checks the field contains the value
+func textMatcher(op string, value string, glob bool, field func(event *LogEvent) string) (func(event *LogEvent) bool, error) {
+    switch op {
+    case ":":
+        if !glob {
+            return containsMatcher(value, field), nil
+        }
+        re, err := regexp.Compile("^" + strings.ReplaceAll(regexp.QuoteMeta(value), `\*`, ".*") + "$")
+        if err != nil {
+            return nil, err
+        }
+        return func(event *LogEvent) bool { return re.MatchString(field(event)) }, nil
+    case "=", "!=":
+        return func(event *LogEvent) bool { return (field(event) == value) == (op == "=") }, nil
+    case "~", "!~":
+        re, err := regexp.Compile(value)
+        if err != nil {
+            return nil, fmt.Errorf("invalid regular expression: %v", err)
+        }
+        return func(event *LogEvent) bool { return re.MatchString(field(event)) == (op == "~") }, nil
+    }
+    return nil, fmt.Errorf("operator %s cannot be used with text", op)
+}
+
+func levelMatcher(op string, level LogLevel) (func(event *LogEvent) bool, error) {
+    switch op {
+    case ":", "=":
+        return func(event *LogEvent) bool { return event.Level == level }, nil
+    case "!=":
+        return func(event *LogEvent) bool { return event.Level != level }, nil
+    case ">":
+        return func(event *LogEvent) bool { return event.Level > level }, nil
+    case ">=":
+        return func(event *LogEvent) bool { return event.Level >= level }, nil
+    case "<":
+        return func(event *LogEvent) bool { return event.Level < level }, nil
+    case "<=":
+        return func(event *LogEvent) bool { return event.Level <= level }, nil
+    }
+    return nil, fmt.Errorf("operator %s cannot be used with levels", op)
+}
+
+func timeMatcher(after bool, value string) (func(event *LogEvent) bool, error) {
+    for _, layout := range queryTimeLayouts {
+        if instant, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+            return func(event *LogEvent) bool { return event.Timestamp.Before(instant) != after }, nil
+        }
+    }
+    for _, layout := range queryTimeOfDayLayouts {
+        if clock, err := time.Parse(layout, value); err == nil {
+            offset := clock.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC))
+            return func(event *LogEvent) bool {
+                ts := event.Timestamp
+                midnight := time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, ts.Location())
+                return ts.Before(midnight.Add(offset)) != after
+            }, nil
+        }
+    }
+    return nil, fmt.Errorf("invalid time %q, expected 15:04, 15:04:05, 2006-01-02 or 2006-01-02T15:04:05", value)
+}
+
+// dataMatcher matches a value in the event data found by the path
+func dataMatcher(op string, path []string, value string) (func(event *LogEvent) bool, error) {
+    var compare func(actual string) bool
+    number, numberErr := strconv.ParseFloat(value, 64)
+    switch op {
+    case ":", "=", "!=":
+        compare = func(actual string) bool {
+            if n, err := strconv.ParseFloat(actual, 64); err == nil && numberErr == nil {
+                return (n == number) == (op != "!=")
+            }
+            return (actual == value) == (op != "!=")
+        }
+    case "~", "!~":
+        re, err := regexp.Compile(value)
+        if err != nil {
+            return nil, fmt.Errorf("invalid regular expression: %v", err)
+        }
+        compare = func(actual string) bool { return re.MatchString(actual) == (op == "~") }
+    default:
+        compare = func(actual string) bool {
+            var c int
+            if n, err := strconv.ParseFloat(actual, 64); err == nil && numberErr == nil {
+                c = compareFloats(n, number)
+            } else {
+                c = strings.Compare(actual, value)
+            }
+            switch op {
+            case ">":
+                return c > 0
+            case ">=":
+                return c >= 0
+            case "<":
+                return c < 0
+            default:
+                return c <= 0
+            }
+        }
+    }
+    return func(event *LogEvent) bool {
+        actual, ok := lookupData(event.Data, path)
+        return ok && compare(actual)
+    }, nil
+}
+
+func compareFloats(a, b float64) int {
+    if a < b {
+        return -1
+    }
+    if a > b {
+        return 1
+    }
+    return 0
+}
+
+// lookupData finds the value by the path in maps of the event data, other data is converted to maps through JSON
+func lookupData(data interface{}, path []string) (string, bool) {
+    if data == nil {
+        return "", false
+    }
+    switch data.(type) {
+    case map[string]interface{}, map[string]string:
+    default:
+        encoded, err := json.Marshal(data)
+        if err != nil || json.Unmarshal(encoded, &data) != nil {
+            return "", false
+        }
+    }
+    for _, key := range path {
+        switch m := data.(type) {
+        case map[string]interface{}:
+            value, ok := m[key]
+            if !ok {
+                return "", false
+            }
+            data = value
+        case map[string]string:
+            value, ok := m[key]
+            if !ok {
+                return "", false
+            }
+            data = value
+        default:
+            return "", false
+        }
+    }
+    switch value := data.(type) {
+    case string:
+        return value, true
+    case nil:
+        return "", false
+    default:
+        return fmt.Sprint(value), true
+    }
+}
@@ -0,0 +1,108 @@
+package clogviewr
+
+import (
+    "github.com/gdamore/tcell/v2"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseQuery(t *testing.T) {
+    event := NewLogEvent("e42", "Request timed out after 30s")
+    event.Source = "api-gateway"
+    event.Level = LogLevelWarning
+    event.Timestamp = time.Date(2021, 03, 01, 10, 30, 0, 0, time.Local)
+    event.Data = map[string]interface{}{"user": map[string]interface{}{"id": 42.0, "name": "alex"}}
+
+    cases := map[string]bool{
+        `timed`:                       true,
+        `TIMED out`:                   true,
+        `"timed out"`:                 true,
+        `"out timed"`:                 false,
+        `-timed`:                      false,
+        `level>=warn`:                 true,
+        `level>warn`:                  false,
+        `level=error`:                 false,
+        `!level=error`:                true,
+        `source:api*`:                 true,
+        `src:api`:                     false,
+        `source=api-gateway`:          true,
+        `msg~"time(out|d out)"`:       true,
+        `msg~^timed`:                  false,
+        `msg=timed`:                   false,
+        `id:e4*`:                      true,
+        `user.id=42`:                  true,
+        `user.id>=43`:                 false,
+        `user.name~^al`:               true,
+        `user.email=x`:                false,
+        `after:10:00`:                 true,
+        `after:10:30:01`:              false,
+        `before:2021-03-02`:           true,
+        `after:2021-03-01T11:00:00`:   false,
+        `level>=warn source:api* 30s`: true,
+        `level>=warn source:web* 30s`: false,
+        `msg:"after 30" -user.id=43`:  true,
+        `msg:"quote \" inside"`:       false,
+    }
+    for query, expected := range cases {
+        q, err := ParseQuery(query)
+        if err != nil {
+            t.Errorf("Failed to parse %s: %v", query, err)
+            continue
+        }
+        if q.Match(event) != expected {
+            t.Errorf("Expected %s to match: %v", query, expected)
+        }
+    }
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+    cases := map[string]string{
+        ``:                  "query is empty",
+        `level>=fatal`:      `unknown level "fatal"`,
+        `msg~"(unclosed"`:   "invalid regular expression",
+        `msg:"unterminated`: "closing quote is missing",
+        `timed src:`:        "value is missing",
+        `after:yesterday`:   "invalid time",
+        `level~warn`:        "cannot be used with levels",
+        `after>10:00`:       "after expects :",
+        `msg:"a"b`:          "space expected after closing quote",
+    }
+    for query, expected := range cases {
+        _, err := ParseQuery(query)
+        if err == nil || !strings.Contains(err.Error(), expected) {
+            t.Errorf("Expected error '%s' for %s, got %v", expected, query, err)
+        }
+    }
+    _, err := ParseQuery(`timed level>=fatal`)
+    if qe, ok := err.(*QueryError); !ok || qe.Pos != 6 {
+        t.Errorf("Expected error at position 6, got %v", err)
+    }
+}
+
+func TestLogView_HighlightRules(t *testing.T) {
+    lv := NewLogView()
+    lv.SetHighlighting(true)
+    lv.AppendEvents(randomEvents(10, time.Now()))
+
+    q, _ := ParseQuery(`msg~"[37]$"`)
+    lv.AddHighlightRule(HighlightRule{Name: q.String(), Predicate: q.Match, Color: tcell.ColorBlue})
+    lv.AppendEvent(NewLogEvent("e13", "Event #13"))
+
+    highlighted := make([]string, 0)
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if _, bg, _ := lv.baseStyle(event).Decompose(); bg == tcell.ColorBlue {
+            highlighted = append(highlighted, event.EventID)
+        }
+    }
+    if strings.Join(highlighted, ",") != "e3,e7,e13" {
+        t.Errorf("Expected e3, e7 and e13 to be highlighted, got %v", highlighted)
+    }
+
+    lv.ClearHighlightRules()
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.highlightRule != 0 {
+            t.Errorf("Expected highlight rules to be cleared, %s is highlighted", event.EventID)
+        }
+    }
+}
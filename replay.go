@@ -0,0 +1,334 @@
+package clogviewr
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// maxReplayWait is the longest replay waits without advancing its clock, so that anchors keep moving
+// even when there are long gaps between events
+const maxReplayWait = time.Second
+
+// LineParser converts a single line of a recorded log into a log event. lineNo starts with 1.
+// Returning nil event skips the line.
+type LineParser func(line string, lineNo int) (*LogEvent, error)
+
+// TimestampLineParser returns a line parser for lines that start with a timestamp in a given layout.
+// The rest of the line becomes the event message. Lines without a valid timestamp get the timestamp of
+// the previous line, so they are replayed together with it. The parser keeps that state, so it must not be shared
+// between files.
+func TimestampLineParser(layout string) LineParser {
+    fields := len(strings.Fields(layout))
+    var last time.Time
+    return func(line string, lineNo int) (*LogEvent, error) {
+        msg := line
+        timestamp := last
+        parts := strings.SplitN(line, " ", fields+1)
+        if len(parts) > fields {
+            if ts, err := time.Parse(layout, strings.Join(parts[:fields], " ")); err == nil {
+                msg = parts[fields]
+                timestamp = ts
+                last = ts
+            }
+        }
+        event := NewLogEvent(strconv.Itoa(lineNo), msg)
+        event.Timestamp = timestamp
+        return event, nil
+    }
+}
+
+// ReadEvents reads all lines from the reader and converts them to log events with the parser
+func ReadEvents(r io.Reader, parse LineParser) ([]*LogEvent, error) {
+    events := make([]*LogEvent, 0)
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+    lineNo := 0
+    for scanner.Scan() {
+        lineNo++
+        event, err := parse(scanner.Text(), lineNo)
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %v", lineNo, err)
+        }
+        if event != nil {
+            events = append(events, event)
+        }
+    }
+    return events, scanner.Err()
+}
+
+// Replay emits recorded events with their original inter-arrival times, as if they were live.
+//
+// Replay keeps its own clock that starts at the timestamp of the first event and advances with the wall clock
+// multiplied by speed. Every event with a timestamp before the replay clock is passed to the event listener, and
+// the clock listener is notified as the clock advances, i.e. to move the anchor of LogVelocityView.
+type Replay struct {
+    events []*LogEvent
+    pos    int
+
+    speed   float64
+    paused  bool
+    running bool
+
+    // clock is the replayed time at the wall clock instant wallRef
+    clock   time.Time
+    wallRef time.Time
+
+    onEvent func(event *LogEvent)
+    onClock func(clock time.Time)
+    onDone  func()
+
+    wakeup chan struct{}
+    stop   chan struct{}
+
+    sync.Mutex
+}
+
+// NewReplay creates a new replay of the events. Events are expected to be ordered by timestamp.
+// onEvent is called for every replayed event and onClock, if not nil, is called every time the replay clock advances.
+func NewReplay(events []*LogEvent, onEvent func(event *LogEvent), onClock func(clock time.Time)) *Replay {
+    r := &Replay{
+        events:  events,
+        speed:   1,
+        onEvent: onEvent,
+        onClock: onClock,
+        wakeup:  make(chan struct{}, 1),
+    }
+    for _, event := range events {
+        if !event.Timestamp.IsZero() {
+            r.clock = event.Timestamp
+            break
+        }
+    }
+    return r
+}
+
+// SetOnDone sets a listener that is called when all the events have been replayed
+func (r *Replay) SetOnDone(listener func()) {
+    r.Lock()
+    defer r.Unlock()
+
+    r.onDone = listener
+}
+
+// Start starts replaying events in a separate goroutine. Starting a running replay does nothing.
+func (r *Replay) Start() {
+    r.Lock()
+    defer r.Unlock()
+
+    if r.running {
+        return
+    }
+    r.running = true
+    r.wallRef = time.Now()
+    r.stop = make(chan struct{})
+    go r.run(r.stop)
+}
+
+// Stop stops the replay. It can be started again, and will continue from the current position.
+func (r *Replay) Stop() {
+    r.Lock()
+    defer r.Unlock()
+
+    r.halt()
+}
+
+// Pause freezes the replay clock
+func (r *Replay) Pause() {
+    r.Lock()
+    defer r.Unlock()
+
+    r.rebase()
+    r.paused = true
+    r.notify()
+}
+
+// Resume unfreezes the replay clock
+func (r *Replay) Resume() {
+    r.Lock()
+    defer r.Unlock()
+
+    r.rebase()
+    r.paused = false
+    r.notify()
+}
+
+// IsPaused returns whether the replay clock is frozen
+func (r *Replay) IsPaused() bool {
+    r.Lock()
+    defer r.Unlock()
+
+    return r.paused
+}
+
+// IsRunning returns whether the replay goroutine is running
+func (r *Replay) IsRunning() bool {
+    r.Lock()
+    defer r.Unlock()
+
+    return r.running
+}
+
+// SetSpeed sets the replay speed multiplier, i.e. 2 replays events twice as fast as they were recorded
+func (r *Replay) SetSpeed(speed float64) error {
+    if speed <= 0 {
+        return fmt.Errorf("invalid replay speed: %v", speed)
+    }
+    r.Lock()
+    defer r.Unlock()
+
+    r.rebase()
+    r.speed = speed
+    r.notify()
+    return nil
+}
+
+// GetSpeed returns the replay speed multiplier
+func (r *Replay) GetSpeed() float64 {
+    r.Lock()
+    defer r.Unlock()
+
+    return r.speed
+}
+
+// Step pauses the replay and emits next count events. The replay clock is moved to the last emitted event.
+// It returns the number of events emitted.
+func (r *Replay) Step(count int) int {
+    r.Lock()
+    r.paused = true
+    events := make([]*LogEvent, 0, count)
+    for len(events) < count && r.pos < len(r.events) {
+        event := r.events[r.pos]
+        events = append(events, event)
+        r.pos++
+        if !event.Timestamp.IsZero() {
+            r.clock = event.Timestamp
+        }
+    }
+    r.wallRef = time.Now()
+    clock := r.clock
+    r.notify()
+    r.Unlock()
+
+    r.emit(events, clock)
+    return len(events)
+}
+
+// Position returns the number of replayed events and the total number of events
+func (r *Replay) Position() (int, int) {
+    r.Lock()
+    defer r.Unlock()
+
+    return r.pos, len(r.events)
+}
+
+// Clock returns the current replay clock
+func (r *Replay) Clock() time.Time {
+    r.Lock()
+    defer r.Unlock()
+
+    return r.now()
+}
+
+// ****************
+// Internal methods
+
+func (r *Replay) run(stop chan struct{}) {
+    for {
+        r.Lock()
+        if r.stop != stop {
+            r.Unlock()
+            return
+        }
+        if r.pos >= len(r.events) {
+            r.halt()
+            onDone := r.onDone
+            r.Unlock()
+            if onDone != nil {
+                onDone()
+            }
+            return
+        }
+        if r.paused {
+            r.Unlock()
+            select {
+            case <-r.wakeup:
+            case <-stop:
+                return
+            }
+            continue
+        }
+
+        clock := r.now()
+        events := make([]*LogEvent, 0)
+        for r.pos < len(r.events) && !r.events[r.pos].Timestamp.After(clock) {
+            events = append(events, r.events[r.pos])
+            r.pos++
+        }
+        wait := maxReplayWait
+        if r.pos < len(r.events) {
+            if next := time.Duration(float64(r.events[r.pos].Timestamp.Sub(clock)) / r.speed); next < wait {
+                wait = next
+            }
+        }
+        r.Unlock()
+
+        r.emit(events, clock)
+
+        timer := time.NewTimer(wait)
+        select {
+        case <-timer.C:
+        case <-r.wakeup:
+            timer.Stop()
+        case <-stop:
+            timer.Stop()
+            return
+        }
+    }
+}
+
+func (r *Replay) emit(events []*LogEvent, clock time.Time) {
+    for _, event := range events {
+        r.onEvent(event)
+    }
+    if r.onClock != nil {
+        r.onClock(clock)
+    }
+}
+
+// now calculates the replay clock
+func (r *Replay) now() time.Time {
+    if r.paused || !r.running {
+        return r.clock
+    }
+    elapsed := time.Since(r.wallRef)
+    return r.clock.Add(time.Duration(float64(elapsed) * r.speed))
+}
+
+// rebase moves the replay clock reference to the current instant, must be called before changing speed or pausing
+func (r *Replay) rebase() {
+    r.clock = r.now()
+    r.wallRef = time.Now()
+}
+
+func (r *Replay) halt() {
+    if !r.running {
+        return
+    }
+    r.rebase()
+    r.running = false
+    close(r.stop)
+    r.stop = nil
+}
+
+// notify wakes up the replay goroutine so it can recalculate its timers
+func (r *Replay) notify() {
+    select {
+    case r.wakeup <- struct{}{}:
+    default:
+    }
+}
@@ -0,0 +1,93 @@
+package clogviewr
+
+import (
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestReadEvents(t *testing.T) {
+    text := "2021-03-01 10:00:00 first event\n" +
+        "  continuation\n" +
+        "2021-03-01 10:00:05 second\tevent\n"
+
+    events, err := ReadEvents(strings.NewReader(text), TimestampLineParser("2006-01-02 15:04:05"))
+    if err != nil {
+        t.Fatalf("Failed to read events: %v", err)
+    }
+    if len(events) != 3 {
+        t.Fatalf("Expected 3 events, got %d", len(events))
+    }
+    if events[0].Message != "first event" || events[0].Timestamp.Second() != 0 {
+        t.Errorf("Invalid first event: %v", events[0])
+    }
+    if events[1].Message != "  continuation" || !events[1].Timestamp.Equal(events[0].Timestamp) {
+        t.Errorf("Continuation line must get the timestamp of the previous line: %v", events[1])
+    }
+    if events[2].Message != "second    event" || events[2].Timestamp.Second() != 5 {
+        t.Errorf("Invalid last event: %v", events[2])
+    }
+}
+
+func TestReplay_Step(t *testing.T) {
+    ts := time.Date(2021, 03, 01, 10, 0, 0, 0, time.Local)
+    replayed := make([]string, 0)
+    var clock time.Time
+    r := NewReplay(randomEvents(10, ts),
+        func(event *LogEvent) {
+            replayed = append(replayed, event.EventID)
+        },
+        func(c time.Time) {
+            clock = c
+        })
+
+    if n := r.Step(3); n != 3 || len(replayed) != 3 || replayed[2] != "e2" {
+        t.Errorf("Expected 3 events to be replayed, got %v", replayed)
+    }
+    if !clock.Equal(ts.Add(2*time.Second)) || !r.IsPaused() {
+        t.Errorf("Step must pause the replay and move the clock to the last event, clock=%v", clock)
+    }
+    if n := r.Step(20); n != 7 {
+        t.Errorf("Expected remaining 7 events to be replayed, got %d", n)
+    }
+    if pos, total := r.Position(); pos != 10 || total != 10 {
+        t.Errorf("Expected replay to be at the end, got %d/%d", pos, total)
+    }
+}
+
+func TestReplay_Speed(t *testing.T) {
+    ts := time.Now()
+    done := make(chan struct{})
+    var lock sync.Mutex
+    count := 0
+    r := NewReplay(randomEvents(5, ts), func(event *LogEvent) {
+        lock.Lock()
+        count++
+        lock.Unlock()
+    }, nil)
+    if err := r.SetSpeed(0); err == nil {
+        t.Errorf("Zero speed must be rejected")
+    }
+    _ = r.SetSpeed(100)
+    r.SetOnDone(func() {
+        close(done)
+    })
+
+    start := time.Now()
+    r.Start()
+    select {
+    case <-done:
+    case <-time.After(5 * time.Second):
+        t.Fatalf("Replay did not finish in time")
+    }
+    // 4 seconds of recorded events replayed 100 times faster
+    if elapsed := time.Since(start); elapsed < 30*time.Millisecond || elapsed > 2*time.Second {
+        t.Errorf("Unexpected replay duration: %v", elapsed)
+    }
+    lock.Lock()
+    defer lock.Unlock()
+    if count != 5 || r.IsRunning() {
+        t.Errorf("Expected all 5 events to be replayed, got %d", count)
+    }
+}
@@ -0,0 +1,492 @@
+package clogviewr
+
+import (
+    "encoding/json"
+    "fmt"
+    "github.com/dlclark/regexp2"
+    "github.com/gdamore/tcell/v2"
+    "regexp"
+    "strings"
+    "time"
+    "unicode"
+)
+
+// SearchOptions define how the search text is matched
+type SearchOptions struct {
+    // Regexp treats the text as a Go (RE2) regular expression
+    Regexp bool
+    // ExtendedRegexp treats the text as a regexp2 regular expression, supporting lookarounds and backreferences
+    ExtendedRegexp bool
+    // IgnoreCase matches the text ignoring case
+    IgnoreCase bool
+    // SmartCase ignores case unless the text contains upper case letters
+    SmartCase bool
+    // WholeWord matches the text only at word boundaries
+    WholeWord bool
+    // InSource searches the event source too
+    InSource bool
+    // InData searches the event data, encoded as JSON, too
+    InData bool
+}
+
+// Search is a compiled search pattern
+type Search struct {
+    pattern string
+    options SearchOptions
+    query   *Query
+    match   func(text string) bool
+    // find returns byte offsets of the non-empty matches in the text
+    find func(text string) [][]int
+}
+
+// extendedRegexpTimeout limits the time regexp2 can spend backtracking on a single field
+const extendedRegexpTimeout = 100 * time.Millisecond
+
+// searchOptionFlags are the flags accepted by ParseSearch
+var searchOptionFlags = map[rune]func(options *SearchOptions){
+    'r': func(options *SearchOptions) { options.Regexp = true },
+    'x': func(options *SearchOptions) { options.ExtendedRegexp = true },
+    'i': func(options *SearchOptions) { options.IgnoreCase, options.SmartCase = true, false },
+    'c': func(options *SearchOptions) { options.IgnoreCase, options.SmartCase = false, false },
+    's': func(options *SearchOptions) { options.IgnoreCase, options.SmartCase = false, true },
+    'w': func(options *SearchOptions) { options.WholeWord = true },
+    'S': func(options *SearchOptions) { options.InSource = true },
+    'D': func(options *SearchOptions) { options.InData = true },
+}
+
+// ParseSearch compiles a search pattern entered by the user. A pattern may start with a backslash followed by
+// option flags and a space, i.e. \riS timed?out:
+//
+// - q - the rest is a query, see Query, it cannot be combined with other flags
+//
+// - r, x - the text is a Go (RE2) or a regexp2 regular expression
+//
+// - i, c, s - ignore case, match case (the default) or smart case: ignore case unless the text has upper case letters
+//
+// - w - match whole words only
+//
+// - S, D - search the source and the data of events too, not only the message
+//
+// A backslash followed by a space searches for the rest as is, i.e. "\ \r" searches for \r.
+func ParseSearch(pattern string) (*Search, error) {
+    if !strings.HasPrefix(pattern, `\`) {
+        return NewSearch(pattern, SearchOptions{})
+    }
+    flags := pattern[1:]
+    text := ""
+    if end := strings.IndexByte(flags, ' '); end >= 0 {
+        flags, text = flags[:end], flags[end+1:]
+    }
+    if flags == "q" {
+        q, err := ParseQuery(strings.TrimSpace(text))
+        if err != nil {
+            return nil, err
+        }
+        return &Search{pattern: pattern, query: q}, nil
+    }
+    options := SearchOptions{}
+    for _, flag := range flags {
+        set, ok := searchOptionFlags[flag]
+        if !ok {
+            if flag == 'q' {
+                return nil, fmt.Errorf("invalid search options \\%s: q cannot be combined with other options", flags)
+            }
+            return nil, fmt.Errorf("invalid search options \\%s: unknown option %c, expected q, r, x, i, c, s, w, S or D", flags, flag)
+        }
+        set(&options)
+    }
+    search, err := NewSearch(text, options)
+    if err != nil {
+        return nil, err
+    }
+    search.pattern = pattern
+    return search, nil
+}
+
+// NewSearch compiles the search text with the options
+func NewSearch(text string, options SearchOptions) (*Search, error) {
+    if text == "" {
+        return nil, fmt.Errorf("search pattern is empty")
+    }
+    if options.Regexp && options.ExtendedRegexp {
+        return nil, fmt.Errorf("invalid search options: r and x cannot be combined")
+    }
+    search := &Search{pattern: text, options: options}
+    ignoreCase := options.IgnoreCase || options.SmartCase && !hasUpper(text)
+    if !options.Regexp && !options.ExtendedRegexp && !options.WholeWord && !ignoreCase {
+        search.match = func(field string) bool { return strings.Contains(field, text) }
+        search.find = func(field string) [][]int {
+            matches := make([][]int, 0)
+            for pos := 0; ; {
+                index := strings.Index(field[pos:], text)
+                if index < 0 {
+                    return matches
+                }
+                pos += index + len(text)
+                matches = append(matches, []int{pos - len(text), pos})
+            }
+        }
+        return search, nil
+    }
+
+    expression := text
+    if !options.Regexp && !options.ExtendedRegexp {
+        expression = regexp.QuoteMeta(text)
+    }
+    if options.WholeWord {
+        expression = `\b(?:` + expression + `)\b`
+    }
+    if options.ExtendedRegexp {
+        reOptions := regexp2.None
+        if ignoreCase {
+            reOptions |= regexp2.IgnoreCase
+        }
+        re, err := regexp2.Compile(expression, reOptions)
+        if err != nil {
+            return nil, fmt.Errorf("invalid regular expression: %v", err)
+        }
+        re.MatchTimeout = extendedRegexpTimeout
+        search.match = func(field string) bool {
+            matched, err := re.MatchString(field)
+            return err == nil && matched
+        }
+        search.find = func(field string) [][]int {
+            matches := make([][]int, 0)
+            var offsets []int
+            match, err := re.FindStringMatch(field)
+            for err == nil && match != nil {
+                if match.Length > 0 {
+                    if offsets == nil {
+                        offsets = runeOffsets(field)
+                    }
+                    matches = append(matches, []int{offsets[match.Index], offsets[match.Index+match.Length]})
+                }
+                match, err = re.FindNextMatch(match)
+            }
+            return matches
+        }
+        return search, nil
+    }
+    if ignoreCase {
+        expression = "(?i)" + expression
+    }
+    re, err := regexp.Compile(expression)
+    if err != nil {
+        return nil, fmt.Errorf("invalid regular expression: %v", err)
+    }
+    search.match = re.MatchString
+    search.find = func(field string) [][]int {
+        matches := make([][]int, 0)
+        for _, match := range re.FindAllStringIndex(field, -1) {
+            if match[1] > match[0] {
+                matches = append(matches, match)
+            }
+        }
+        return matches
+    }
+    return search, nil
+}
+
+// Match checks whether the event matches the search
+func (s *Search) Match(event *LogEvent) bool {
+    if s.query != nil {
+        return s.query.Match(event)
+    }
+    if s.match(event.Message) || s.options.InSource && s.match(event.Source) {
+        return true
+    }
+    if !s.options.InData || event.Data == nil {
+        return false
+    }
+    return s.match(dataText(event.Data))
+}
+
+// Options returns the options of the search
+func (s *Search) Options() SearchOptions {
+    return s.options
+}
+
+// String returns the pattern of the search
+func (s *Search) String() string {
+    return s.pattern
+}
+
+// SetActiveSearch overlays the search match style on every occurrence of the search in the displayed messages,
+// nil clears the matches. Queries have no occurrences to highlight.
+func (lv *LogView) SetActiveSearch(search *Search) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.activeSearch = search
+    lv.searchMatchesOf = nil
+}
+
+// GetActiveSearch returns the search set by SetActiveSearch, or nil
+func (lv *LogView) GetActiveSearch() *Search {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.activeSearch
+}
+
+// SetSearchMatchStyle sets the style of search matches
+func (lv *LogView) SetSearchMatchStyle(style tcell.Style) {
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.searchMatchStyle = style
+}
+
+// ParsePattern compiles a search pattern entered by the user into a predicate, see ParseSearch
+func ParsePattern(pattern string) (func(event *LogEvent) bool, error) {
+    search, err := ParseSearch(pattern)
+    if err != nil {
+        return nil, err
+    }
+    return search.Match, nil
+}
+
+// *******************************
+// internal implementation details
+
+// searchMatchesFor returns the matches of the active search in the message of the event. Wrapped lines of an event
+// are drawn one after another, so the matches of the last event are kept for its next lines.
+func (lv *LogView) searchMatchesFor(event *logEventLine) [][]int {
+    if lv.activeSearch == nil || lv.activeSearch.find == nil {
+        return nil
+    }
+    if lv.searchMatchesOf != event.storedEvent || lv.searchMatchesLength != len(event.message) {
+        lv.searchMatchesOf = event.storedEvent
+        lv.searchMatchesLength = len(event.message)
+        lv.searchMatches = lv.activeSearch.find(event.message)
+    }
+    return lv.searchMatches
+}
+
+// runeOffsets returns byte offsets of the runes of the text, followed by the length of the text
+func runeOffsets(text string) []int {
+    offsets := make([]int, 0, len(text)+1)
+    for pos := range text {
+        offsets = append(offsets, pos)
+    }
+    return append(offsets, len(text))
+}
+
+func hasUpper(text string) bool {
+    for _, r := range text {
+        if unicode.IsUpper(r) {
+            return true
+        }
+    }
+    return false
+}
+
+// dataText returns the event data as text, data that cannot be encoded as JSON is formatted with fmt
+func dataText(data interface{}) string {
+    if text, ok := data.(string); ok {
+        return text
+    }
+    encoded, err := json.Marshal(data)
+    if err != nil {
+        return fmt.Sprint(data)
+    }
+    return string(encoded)
+}
+
+// findWrapping finds the first match after or before the event, wrapping around the ends of the log view.
+// It returns true if the search wrapped around. The search gives up, returning nil, once stop is closed.
+func (lv *LogView) findWrapping(eventID string, search *Search, backward bool, stop <-chan struct{}) (*LogEvent, bool) {
+    find := lv.findMatchingEvent
+    if backward {
+        find = lv.findPreviousMatchingEvent
+    }
+    event := find(eventID, search.Match, stop)
+    if event != nil || eventID == "" || isStopped(stop) {
+        return event, false
+    }
+    event = find("", search.Match, stop)
+    return event, event != nil
+}
+
+// isStopped checks whether the stop channel of a search is closed, searches with a nil channel are never stopped
+func isStopped(stop <-chan struct{}) bool {
+    select {
+    case <-stop:
+        return true
+    default:
+        return false
+    }
+}
+
+const (
+    // incrementalSearchDebounce delays incremental search on buffers larger than incrementalSearchDebounceEvents
+    // and open files
+    incrementalSearchDebounce       = 250 * time.Millisecond
+    incrementalSearchDebounceEvents = 50_000
+)
+
+// incrementalSearch searches the log view as /pattern or ?pattern is typed. Position, following and the active
+// search from before the search started are restored if it is cancelled. Results are reported with status, and
+// results of debounced searches are shown with queueUpdate, which runs them with the other updates of the log view.
+type incrementalSearch struct {
+    lv          *LogView
+    status      func(text string)
+    queueUpdate func(f func())
+
+    debounce       time.Duration
+    debounceEvents int
+
+    // typed is the search being typed, nil if no search is typed
+    typed *typedSearch
+}
+
+// typedSearch is the state of a search being typed
+type typedSearch struct {
+    origin    string
+    following bool
+    search    *Search
+    // typing on large buffers is debounced, results of superseded searches are dropped and their scans stopped
+    timer      *time.Timer
+    stop       chan struct{}
+    generation int
+}
+
+func newIncrementalSearch(lv *LogView, status func(text string), queueUpdate func(f func())) *incrementalSearch {
+    return &incrementalSearch{
+        lv:             lv,
+        status:         status,
+        queueUpdate:    queueUpdate,
+        debounce:       incrementalSearchDebounce,
+        debounceEvents: incrementalSearchDebounceEvents,
+    }
+}
+
+// update starts or updates the search as the text of the command entry changes, text that is not a search cancels it
+func (is *incrementalSearch) update(text string) {
+    if !strings.HasPrefix(text, "/") && !strings.HasPrefix(text, "?") {
+        is.cancel()
+        return
+    }
+    typed := is.typed
+    if typed == nil {
+        typed = &typedSearch{following: is.lv.IsFollowing(), search: is.lv.GetActiveSearch()}
+        if event := is.lv.GetCurrentEvent(); event != nil {
+            typed.origin = event.EventID
+        }
+        is.typed = typed
+    }
+    is.stopSearch(typed)
+    typed.generation++
+    generation := typed.generation
+    backward := text[0] == '?'
+    if len(text) == 1 {
+        is.restoreOrigin(typed)
+        return
+    }
+    pattern, err := ParseSearch(text[1:])
+    if err != nil {
+        is.status(err.Error())
+        return
+    }
+    is.lv.SetActiveSearch(pattern)
+    if !is.lv.IsFileOpen() && int(is.lv.EventCount())+is.lv.GetSpilledEventCount() < is.debounceEvents {
+        event, _ := is.lv.findWrapping(typed.origin, pattern, backward, nil)
+        is.showResult(typed, event)
+        if event != nil {
+            is.showHits(event, is.lv.FindTotalMatches(pattern.Match))
+        }
+        return
+    }
+    // the match is shown as soon as it is found, matches are counted only after that
+    stop := make(chan struct{})
+    typed.stop = stop
+    current := func() bool {
+        return is.typed == typed && typed.generation == generation
+    }
+    typed.timer = time.AfterFunc(is.debounce, func() {
+        event, _ := is.lv.findWrapping(typed.origin, pattern, backward, stop)
+        is.queueUpdate(func() {
+            if !current() {
+                return
+            }
+            is.showResult(typed, event)
+            if event == nil {
+                return
+            }
+            go func() {
+                hits := is.lv.countMatches(pattern.Match, stop)
+                is.queueUpdate(func() {
+                    if current() {
+                        is.showHits(event, hits)
+                    }
+                })
+            }()
+        })
+    })
+}
+
+func (is *incrementalSearch) showResult(typed *typedSearch, event *LogEvent) {
+    if event == nil {
+        is.restorePosition(typed)
+        is.status("No matches, Escape returns to where the search started")
+        return
+    }
+    is.lv.ScrollToEventID(event.EventID)
+    is.status(fmt.Sprintf("EventID: %s counting matches, Enter to search, Escape to cancel", event.EventID))
+}
+
+func (is *incrementalSearch) showHits(event *LogEvent, hits int) {
+    is.status(fmt.Sprintf("EventID: %s total maches: %d, Enter to search, Escape to cancel", event.EventID, hits))
+}
+
+// stopSearch stops the debounced search and the scans it started
+func (is *incrementalSearch) stopSearch(typed *typedSearch) {
+    if typed.timer != nil {
+        typed.timer.Stop()
+    }
+    if typed.stop != nil {
+        close(typed.stop)
+        typed.stop = nil
+    }
+}
+
+// finish keeps the position of the search when a command is entered. Entered searches start from the returned
+// origin of the search, so that they find the match already displayed. Other commands restore the origin, false is
+// returned for them and when no search was typed.
+func (is *incrementalSearch) finish(cmd string) (origin string, ok bool) {
+    typed := is.typed
+    if typed == nil {
+        return "", false
+    }
+    is.stopSearch(typed)
+    is.typed = nil
+    if strings.HasPrefix(cmd, "/") || strings.HasPrefix(cmd, "?") {
+        return typed.origin, true
+    }
+    is.restoreOrigin(typed)
+    return "", false
+}
+
+// cancel restores the position and the active search from before the search started
+func (is *incrementalSearch) cancel() {
+    typed := is.typed
+    if typed == nil {
+        return
+    }
+    is.stopSearch(typed)
+    is.typed = nil
+    is.restoreOrigin(typed)
+    is.status("Search cancelled")
+}
+
+func (is *incrementalSearch) restoreOrigin(typed *typedSearch) {
+    is.lv.SetActiveSearch(typed.search)
+    is.restorePosition(typed)
+}
+
+func (is *incrementalSearch) restorePosition(typed *typedSearch) {
+    if typed.following || typed.origin == "" || !is.lv.ScrollToEventID(typed.origin) {
+        is.lv.ScrollToBottom()
+    }
+    is.lv.SetFollowing(typed.following)
+}
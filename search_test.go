@@ -0,0 +1,259 @@
+package clogviewr
+
+import (
+    "fmt"
+    "github.com/gdamore/tcell/v2"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseSearch(t *testing.T) {
+    event := NewLogEvent("e1", "Request timed out after 30s")
+    event.Source = "api-gateway"
+    event.Level = LogLevelError
+    event.Data = map[string]interface{}{"user": "alex"}
+
+    cases := map[string]bool{
+        `timed`:               true,
+        `Timed`:               false,
+        `\i Timed`:            true,
+        `\s timed`:            true,
+        `\s Timed`:            false,
+        `\w time`:             false,
+        `\w timed out`:        true,
+        `\r time[sd] out`:     true,
+        `\ri ^REQUEST`:        true,
+        `\rw \d+`:             false,
+        `\x timed(?= out)`:    true,
+        `\x timed(?! out)`:    false,
+        `\xi (?<w>OUT) after`: true,
+        `gateway`:             false,
+        `\S gateway`:          true,
+        `\D alex`:             true,
+        `\Dw ale`:             false,
+        `\q Timed`:            true,
+        `\q level=warn`:       false,
+        `\ timed`:             true,
+        `\ \r`:                false,
+    }
+    for pattern, expected := range cases {
+        predicate, err := ParsePattern(pattern)
+        if err != nil {
+            t.Errorf("Failed to parse %s: %v", pattern, err)
+        } else if predicate(event) != expected {
+            t.Errorf("Expected %s to match: %v", pattern, expected)
+        }
+    }
+}
+
+func TestParseSearch_Errors(t *testing.T) {
+    cases := map[string]string{
+        `\q level=fatal`: `unknown level "fatal"`,
+        `\r (unclosed`:   "invalid regular expression",
+        `\x (unclosed`:   "invalid regular expression",
+        `\rx timed`:      "r and x cannot be combined",
+        `\qr timed`:      "q cannot be combined",
+        `\z timed`:       "unknown option z",
+        `\i`:             "search pattern is empty",
+    }
+    for pattern, expected := range cases {
+        _, err := ParseSearch(pattern)
+        if err == nil || !strings.Contains(err.Error(), expected) {
+            t.Errorf("Expected error '%s' for %s, got %v", expected, pattern, err)
+        }
+    }
+}
+
+func TestLogView_SearchMatches(t *testing.T) {
+    screen := tcell.NewSimulationScreen("UTF-8")
+    screen.Init()
+    screen.SetSize(20, 2)
+
+    lv := NewLogView()
+    lv.pageWidth = 10
+    lv.SetLineWrap(true)
+    lv.AppendEvent(NewLogEvent("e1", "0123456789abcdefghij"))
+
+    search, _ := ParseSearch(`\ri 89AB|[d-e]`)
+    lv.SetActiveSearch(search)
+    matched := func() string {
+        text := strings.Builder{}
+        y := 0
+        for event := lv.firstEvent; event != nil; event = event.next {
+            lv.drawEvent(screen, 0, y, event)
+            for x := 0; x < 10; x++ {
+                r, _, style, _ := screen.GetContent(x, y)
+                if style == lv.searchMatchStyle {
+                    text.WriteRune(r)
+                }
+            }
+            y++
+        }
+        return text.String()
+    }
+    if second := lv.firstEvent.next; second == nil || second.order != 2 {
+        t.Fatalf("Expected the event to be wrapped")
+    }
+    if text := matched(); text != "89abde" {
+        t.Errorf("Expected matches across wrapped lines to be highlighted, got %s", text)
+    }
+    lv.SetHighlighting(false)
+    if text := matched(); text != "89abde" {
+        t.Errorf("Expected matches to be highlighted without highlighting, got %s", text)
+    }
+    lv.SetActiveSearch(nil)
+    if text := matched(); text != "" {
+        t.Errorf("Expected no matches after the search is cleared, got %s", text)
+    }
+}
+
+func TestLogView_SearchResults(t *testing.T) {
+    lv := NewLogView()
+    lv.PushFilter(EventFilter{Name: "not debug", Predicate: func(event *LogEvent) bool { return !strings.Contains(event.Message, "debug") }})
+    appended := make([]*LogEvent, 0)
+    lv.SetOnAppended(func(events []*LogEvent) {
+        appended = append(appended, events...)
+    })
+    for i := 0; i < 10; i++ {
+        lv.AppendEvent(NewLogEvent(fmt.Sprintf("e%d", i), fmt.Sprintf("request %d failed", i)))
+    }
+    lv.AppendEvent(NewLogEvent("d", "debug: request failed"))
+    if len(appended) != 10 {
+        t.Errorf("Expected 10 displayed events to be reported as appended, got %d", len(appended))
+    }
+
+    search, _ := ParseSearch("failed")
+    events, total := lv.FindAllMatches(search.Match, 3)
+    if total != 10 || len(events) != 3 || events[0].EventID != "e7" || events[2].EventID != "e9" {
+        t.Errorf("Expected the last 3 of 10 matches, got %d of %d", len(events), total)
+    }
+
+    rv := NewSearchResultsView()
+    rv.SetLimit(5)
+    rv.SetResults(search, events, total)
+    rv.AddEvents([]*LogEvent{NewLogEvent("e10", "request 10 failed"), NewLogEvent("e11", "request 11 done")})
+    rv.AddEvents([]*LogEvent{NewLogEvent("e10", "request 10 failed\nretrying")})
+    results, total := rv.GetResults()
+    if total != 11 || len(results) != 4 || results[3].Message != "request 10 failed\nretrying" {
+        t.Errorf("Expected continued event to replace its result, got %d of %d", len(results), total)
+    }
+    for i := 12; i < 20; i++ {
+        rv.AddEvents([]*LogEvent{NewLogEvent(fmt.Sprintf("e%d", i), "failed")})
+    }
+    if results, total = rv.GetResults(); total != 19 || len(results) != 5 || results[4].EventID != "e19" {
+        t.Errorf("Expected the last 5 of 19 matches, got %d of %d", len(results), total)
+    }
+
+    search, _ = ParseSearch("\\i FAILED")
+    rv.SetResults(search, nil, 0)
+    if snippet := rv.snippet("a very long message prefix of the request that failed\nretrying"); snippet != "…of the request that [black:yellow]failed[-:-] retrying" {
+        t.Errorf("Expected snippet around the match, got %s", snippet)
+    }
+}
+
+func TestIncrementalSearch(t *testing.T) {
+    lv := NewLogView()
+    lv.AppendEvents(randomEvents(10, time.Now()))
+    lv.ScrollToEventID("e2")
+    lv.SetFollowing(false)
+    status := ""
+    is := newIncrementalSearch(lv, func(text string) { status = text }, func(f func()) { f() })
+    current := func() string {
+        if event := lv.GetCurrentEvent(); event != nil {
+            return event.EventID
+        }
+        return ""
+    }
+
+    for _, text := range []string{"/", "/E", "/Event #", "/Event #5"} {
+        is.update(text)
+    }
+    if current() != "e5" || lv.GetActiveSearch() == nil || lv.GetActiveSearch().String() != "Event #5" {
+        t.Errorf("Expected typing to move to e5, got %s", current())
+    }
+    is.update("/Event #7")
+    if current() != "e7" {
+        t.Errorf("Expected typing to move to e7, got %s", current())
+    }
+    is.update("?Event #1")
+    if current() != "e1" {
+        t.Errorf("Expected backward search from the origin to move to e1, got %s", current())
+    }
+    is.update("/missing")
+    if current() != "e2" || !strings.HasPrefix(status, "No matches") {
+        t.Errorf("Expected no match to return to the origin e2, got %s: %s", current(), status)
+    }
+
+    // Escape restores the position, following and the active search
+    is.update("/Event #5")
+    is.cancel()
+    if current() != "e2" || lv.IsFollowing() || lv.GetActiveSearch() != nil || status != "Search cancelled" {
+        t.Errorf("Expected cancel to restore e2, got %s following %v: %s", current(), lv.IsFollowing(), status)
+    }
+    // text that is not a search cancels too
+    is.update("/Event #5")
+    is.update("")
+    if current() != "e2" || is.typed != nil {
+        t.Errorf("Expected cleared command entry to cancel the search, got %s", current())
+    }
+
+    is.update("/Event #5")
+    if origin, ok := is.finish("/Event #5"); !ok || origin != "e2" || current() != "e5" || is.typed != nil {
+        t.Errorf("Expected entered search to keep e5 and continue from e2, got %s from %s", current(), origin)
+    }
+    is.update("/Event #5")
+    if _, ok := is.finish(":level error"); ok || current() != "e5" || lv.GetActiveSearch().String() != "Event #5" {
+        t.Errorf("Expected other commands to restore the origin e5, got %s", current())
+    }
+}
+
+func TestIncrementalSearch_Debounce(t *testing.T) {
+    lv := NewLogView()
+    lv.AppendEvents(randomEvents(10, time.Now()))
+    lv.ScrollToEventID("e2")
+    lv.SetFollowing(false)
+    updates := make(chan func(), 2)
+    status := ""
+    is := newIncrementalSearch(lv, func(text string) { status = text }, func(f func()) { updates <- f })
+    is.debounce = time.Millisecond
+    is.debounceEvents = 0
+    current := func() string {
+        return lv.GetCurrentEvent().EventID
+    }
+
+    is.update("/Event #5")
+    first := <-updates
+    stop := is.typed.stop
+    is.update("/Event #7")
+    second := <-updates
+    if !isStopped(stop) {
+        t.Errorf("Expected the scans of a superseded search to be stopped")
+    }
+    if current() != "e2" {
+        t.Errorf("Expected debounced search to wait for the update, got %s", current())
+    }
+    first()
+    if current() != "e2" {
+        t.Errorf("Expected the result of a superseded search to be dropped, got %s", current())
+    }
+    second()
+    if current() != "e7" || !strings.Contains(status, "counting matches") {
+        t.Errorf("Expected the result of the last search before its matches are counted, got %s: %s", current(), status)
+    }
+    (<-updates)()
+    if !strings.Contains(status, "total maches: 1") {
+        t.Errorf("Expected matches to be counted after the result is shown, got %s", status)
+    }
+
+    is.update("/Event #5")
+    late := <-updates
+    is.cancel()
+    late()
+    if current() != "e2" {
+        t.Errorf("Expected the result of a cancelled search to be dropped, got %s", current())
+    }
+    if matches := lv.countMatches(func(*LogEvent) bool { return true }, stop); matches != 0 {
+        t.Errorf("Expected a stopped count to give up, counted %d matches", matches)
+    }
+}
@@ -0,0 +1,284 @@
+package clogviewr
+
+import (
+    gui "code.rocketnine.space/tslocum/cview"
+    "fmt"
+    "github.com/gdamore/tcell/v2"
+    "strings"
+    "sync"
+    "unicode/utf8"
+)
+
+// OnResultSelected is a listener called when a result is selected in SearchResultsView
+type OnResultSelected func(event *LogEvent)
+
+// SearchResultsView is a table of events matching a search with their timestamp, source and the part of the message
+// around the first match. Matching events appended to the log view are added with AddEvents.
+type SearchResultsView struct {
+    *gui.Table
+
+    search  *Search
+    results []*LogEvent
+    total   int
+    limit   int
+    // rows of the results before drawn are already in the table, the table is rebuilt on next draw if stale
+    drawn int
+    stale bool
+
+    timestampFormat string
+    matchColor      tcell.Color
+    matchBgColor    tcell.Color
+
+    onSelected OnResultSelected
+
+    sync.Mutex
+}
+
+const (
+    // defaultSearchResultsLimit is the number of the most recent results kept by a new SearchResultsView
+    defaultSearchResultsLimit = 10_000
+    // snippetContext is the number of characters displayed before the first match of a result
+    snippetContext = 20
+)
+
+// NewSearchResultsView creates a new empty search results view
+func NewSearchResultsView() *SearchResultsView {
+    rv := &SearchResultsView{
+        Table:           gui.NewTable(),
+        limit:           defaultSearchResultsLimit,
+        timestampFormat: "15:04:05",
+        matchColor:      tcell.ColorBlack,
+        matchBgColor:    tcell.ColorYellow,
+    }
+    rv.SetSelectable(true, false)
+    rv.SetFixed(1, 0)
+    rv.SetSeparator(' ')
+    rv.SetSelectedFunc(func(row, _ int) {
+        rv.Lock()
+        var event *LogEvent
+        if row > 0 && row <= len(rv.results) {
+            event = rv.results[row-1]
+        }
+        listener := rv.onSelected
+        rv.Unlock()
+        if event != nil && listener != nil {
+            listener(event)
+        }
+    })
+    rv.stale = true
+    rv.refresh()
+    return rv
+}
+
+// SetResults replaces the results with events found by the search and the total number of matches, which can be
+// greater than the number of events. A nil search clears the results.
+func (rv *SearchResultsView) SetResults(search *Search, events []*LogEvent, total int) {
+    rv.Lock()
+    defer rv.Unlock()
+
+    rv.search = search
+    rv.results = nil
+    rv.total = 0
+    if search != nil {
+        rv.results = events
+        rv.total = total
+        rv.trim()
+    }
+    rv.stale = true
+}
+
+// GetSearch returns the search of the results, or nil
+func (rv *SearchResultsView) GetSearch() *Search {
+    rv.Lock()
+    defer rv.Unlock()
+
+    return rv.search
+}
+
+// GetResults returns the results and the total number of matches
+func (rv *SearchResultsView) GetResults() ([]*LogEvent, int) {
+    rv.Lock()
+    defer rv.Unlock()
+
+    return append([]*LogEvent(nil), rv.results...), rv.total
+}
+
+// AddEvents adds the events matching the search to the results. An event that is already the last result, continued
+// by following lines, replaces it. It returns true if the results have changed.
+func (rv *SearchResultsView) AddEvents(events []*LogEvent) bool {
+    rv.Lock()
+    defer rv.Unlock()
+
+    if rv.search == nil {
+        return false
+    }
+    changed := false
+    for _, event := range events {
+        last := len(rv.results) - 1
+        if last >= 0 && rv.results[last].EventID == event.EventID {
+            // the row of the event is drawn again with the whole message
+            if rv.search.Match(event) {
+                rv.results[last] = event
+            } else {
+                rv.results = rv.results[:last]
+                rv.total--
+            }
+            rv.drawn = minInt(rv.drawn, last)
+            changed = true
+            continue
+        }
+        if rv.search.Match(event) {
+            rv.results = append(rv.results, event)
+            rv.total++
+            changed = true
+        }
+    }
+    if len(rv.results) >= rv.limit+rv.limit/10 {
+        rv.trim()
+    }
+    return changed
+}
+
+// SetLimit sets the number of the most recent results that are kept
+func (rv *SearchResultsView) SetLimit(limit int) {
+    rv.Lock()
+    defer rv.Unlock()
+
+    rv.limit = limit
+    rv.trim()
+}
+
+// SetOnResultSelected sets a listener that is called when a result is selected with Enter or a mouse click
+func (rv *SearchResultsView) SetOnResultSelected(listener OnResultSelected) {
+    rv.Lock()
+    defer rv.Unlock()
+
+    rv.onSelected = listener
+}
+
+// SetTimestampFormat sets the format of result timestamps
+func (rv *SearchResultsView) SetTimestampFormat(format string) {
+    rv.Lock()
+    defer rv.Unlock()
+
+    rv.timestampFormat = format
+    rv.stale = true
+}
+
+// SetMatchColors sets the colors of the matches in the results
+func (rv *SearchResultsView) SetMatchColors(color, bgColor tcell.Color) {
+    rv.Lock()
+    defer rv.Unlock()
+
+    rv.matchColor = color
+    rv.matchBgColor = bgColor
+    rv.stale = true
+}
+
+// Draw refreshes the table with the results added since the last draw and draws it
+func (rv *SearchResultsView) Draw(screen tcell.Screen) {
+    rv.refresh()
+    rv.Table.Draw(screen)
+}
+
+// ****************
+// Internal methods
+
+// trim drops the oldest results over the limit
+func (rv *SearchResultsView) trim() {
+    if rv.limit > 0 && len(rv.results) > rv.limit {
+        rv.results = append([]*LogEvent(nil), rv.results[len(rv.results)-rv.limit:]...)
+        rv.stale = true
+    }
+}
+
+func (rv *SearchResultsView) refresh() {
+    rv.Lock()
+    defer rv.Unlock()
+
+    if !rv.stale && rv.drawn == len(rv.results) && rv.drawn == rv.GetRowCount()-1 {
+        return
+    }
+    if rv.search == nil {
+        rv.SetTitle("Search results, search with /pattern first")
+    } else if rv.total > len(rv.results) {
+        rv.SetTitle(fmt.Sprintf("%d matches of %s, showing the last %d", rv.total, rv.search, len(rv.results)))
+    } else {
+        rv.SetTitle(fmt.Sprintf("%d matches of %s", rv.total, rv.search))
+    }
+
+    row, _ := rv.GetSelection()
+    if rv.stale {
+        // keep the selected result selected as older results are dropped
+        selectedID := ""
+        if row > 0 && row < rv.GetRowCount() {
+            selectedID, _ = rv.GetCell(row, 0).GetReference().(string)
+        }
+        rv.stale = false
+        rv.drawn = 0
+        rv.Clear()
+        for col, title := range []string{"Time", "Source", "Message"} {
+            cell := gui.NewTableCell(title)
+            cell.SetSelectable(false)
+            cell.SetAttributes(tcell.AttrBold)
+            rv.SetCell(0, col, cell)
+        }
+        row = 1
+        for i, event := range rv.results {
+            if event.EventID == selectedID {
+                row = i + 1
+            }
+        }
+    }
+    for rv.GetRowCount()-1 > rv.drawn {
+        rv.RemoveRow(rv.GetRowCount() - 1)
+    }
+    for i := rv.drawn; i < len(rv.results); i++ {
+        event := rv.results[i]
+        cells := []*gui.TableCell{
+            gui.NewTableCell(event.Timestamp.Format(rv.timestampFormat)),
+            gui.NewTableCell(gui.Escape(event.Source)),
+            gui.NewTableCell(rv.snippet(event.Message)),
+        }
+        cells[0].SetReference(event.EventID)
+        cells[2].SetExpansion(1)
+        for col, cell := range cells {
+            rv.SetCell(i+1, col, cell)
+        }
+    }
+    rv.drawn = len(rv.results)
+    rv.Select(minInt(maxInt(row, 1), maxInt(len(rv.results), 1)), 0)
+}
+
+// snippet returns the message on a single line starting shortly before the first match, with the matches colored
+func (rv *SearchResultsView) snippet(message string) string {
+    message = strings.NewReplacer("\r\n", "  ", "\n", " ", "\r", " ", "\t", " ").Replace(message)
+    var matches [][]int
+    if rv.search.find != nil {
+        matches = rv.search.find(message)
+    }
+    if len(matches) == 0 {
+        return gui.Escape(message)
+    }
+
+    start := matches[0][0]
+    for i := 0; i < snippetContext && start > 0; i++ {
+        _, size := utf8.DecodeLastRuneInString(message[:start])
+        start -= size
+    }
+    var b strings.Builder
+    if start > 0 {
+        b.WriteString("…")
+    }
+    tag := fmt.Sprintf("[%s:%s]", colorName(rv.matchColor), colorName(rv.matchBgColor))
+    pos := start
+    for _, match := range matches {
+        b.WriteString(gui.Escape(message[pos:match[0]]))
+        b.WriteString(tag)
+        b.WriteString(gui.Escape(message[match[0]:match[1]]))
+        b.WriteString("[-:-]")
+        pos = match[1]
+    }
+    b.WriteString(gui.Escape(message[pos:]))
+    return b.String()
+}
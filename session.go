@@ -0,0 +1,401 @@
+package clogviewr
+
+import (
+    "compress/gzip"
+    "encoding/json"
+    "fmt"
+    "github.com/dlclark/regexp2"
+    "github.com/gdamore/tcell/v2"
+    "io"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "time"
+)
+
+// sessionVersion is the version of the session file format
+const sessionVersion = 1
+
+// session is a serialized state of a log view. Current and top events are stored as indexes in the event list,
+// because event ids are not guaranteed to be unique, or as indexes of lines in the open file.
+type session struct {
+    Version int            `json:"version"`
+    Events  []sessionEvent `json:"events"`
+    File    *sessionFile   `json:"file,omitempty"`
+
+    Current   int  `json:"current"`
+    Top       int  `json:"top"`
+    TopLine   int  `json:"topLine,omitempty"`
+    Following bool `json:"following"`
+
+    EventLimit         uint            `json:"eventLimit,omitempty"`
+    SourceLimits       map[string]uint `json:"sourceLimits,omitempty"`
+    DefaultSourceLimit uint            `json:"defaultSourceLimit,omitempty"`
+
+    ConcatenateEvents  bool   `json:"concatenateEvents,omitempty"`
+    NewEventMatcher    string `json:"newEventMatcher,omitempty"`
+    CollapseDuplicates bool   `json:"collapseDuplicates,omitempty"`
+    MaskDuplicates     bool   `json:"maskDuplicates,omitempty"`
+
+    // filters and highlight rules are stored as patterns like in views, the template filter as the template
+    Filters        []string      `json:"filters,omitempty"`
+    ContextBefore  int           `json:"contextBefore,omitempty"`
+    ContextAfter   int           `json:"contextAfter,omitempty"`
+    ContextWindow  time.Duration `json:"contextWindow,omitempty"`
+    HiddenLevels   []string      `json:"hiddenLevels,omitempty"`
+    HiddenSources  []string      `json:"hiddenSources,omitempty"`
+    TemplateFilter string        `json:"templateFilter,omitempty"`
+
+    Highlighting     bool            `json:"highlighting"`
+    HighlightPattern string          `json:"highlightPattern,omitempty"`
+    HighlightRules   []ViewHighlight `json:"highlightRules,omitempty"`
+    HighlightLevels  bool            `json:"highlightLevels"`
+    HighlightCurrent bool            `json:"highlightCurrent"`
+    WarningBgColor   int32           `json:"warningBgColor"`
+    ErrorBgColor     int32           `json:"errorBgColor"`
+    CurrentBgColor   int32           `json:"currentBgColor"`
+
+    ShowSource       bool   `json:"showSource"`
+    SourceClipLength int    `json:"sourceClipLength"`
+    ShowTimestamp    bool   `json:"showTimestamp"`
+    TimestampFormat  string `json:"timestampFormat"`
+    Wrap             bool   `json:"wrap"`
+}
+
+// sessionEvent is an event of a session with its bookmark and marks
+type sessionEvent struct {
+    eventRecord
+    Bookmarked bool   `json:"bookmarked,omitempty"`
+    Marks      string `json:"marks,omitempty"`
+}
+
+// sessionFile is the file open in the log view. Lines of the file are not stored in the session, they are referred
+// to by their index in the file. Current and top are -1 if they are not lines of the file.
+type sessionFile struct {
+    Path    string        `json:"path"`
+    Marks   []sessionMark `json:"marks,omitempty"`
+    Current int           `json:"current"`
+    Top     int           `json:"top"`
+    TopLine int           `json:"topLine,omitempty"`
+}
+
+// sessionMark is the bookmark and the marks of a line of the open file
+type sessionMark struct {
+    Index      int    `json:"index"`
+    Bookmarked bool   `json:"bookmarked,omitempty"`
+    Marks      string `json:"marks,omitempty"`
+}
+
+// SaveSession writes all events, including spilled ones, current and top positions, highlighting and display
+// settings into a compact gzip compressed file. Another log view can be restored to exactly the same state
+// with LoadSession.
+//
+// Lines of an open file are not written, the session refers to the file by its path, and to its lines by their
+// position in the file.
+func (lv *LogView) SaveSession(w io.Writer) error {
+    lv.Lock()
+    defer lv.Unlock()
+
+    s := session{
+        Version:            sessionVersion,
+        Events:             make([]sessionEvent, 0, lv.eventCount),
+        Current:            -1,
+        Top:                -1,
+        Following:          lv.following,
+        EventLimit:         lv.eventLimit,
+        SourceLimits:       lv.sourceLimits,
+        DefaultSourceLimit: lv.defaultSourceLimit,
+        ConcatenateEvents:  lv.concatenateEvents,
+        CollapseDuplicates: lv.collapseDuplicates,
+        MaskDuplicates:     lv.maskDuplicates,
+        Filters:            filterPatterns(lv.filters),
+        ContextBefore:      lv.filterContext.Before,
+        ContextAfter:       lv.filterContext.After,
+        ContextWindow:      lv.filterContext.Window,
+        HiddenLevels:       hiddenLevelNames(lv.hiddenLevels),
+        HiddenSources:      hiddenSourceNames(lv.hiddenSources),
+        Highlighting:       lv.highlightingEnabled,
+        HighlightRules:     highlightRulePatterns(lv.highlightRules),
+        HighlightLevels:    lv.highlightLevels,
+        HighlightCurrent:   lv.highlightCurrent,
+        WarningBgColor:     int32(lv.warningBgColor),
+        ErrorBgColor:       int32(lv.errorBgColor),
+        CurrentBgColor:     int32(lv.currentBgColor),
+        ShowSource:         lv.showSource,
+        SourceClipLength:   lv.sourceClipLength,
+        ShowTimestamp:      lv.showTimestamp,
+        TimestampFormat:    lv.timestampFormat,
+        Wrap:               lv.wrap,
+    }
+    if lv.newEventMatcher != nil {
+        s.NewEventMatcher = lv.newEventMatcher.String()
+    }
+    if lv.highlightPattern != nil {
+        s.HighlightPattern = lv.highlightPattern.String()
+    }
+    if lv.templateFilter != 0 && lv.templateMiner != nil {
+        if template, ok := lv.templateMiner.GetTemplate(lv.templateFilter); ok {
+            s.TemplateFilter = template.Pattern
+        }
+    }
+
+    if lv.file != nil {
+        s.File = &sessionFile{Path: lv.file.path, Current: -1, Top: -1}
+        if path, err := filepath.Abs(lv.file.path); err == nil {
+            s.File.Path = path
+        }
+        for index, mark := range lv.storeMarks {
+            s.File.Marks = append(s.File.Marks, sessionMark{Index: index, Bookmarked: mark.bookmarked, Marks: mark.marks})
+        }
+    } else if lv.spill != nil {
+        err := lv.spill.scanRecords(0, lv.spill.count(), func(index int, record eventRecord) bool {
+            mark := lv.storeMarks[index]
+            s.Events = append(s.Events, sessionEvent{eventRecord: record, Bookmarked: mark.bookmarked, Marks: mark.marks})
+            return true
+        })
+        if err != nil {
+            return fmt.Errorf("failed to read spilled events: %v", err)
+        }
+    }
+    index, fileIndex := len(s.Events)-1, -1
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.order <= 1 {
+            index, fileIndex = -1, -1
+            switch {
+            case event.fromSpill && s.File != nil:
+                fileIndex = event.spillIndex
+                if event.bookmarked || event.marks != "" {
+                    s.File.Marks = append(s.File.Marks, sessionMark{Index: fileIndex, Bookmarked: event.bookmarked, Marks: event.marks})
+                }
+            case event.fromSpill:
+                index = event.spillIndex
+            default:
+                index = len(s.Events)
+                record := newEventRecord(event.AsLogEvent())
+                record.Repeats = event.repeatCount
+                s.Events = append(s.Events, sessionEvent{eventRecord: record})
+            }
+            if index >= 0 && index < len(s.Events) {
+                s.Events[index].Bookmarked = event.bookmarked
+                s.Events[index].Marks = event.marks
+            }
+        }
+        topLine := 0
+        if event.order > 1 {
+            topLine = event.order - 1
+        }
+        if event == lv.current {
+            s.Current = index
+            if s.File != nil {
+                s.File.Current = fileIndex
+            }
+        }
+        if event == lv.top {
+            s.Top = index
+            s.TopLine = topLine
+            if s.File != nil {
+                s.File.Top = fileIndex
+                s.File.TopLine = topLine
+            }
+        }
+    }
+    if s.File != nil {
+        if lv.filePosition != nil {
+            // the position restored from a session has not been scrolled to yet
+            s.File.Current, s.File.Top, s.File.TopLine = lv.filePosition.Current, lv.filePosition.Top, lv.filePosition.TopLine
+        }
+        sort.Slice(s.File.Marks, func(i, j int) bool { return s.File.Marks[i].Index < s.File.Marks[j].Index })
+    }
+
+    gz := gzip.NewWriter(w)
+    if err := json.NewEncoder(gz).Encode(s); err != nil {
+        return fmt.Errorf("failed to write session: %v", err)
+    }
+    return gz.Close()
+}
+
+// LoadSession replaces all events and settings of the log view with the session written by SaveSession.
+// The file the session was saved with open is opened again, with lines as event messages, see LoadSessionWithParser.
+func (lv *LogView) LoadSession(r io.Reader) error {
+    return lv.LoadSessionWithParser(r, nil)
+}
+
+// LoadSessionWithParser is LoadSession that converts lines of the file the session was saved with open to events
+// with the parser, see OpenFile. Current and top lines of the file are scrolled to once the file is indexed up to them.
+func (lv *LogView) LoadSessionWithParser(r io.Reader, parser LineParser) error {
+    gz, err := gzip.NewReader(r)
+    if err != nil {
+        return fmt.Errorf("invalid session file: %v", err)
+    }
+    var s session
+    if err = json.NewDecoder(gz).Decode(&s); err != nil {
+        return fmt.Errorf("invalid session file: %v", err)
+    }
+    if s.Version > sessionVersion {
+        return fmt.Errorf("unsupported session version %d", s.Version)
+    }
+    var newEventMatcher *regexp.Regexp
+    if s.NewEventMatcher != "" {
+        if newEventMatcher, err = regexp.Compile(s.NewEventMatcher); err != nil {
+            return fmt.Errorf("invalid new event matcher in session: %v", err)
+        }
+    }
+    var highlightPattern *regexp2.Regexp
+    if s.HighlightPattern != "" {
+        if highlightPattern, err = regexp2.Compile(s.HighlightPattern, regexp2.IgnoreCase+regexp2.RE2); err != nil {
+            return fmt.Errorf("invalid highlight pattern in session: %v", err)
+        }
+    }
+    filters, err := parseFilterPatterns(s.Filters)
+    if err != nil {
+        return fmt.Errorf("%v in session", err)
+    }
+    rules, err := parseHighlightRulePatterns(s.HighlightRules)
+    if err != nil {
+        return fmt.Errorf("%v in session", err)
+    }
+    hiddenLevels, err := parseHiddenLevels(s.HiddenLevels)
+    if err != nil {
+        return fmt.Errorf("%v in session", err)
+    }
+    var file *fileSource
+    if s.File != nil {
+        if file, err = newFileSource(s.File.Path, parser, lv.fireOnFileProgress); err != nil {
+            return fmt.Errorf("failed to open the file of the session: %v", err)
+        }
+    }
+
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.closeFile()
+    lv.fileErr = nil
+    lv.clear()
+    lv.file = file
+    if file != nil {
+        for _, mark := range s.File.Marks {
+            lv.storeMarks[mark.Index] = storeMark{bookmarked: mark.Bookmarked, marks: mark.Marks}
+        }
+        lv.updateMarkedEvents(len(lv.storeMarks))
+    }
+    lv.eventLimit = 0
+    lv.sourceLimits = make(map[string]uint)
+    lv.defaultSourceLimit = 0
+    lv.concatenateEvents = s.ConcatenateEvents
+    lv.newEventMatcher = newEventMatcher
+    lv.collapseDuplicates = s.CollapseDuplicates
+    lv.maskDuplicates = s.MaskDuplicates
+    lv.filters = filters
+    lv.filterContext = FilterContext{Before: s.ContextBefore, After: s.ContextAfter, Window: s.ContextWindow}
+    lv.hiddenLevels = hiddenLevels
+    lv.hiddenSources = hiddenSourceSet(s.HiddenSources)
+    if s.TemplateFilter != "" && lv.templateMiner == nil {
+        // templates of the restored events are mined to restore the template filter
+        lv.templateMiner = NewTemplateMiner()
+    }
+    lv.highlightingEnabled = s.Highlighting
+    lv.highlightPattern = highlightPattern
+    lv.highlightRules = rules
+    lv.highlightLevels = s.HighlightLevels
+    lv.highlightCurrent = s.HighlightCurrent
+    lv.warningBgColor = tcell.Color(s.WarningBgColor)
+    lv.errorBgColor = tcell.Color(s.ErrorBgColor)
+    lv.currentBgColor = tcell.Color(s.CurrentBgColor)
+    lv.showSource = s.ShowSource
+    lv.sourceClipLength = s.SourceClipLength
+    lv.showTimestamp = s.ShowTimestamp
+    lv.timestampFormat = s.TimestampFormat
+    lv.wrap = s.Wrap
+    lv.forceWrap = true
+    lv.following = false
+
+    // events are restored one to one, without concatenation, collapsing and flood protection,
+    // limits are applied once positions are restored
+    concatenate := lv.concatenateEvents
+    collapse := lv.collapseDuplicates
+    retention := lv.retention
+    lv.concatenateEvents = false
+    lv.collapseDuplicates = false
+    lv.retention = RetentionPolicy{}
+    var current, top *logEventLine
+    // restored events are not reported to the appended and evicted listeners, neither are the dropped ones
+    onAppended, onEvicted := lv.onAppended, lv.onEvicted
+    lv.onAppended, lv.onEvicted = nil, nil
+    lv.evicted = nil
+    for i, record := range s.Events {
+        lv.append(record.logEvent())
+        event := findFirstWrappedLine(lv.lastEvent)
+        event.repeatCount = record.Repeats
+        if record.Bookmarked || record.Marks != "" {
+            lv.updateMarks(event, record.Bookmarked, record.Marks)
+        }
+        if i == s.Current {
+            current = event
+        }
+        if i == s.Top {
+            top = lv.atOffset(event, minInt(s.TopLine, int(event.lineCount)-1))
+        }
+    }
+    if s.TemplateFilter != "" {
+        lv.templateFilter = lv.templateMiner.Match(s.TemplateFilter)
+    }
+    lv.concatenateEvents = concatenate
+    lv.collapseDuplicates = collapse
+    lv.retention = retention
+
+    lv.following = s.Following
+    if lv.following {
+        lv.scrollToEnd()
+    } else {
+        if current != nil {
+            lv.current = current
+        }
+        if top != nil {
+            lv.top = top
+        }
+        if file != nil && (s.File.Current >= 0 || s.File.Top >= 0) {
+            lv.filePosition = s.File
+        }
+    }
+
+    lv.eventLimit = s.EventLimit
+    for source, limit := range s.SourceLimits {
+        lv.sourceLimits[source] = limit
+    }
+    lv.defaultSourceLimit = s.DefaultSourceLimit
+    lv.ensureEventLimit()
+    lv.onAppended, lv.onEvicted = onAppended, onEvicted
+    if lv.current == nil {
+        lv.current = lv.firstEvent
+    }
+    if lv.top == nil {
+        lv.top = lv.firstEvent
+    }
+    return nil
+}
+
+// *******************************
+// internal implementation details
+
+// restoreFilePosition scrolls to the position in the open file restored by LoadSession, once the file is indexed up
+// to it
+func (lv *LogView) restoreFilePosition() {
+    position := lv.filePosition
+    progress := lv.file.progress()
+    if lv.file.count() <= maxInt(position.Current, position.Top) && !progress.IsComplete() && progress.Err == nil {
+        return
+    }
+    lv.filePosition = nil
+    if position.Top >= 0 {
+        if top := lv.eventAt(position.Top); top != nil {
+            lv.top = lv.atOffset(top, minInt(position.TopLine, int(top.lineCount)-1))
+        }
+    }
+    if position.Current >= 0 {
+        if current := lv.eventAt(position.Current); current != nil {
+            lv.current = current
+        }
+    }
+    lv.fillSpillGaps(lv.top, lv.pageHeight)
+}
@@ -0,0 +1,224 @@
+package clogviewr
+
+import (
+    "bytes"
+    "github.com/gdamore/tcell/v2"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestLogView_SaveLoadSession(t *testing.T) {
+    lv := NewLogView()
+    lv.SetHighlightCurrentEvent(true)
+    lv.SetShowSource(true)
+    lv.SetTimestampFormat("15:04:05")
+    lv.SetHighlightPattern(`(?P<red>Event)`)
+    events := randomEvents(100, time.Now().Add(-24*time.Hour))
+    events[10].Data = map[string]interface{}{"user": "alex"}
+    lv.AppendEvents(events)
+    lv.ScrollToEventID("e42")
+    lv.SetFollowing(false)
+
+    var buf bytes.Buffer
+    if err := lv.SaveSession(&buf); err != nil {
+        t.Fatalf("Failed to save session: %v", err)
+    }
+
+    restored := NewLogView()
+    if err := restored.LoadSession(&buf); err != nil {
+        t.Fatalf("Failed to load session: %v", err)
+    }
+
+    if restored.EventCount() != 100 || restored.GetCurrentEvent().EventID != "e42" || restored.IsFollowing() {
+        t.Errorf("Invalid restored events, count=%d, current=%s", restored.EventCount(), restored.GetCurrentEvent().EventID)
+    }
+    if restored.top.EventID != lv.top.EventID {
+        t.Errorf("Invalid restored top event %s, expected %s", restored.top.EventID, lv.top.EventID)
+    }
+    if !restored.IsShowSource() || restored.GetTimestampFormat() != "15:04:05" || !restored.IsHighlightCurrentEventEnabled() {
+        t.Errorf("Display settings are not restored")
+    }
+    if restored.highlightPattern == nil || restored.highlightPattern.String() != `(?P<red>Event)` {
+        t.Errorf("Highlight pattern is not restored")
+    }
+    data, ok := restored.findByEventId("e10").Data.(map[string]interface{})
+    if !ok || data["user"] != "alex" {
+        t.Errorf("Event data is not restored: %v", data)
+    }
+}
+
+func TestLogView_SessionListeners(t *testing.T) {
+    lv := NewLogView()
+    if err := lv.SetSpillFile(filepath.Join(t.TempDir(), "spill.log")); err != nil {
+        t.Fatalf("Failed to set spill file: %v", err)
+    }
+    defer lv.SetSpillFile("")
+    lv.AppendEvents(randomEvents(20, time.Now().Add(-24*time.Hour)))
+    var unlimited, limited bytes.Buffer
+    if err := lv.SaveSession(&unlimited); err != nil {
+        t.Fatalf("Failed to save session: %v", err)
+    }
+    // the session keeps the spilled events, the restored view has no spill file and drops them again
+    lv.SetMaxEvents(10)
+    lv.AppendEvent(NewLogEvent("late", "Late event"))
+    if err := lv.SaveSession(&limited); err != nil {
+        t.Fatalf("Failed to save session: %v", err)
+    }
+
+    restored := NewLogView()
+    restored.AppendEvents(randomEvents(5, time.Now().Add(-time.Hour)))
+    appended, evicted := 0, 0
+    restored.SetOnAppended(func(events []*LogEvent) {
+        appended += len(events)
+    })
+    restored.SetOnEvicted(func(events []*LogEvent) {
+        evicted += len(events)
+    })
+    if err := restored.LoadSession(&unlimited); err != nil {
+        t.Fatalf("Failed to load session: %v", err)
+    }
+    if restored.EventCount() != 20 || appended != 0 || evicted != 0 {
+        t.Errorf("Expected 20 restored events without notifications, got %d events, %d appended, %d evicted",
+            restored.EventCount(), appended, evicted)
+    }
+    if err := restored.LoadSession(&limited); err != nil {
+        t.Fatalf("Failed to load session: %v", err)
+    }
+    if restored.EventCount() != 10 || appended != 0 || evicted != 0 {
+        t.Errorf("Expected 10 restored events without notifications, got %d events, %d appended, %d evicted",
+            restored.EventCount(), appended, evicted)
+    }
+    restored.AppendEvent(NewLogEvent("next", "Next event"))
+    if appended != 1 || evicted != 1 {
+        t.Errorf("Expected appended and evicted events to be reported after loading, got %d appended, %d evicted",
+            appended, evicted)
+    }
+}
+
+func TestLogView_SessionFilters(t *testing.T) {
+    lv := NewLogView()
+    events := randomEvents(40, time.Now().Add(-24*time.Hour))
+    for i, event := range events {
+        if i%4 == 0 {
+            event.Source = "db"
+        }
+        if i%5 == 0 {
+            event.Level = LogLevelWarning
+        }
+        if i >= 20 {
+            event.Message = "request " + event.EventID + " done"
+        }
+    }
+    lv.AppendEvents(events)
+    predicate, _ := ParsePattern(`\q id:e3*`)
+    lv.PushFilter(EventFilter{Name: `\q id:e3*`, Pattern: `\q id:e3*`, Predicate: predicate})
+    lv.SetFilterContext(FilterContext{Before: 1})
+    lv.SetLevelVisible(LogLevelWarning, false)
+    lv.SetSourceVisible("db", false)
+    predicate, _ = ParsePattern("#13")
+    lv.AddHighlightRule(HighlightRule{Name: "#13", Pattern: "#13", Predicate: predicate, Color: tcell.ColorRed})
+    miner := NewTemplateMiner()
+    lv.SetTemplateMiner(miner)
+    for _, template := range miner.Templates() {
+        if strings.HasPrefix(template.Pattern, "request") {
+            lv.SetTemplateFilter(template.ID)
+        }
+    }
+    if lv.GetTemplateFilter() == 0 {
+        t.Fatalf("Expected a template of the request events")
+    }
+    lv.SetBookmark("e31", true)
+    lv.SetFollowing(false)
+    lv.ScrollToEventID("e33")
+    _ = lv.SetMark('a')
+
+    var buf bytes.Buffer
+    if err := lv.SaveSession(&buf); err != nil {
+        t.Fatalf("Failed to save session: %v", err)
+    }
+    restored := NewLogView()
+    if err := restored.LoadSession(&buf); err != nil {
+        t.Fatalf("Failed to load session: %v", err)
+    }
+
+    if !reflect.DeepEqual(restored.CaptureView("session"), lv.CaptureView("session")) {
+        t.Errorf("Expected filters, context, hidden levels and sources and highlight rules to be restored, got %+v", restored.CaptureView("session"))
+    }
+    visible := func(lv *LogView) []string {
+        ids := make([]string, 0)
+        for event := lv.firstEvent; event != nil; event = event.next {
+            if event.order <= 1 && lv.isVisible(event) {
+                ids = append(ids, event.EventID)
+            }
+        }
+        return ids
+    }
+    if expected, ids := visible(lv), visible(restored); len(ids) == 0 || !reflect.DeepEqual(ids, expected) {
+        t.Errorf("Expected displayed events %v, got %v", expected, ids)
+    }
+    if restored.GetTemplateMiner() == nil || restored.GetTemplateFilter() == 0 {
+        t.Errorf("Expected the template filter to be restored")
+    }
+    if !restored.IsBookmarked("e31") || restored.GetMarks()['a'].EventID != "e33" || restored.GetCurrentEvent().EventID != "e33" {
+        t.Errorf("Expected bookmarks, marks and the current event to be restored")
+    }
+}
+
+func TestLogView_SessionOpenFile(t *testing.T) {
+    lv, _ := openTestFile(t, 3000)
+    defer lv.CloseFile()
+    lv.AppendEvent(NewLogEvent("live", "Live event"))
+    lv.ScrollToEventID("1500")
+    lv.ToggleBookmark()
+    lv.ScrollToEventID("2000")
+    _ = lv.SetMark('a')
+    lv.ScrollToTop()
+    lv.ScrollToEventID("2900")
+
+    var buf bytes.Buffer
+    if err := lv.SaveSession(&buf); err != nil {
+        t.Fatalf("Failed to save session: %v", err)
+    }
+    restored := NewLogView()
+    restored.SetMaxEvents(1200)
+    if err := restored.LoadSessionWithParser(&buf, TimestampLineParser("2006-01-02 15:04:05")); err != nil {
+        t.Fatalf("Failed to load session: %v", err)
+    }
+    defer restored.CloseFile()
+    for !restored.GetFileProgress().IsComplete() {
+        time.Sleep(time.Millisecond)
+    }
+    screen := tcell.NewSimulationScreen("UTF-8")
+    screen.SetSize(100, 10)
+    restored.Draw(screen)
+
+    if !restored.IsFileOpen() || restored.GetTotalEventCount() != 3001 || restored.findByEventId("live") == nil {
+        t.Errorf("Expected the file to be opened again with the live event, %d events", restored.GetTotalEventCount())
+    }
+    if current := restored.GetCurrentEvent(); current == nil || current.EventID != "2900" || current.Message != "Line #2900" {
+        t.Errorf("Expected line 2900 to be current, got %v", current)
+    }
+    if restored.top.EventID != lv.top.EventID {
+        t.Errorf("Expected top line %s, got %s", lv.top.EventID, restored.top.EventID)
+    }
+    bookmarks, marks := restored.GetBookmarks(), restored.GetMarks()
+    if len(bookmarks) != 1 || bookmarks[0].EventID != "1500" || marks['a'] == nil || marks['a'].EventID != "2000" {
+        t.Errorf("Expected the bookmark of line 1500 and the mark of line 2000 to be restored, got %v %v", bookmarks, marks)
+    }
+
+    buf.Reset()
+    plain := NewLogView()
+    plain.AppendEvents(randomEvents(10, time.Now()))
+    if err := plain.SaveSession(&buf); err != nil {
+        t.Fatalf("Failed to save session: %v", err)
+    }
+    if err := restored.LoadSession(&buf); err != nil {
+        t.Fatalf("Failed to load session: %v", err)
+    }
+    if restored.IsFileOpen() || restored.GetTotalEventCount() != 10 || len(restored.GetBookmarks()) != 0 {
+        t.Errorf("Expected the file to be closed by a session without it, %d events", restored.GetTotalEventCount())
+    }
+}
@@ -0,0 +1,478 @@
+package clogviewr
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "time"
+)
+
+// spillPageSize is the number of events paged back in from the spill store at once
+const spillPageSize = 500
+
+// eventRecord is a serialized form of a single event, used in spill and session files.
+// Event data is serialized as JSON, so it must be JSON friendly to survive the round trip.
+type eventRecord struct {
+    EventID   string      `json:"id"`
+    Source    string      `json:"src,omitempty"`
+    Timestamp time.Time   `json:"ts"`
+    Level     LogLevel    `json:"lvl,omitempty"`
+    Message   string      `json:"msg"`
+    Data      interface{} `json:"data,omitempty"`
+    // Repeats is the number of collapsed duplicate occurrences
+    Repeats uint `json:"rep,omitempty"`
+}
+
+func newEventRecord(event *LogEvent) eventRecord {
+    return eventRecord{
+        EventID:   event.EventID,
+        Source:    event.Source,
+        Timestamp: event.Timestamp,
+        Level:     event.Level,
+        Message:   event.Message,
+        Data:      event.Data,
+    }
+}
+
+func (r eventRecord) logEvent() *LogEvent {
+    return &LogEvent{
+        EventID:   r.EventID,
+        Source:    r.Source,
+        Timestamp: r.Timestamp,
+        Level:     r.Level,
+        Message:   r.Message,
+        Data:      r.Data,
+    }
+}
+
+// pagedStore holds events outside of the log view, they are paged in when needed
+type pagedStore interface {
+    count() int
+    // page returns records of events with indexes in range [from, to), with the repeat counts the store keeps
+    page(from, to int) ([]eventRecord, error)
+    // scan calls f for every event with index in range [from, to) until f returns false
+    scan(from, to int, f func(index int, event *LogEvent) bool) error
+    // indexOf returns the index of the first event with a given eventID or -1 if there is no such event
+    indexOf(eventID string) int
+    // indexOfTimestamp returns the index of the first event with a timestamp equal to or greater than given or -1
+    indexOfTimestamp(timestamp time.Time) int
+}
+
+// spillStore is a local append-only file that holds events evicted from the log view.
+// Events are stored as JSON lines, offsets of all the records are kept in memory so any event can be read back
+// by its index.
+type spillStore struct {
+    file    *os.File
+    writer  *bufio.Writer
+    offsets []int64
+    size    int64
+}
+
+func newSpillStore(path string) (*spillStore, error) {
+    file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+    if err != nil {
+        return nil, err
+    }
+    return &spillStore{
+        file:    file,
+        writer:  bufio.NewWriter(file),
+        offsets: make([]int64, 0),
+    }, nil
+}
+
+func (s *spillStore) count() int {
+    return len(s.offsets)
+}
+
+// append writes the event with the number of its collapsed duplicate occurrences
+func (s *spillStore) append(event *LogEvent, repeats uint) error {
+    record := newEventRecord(event)
+    record.Repeats = repeats
+    data, err := json.Marshal(record)
+    if err != nil {
+        return err
+    }
+    data = append(data, '\n')
+    if _, err = s.writer.Write(data); err != nil {
+        return err
+    }
+    s.offsets = append(s.offsets, s.size)
+    s.size += int64(len(data))
+    return nil
+}
+
+// read returns events with indexes in range [from, to)
+func (s *spillStore) read(from, to int) ([]*LogEvent, error) {
+    events := make([]*LogEvent, 0)
+    err := s.scan(from, to, func(_ int, event *LogEvent) bool {
+        events = append(events, event)
+        return true
+    })
+    return events, err
+}
+
+// page returns records of events with indexes in range [from, to)
+func (s *spillStore) page(from, to int) ([]eventRecord, error) {
+    records := make([]eventRecord, 0)
+    err := s.scanRecords(from, to, func(_ int, record eventRecord) bool {
+        records = append(records, record)
+        return true
+    })
+    return records, err
+}
+
+// scan calls f for every event with index in range [from, to) until f returns false
+func (s *spillStore) scan(from, to int, f func(index int, event *LogEvent) bool) error {
+    return s.scanRecords(from, to, func(index int, record eventRecord) bool {
+        return f(index, record.logEvent())
+    })
+}
+
+// scanRecords calls f for every record with index in range [from, to) until f returns false
+func (s *spillStore) scanRecords(from, to int, f func(index int, record eventRecord) bool) error {
+    if from < 0 {
+        from = 0
+    }
+    if to > len(s.offsets) {
+        to = len(s.offsets)
+    }
+    if from >= to {
+        return nil
+    }
+    if err := s.writer.Flush(); err != nil {
+        return err
+    }
+    reader := bufio.NewReader(io.NewSectionReader(s.file, s.offsets[from], s.size-s.offsets[from]))
+    for i := from; i < to; i++ {
+        line, err := reader.ReadBytes('\n')
+        if err != nil {
+            return fmt.Errorf("spill file is corrupted at event %d: %v", i, err)
+        }
+        var record eventRecord
+        if err = json.Unmarshal(line, &record); err != nil {
+            return fmt.Errorf("spill file is corrupted at event %d: %v", i, err)
+        }
+        if !f(i, record) {
+            break
+        }
+    }
+    return nil
+}
+
+// indexOf returns the index of the first event with a given eventID or -1 if there is no such event
+func (s *spillStore) indexOf(eventID string) int {
+    index := -1
+    _ = s.scan(0, s.count(), func(i int, event *LogEvent) bool {
+        if event.EventID == eventID {
+            index = i
+            return false
+        }
+        return true
+    })
+    return index
+}
+
+// indexOfTimestamp returns the index of the first event with a timestamp equal to or greater than given, or -1 if
+// there is no such event. Events are expected to be evicted in the order of their timestamps.
+func (s *spillStore) indexOfTimestamp(timestamp time.Time) int {
+    failed := false
+    index := sort.Search(s.count(), func(i int) bool {
+        events, err := s.read(i, i+1)
+        if err != nil || len(events) == 0 {
+            failed = true
+            return true
+        }
+        return !events[0].Timestamp.Before(timestamp)
+    })
+    if failed || index == s.count() {
+        return -1
+    }
+    return index
+}
+
+func (s *spillStore) reset() error {
+    s.writer.Reset(s.file)
+    s.offsets = s.offsets[:0]
+    s.size = 0
+    return s.file.Truncate(0)
+}
+
+func (s *spillStore) close() error {
+    if err := s.writer.Flush(); err != nil {
+        _ = s.file.Close()
+        return err
+    }
+    return s.file.Close()
+}
+
+// SetSpillFile enables spilling of events evicted by the event limit to a local append-only file.
+// Spilled events are paged back in when scrolling to the top, scrolling past the first event in memory,
+// scrolling to an event id and searching.
+//
+// While spilled history is being viewed, events at or below the top of the view are not evicted.
+// Existing file is truncated. Setting an empty path disables spilling and closes the file.
+func (lv *LogView) SetSpillFile(path string) error {
+    lv.Lock()
+    defer lv.Unlock()
+
+    if lv.spill != nil {
+        err := lv.spill.close()
+        lv.spill = nil
+        lv.dropStoreMarks()
+        if err != nil {
+            return err
+        }
+    }
+    lv.spillErr = nil
+    if path == "" {
+        return nil
+    }
+    spill, err := newSpillStore(path)
+    if err != nil {
+        return err
+    }
+    lv.spill = spill
+    return nil
+}
+
+// GetSpilledEventCount returns the number of events in the spill file
+func (lv *LogView) GetSpilledEventCount() int {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    if lv.spill == nil {
+        return 0
+    }
+    return lv.spill.count()
+}
+
+// GetSpillError returns the error that caused spilling to be disabled, if any
+func (lv *LogView) GetSpillError() error {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    return lv.spillErr
+}
+
+// *******************************
+// internal implementation details
+
+// store returns the store events are paged in from: the open file or the spill store, nil if there is none
+func (lv *LogView) store() pagedStore {
+    if lv.file != nil {
+        return lv.file
+    }
+    if lv.spill != nil {
+        return lv.spill
+    }
+    return nil
+}
+
+// storeFailed closes the store events are paged in from after an I/O error
+func (lv *LogView) storeFailed(err error) {
+    if lv.file != nil {
+        lv.fileErr = err
+        lv.closeFile()
+        return
+    }
+    lv.spillFailed(err)
+}
+
+// spillFailed disables spilling after an I/O error
+func (lv *LogView) spillFailed(err error) {
+    lv.spillErr = err
+    _ = lv.spill.close()
+    lv.spill = nil
+    lv.dropStoreMarks()
+}
+
+// spillIndexAfter returns the spill index of the event that must follow the paged in event in the log view
+func (lv *LogView) spillIndexAfter(event *logEventLine) int {
+    if event == nil {
+        return 0
+    }
+    return event.spillIndex + 1
+}
+
+// nextSpillIndex returns the spill index of a paged in event. For events that have never been spilled
+// it returns the index the first of them would get once spilled.
+func (lv *LogView) nextSpillIndex(event *logEventLine) int {
+    if event == nil || !event.fromSpill {
+        return lv.store().count()
+    }
+    return event.spillIndex
+}
+
+// pageIn loads spilled events with indexes in range [from, to) that are not in memory yet and inserts them
+// into the log view in order
+func (lv *LogView) pageIn(from, to int) {
+    store := lv.store()
+    if store == nil {
+        return
+    }
+    if from < 0 {
+        from = 0
+    }
+    if lv.file != nil && !lv.adjoinsWindow(from, to) {
+        // lines of the file in memory are kept contiguous
+        lv.dropWindow()
+    }
+    records, err := store.page(from, to)
+    if err != nil {
+        lv.storeFailed(err)
+        return
+    }
+
+    // paged in events are always at the beginning of the log, before the events that have never been spilled
+    var previous *logEventLine
+    next := lv.firstEvent
+    for i, record := range records {
+        index := from + i
+        for next != nil && next.fromSpill && next.spillIndex < index {
+            previous = next
+            next = next.next
+        }
+        for next != nil && next.order > 1 {
+            previous = next
+            next = next.next
+        }
+        if next != nil && next.fromSpill && next.spillIndex == index {
+            continue
+        }
+        logEvent := record.logEvent()
+        event := lv.newEventLine(logEvent)
+        event.fromSpill = true
+        event.spillIndex = index
+        event.repeatCount = record.Repeats
+        if mark, ok := lv.storeMarks[index]; ok {
+            // marks are counted while they are kept for the store
+            event.bookmarked, event.marks = mark.bookmarked, mark.marks
+            delete(lv.storeMarks, index)
+        }
+        if lv.templateMiner != nil {
+            event.templateID = lv.templateMiner.Match(logEvent.Message)
+        }
+        if previous == nil {
+            lv.insertFirst(event)
+        } else {
+            lv.insertAfter(previous, event, true)
+        }
+        lv.filterEvent(event)
+        lv.colorize(event)
+        previous = lv.calculateWrap(event)
+    }
+    if lv.file != nil {
+        lv.trimWindow(from, to)
+    }
+}
+
+// pageInBefore loads a page of spilled events preceding the event
+func (lv *LogView) pageInBefore(event *logEventLine) {
+    if lv.store() == nil || event == nil {
+        return
+    }
+    event = findFirstWrappedLine(event)
+    to := lv.nextSpillIndex(event)
+    lv.pageIn(to-spillPageSize, to)
+}
+
+// fillSpillGaps walks given number of lines from the start event and pages in spilled events that
+// are missing between paged in events and the rest of the log
+func (lv *LogView) fillSpillGaps(start *logEventLine, lines int) {
+    if lv.store() == nil {
+        return
+    }
+    event := start
+    for event != nil && lines > 0 && event.fromSpill {
+        if event.next == nil || event.next.order <= 1 {
+            expected := lv.spillIndexAfter(event)
+            actual := lv.nextSpillIndex(event.next)
+            if actual > expected {
+                lv.pageIn(expected, minInt(actual, expected+spillPageSize))
+            }
+        }
+        event = event.next
+        lines--
+    }
+}
+
+// findSpilled searches for an event matching the predicate in the spill file. If lastEventId is not an empty
+// string, search starts after the event with that id. The search ends without a match once stop is closed.
+// If lastEventId is neither empty nor in the spill file, it returns false to indicate the spill file was not searched.
+func (lv *LogView) findSpilled(lastEventId string, predicate func(event *LogEvent) bool, stop <-chan struct{}) (*LogEvent, bool) {
+    start := 0
+    if lastEventId != "" {
+        start = lv.spillIndexOf(lastEventId) + 1
+        if start == 0 {
+            return nil, false
+        }
+    }
+    var match *LogEvent
+    err := lv.spill.scan(start, lv.spill.count(), func(_ int, event *LogEvent) bool {
+        if isStopped(stop) {
+            return false
+        }
+        if predicate(event) {
+            match = event
+            return false
+        }
+        return true
+    })
+    if err != nil {
+        lv.spillFailed(err)
+        return nil, false
+    }
+    return match, true
+}
+
+// spillIndexOf returns the index of the event with the id in the spill file, or -1. Events in memory are checked
+// first, so that the file is not scanned for an event that has never been spilled or is paged in.
+func (lv *LogView) spillIndexOf(eventID string) int {
+    for event := lv.firstEvent; event != nil; event = event.next {
+        if event.EventID == eventID {
+            if event.fromSpill {
+                return findFirstWrappedLine(event).spillIndex
+            }
+            return -1
+        }
+    }
+    return lv.spill.indexOf(eventID)
+}
+
+// findPreviousInStore searches the store backwards, page by page, for the last event matching the predicate with
+// index lower than to, until stop is closed
+func findPreviousInStore(store pagedStore, to int, predicate func(event *LogEvent) bool, stop <-chan struct{}) (*LogEvent, error) {
+    for to > 0 && !isStopped(stop) {
+        from := maxInt(0, to-spillPageSize)
+        var match *LogEvent
+        err := store.scan(from, to, func(_ int, event *LogEvent) bool {
+            if predicate(event) {
+                match = event
+            }
+            return true
+        })
+        if err != nil || match != nil {
+            return match, err
+        }
+        to = from
+    }
+    return nil, nil
+}
+
+// countSpilled counts spilled events matching the predicate until stop is closed
+func (lv *LogView) countSpilled(predicate func(event *LogEvent) bool, stop <-chan struct{}) int {
+    matches := 0
+    err := lv.spill.scan(0, lv.spill.count(), func(_ int, event *LogEvent) bool {
+        if predicate(event) {
+            matches++
+        }
+        return !isStopped(stop)
+    })
+    if err != nil {
+        lv.spillFailed(err)
+    }
+    return matches
+}
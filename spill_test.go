@@ -0,0 +1,135 @@
+package clogviewr
+
+import (
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+func newSpillingLogView(t *testing.T, count int) *LogView {
+    lv := NewLogView()
+    lv.SetHighlightCurrentEvent(true)
+    if err := lv.SetSpillFile(filepath.Join(t.TempDir(), "spill.log")); err != nil {
+        t.Fatalf("Failed to create spill file: %v", err)
+    }
+    lv.SetMaxEvents(10)
+    lv.AppendEvents(randomEvents(count, time.Now().Add(-24*time.Hour)))
+    return lv
+}
+
+func TestLogView_SpillScrollToTop(t *testing.T) {
+    lv := newSpillingLogView(t, 1200)
+    defer lv.SetSpillFile("")
+
+    if lv.EventCount() != 10 || lv.GetSpilledEventCount() != 1190 {
+        t.Fatalf("Expected 10 events in memory and 1190 spilled, got %d and %d", lv.EventCount(), lv.GetSpilledEventCount())
+    }
+
+    lv.ScrollToTop()
+    for i := 0; i < 1200; i++ {
+        id := "e" + strconv.Itoa(i)
+        if lv.GetCurrentEvent().EventID != id {
+            t.Fatalf("Expected current event %s, got %s", id, lv.GetCurrentEvent().EventID)
+        }
+        lv.SelectNextEvent()
+    }
+    if lv.GetSpilledEventCount() != 1190 {
+        t.Errorf("Paging in must not spill events again, spilled %d", lv.GetSpilledEventCount())
+    }
+}
+
+func TestLogView_SpillScrollUp(t *testing.T) {
+    lv := newSpillingLogView(t, 100)
+    defer lv.SetSpillFile("")
+
+    lv.ScrollToEventID("e90")
+    lv.SelectPrevEvent()
+    if lv.GetCurrentEvent().EventID != "e89" {
+        t.Errorf("Scrolling up past the first event must page in spilled events, current %s", lv.GetCurrentEvent().EventID)
+    }
+}
+
+func TestLogView_SpillSearch(t *testing.T) {
+    lv := newSpillingLogView(t, 100)
+    defer lv.SetSpillFile("")
+
+    event := lv.FindMatchingEvent("", func(event *LogEvent) bool {
+        return event.Message == "Event #42"
+    })
+    if event == nil || event.EventID != "e42" {
+        t.Fatalf("Failed to find spilled event, got %v", event)
+    }
+    event = lv.FindMatchingEvent("e42", func(event *LogEvent) bool {
+        return event.Message == "Event #95"
+    })
+    if event == nil || event.EventID != "e95" {
+        t.Fatalf("Failed to continue search from spilled event to events in memory, got %v", event)
+    }
+    if hits := lv.FindTotalMatches(func(event *LogEvent) bool { return true }); hits != 100 {
+        t.Errorf("Expected 100 matches, got %d", hits)
+    }
+
+    if !lv.ScrollToEventID("e95") || !lv.IsFollowing() {
+        t.Errorf("Scrolling to an event in memory must not stop following")
+    }
+    if lv.spillIndexOf("e95") != -1 {
+        t.Errorf("Events that have never been spilled must not be looked up in the spill file")
+    }
+    if !lv.ScrollToEventID("e42") || lv.GetCurrentEvent().EventID != "e42" || lv.IsFollowing() {
+        t.Errorf("Failed to scroll to spilled event")
+    }
+    if index := lv.spillIndexOf("e42"); index != 42 {
+        t.Errorf("Expected paged in event e42 at 42, got %d", index)
+    }
+}
+
+func TestLogView_SpillSearchBackward(t *testing.T) {
+    lv := newSpillingLogView(t, 1200)
+    defer lv.SetSpillFile("")
+
+    endsWith5 := func(event *LogEvent) bool {
+        return strings.HasSuffix(event.Message, "5")
+    }
+    expected := []string{"e1195", "e1185", "e1175"}
+    lastEventId := ""
+    for _, id := range expected {
+        event := lv.FindPreviousMatchingEvent(lastEventId, endsWith5)
+        if event == nil || event.EventID != id {
+            t.Fatalf("Expected %s, got %v", id, event)
+        }
+        lastEventId = event.EventID
+    }
+    event := lv.FindPreviousMatchingEvent("e1190", func(event *LogEvent) bool {
+        return event.Message == "Event #3"
+    })
+    if event == nil || event.EventID != "e3" {
+        t.Fatalf("Failed to continue search from events in memory to spilled events, got %v", event)
+    }
+    if event = lv.FindPreviousMatchingEvent("e5", endsWith5); event != nil {
+        t.Errorf("Expected no match before e5, got %v", event)
+    }
+}
+
+func TestLogView_SpillRepeats(t *testing.T) {
+    lv := NewLogView()
+    if err := lv.SetSpillFile(filepath.Join(t.TempDir(), "spill.log")); err != nil {
+        t.Fatalf("Failed to create spill file: %v", err)
+    }
+    defer lv.SetSpillFile("")
+    lv.SetMaxEvents(10)
+    lv.SetCollapseDuplicates(true)
+    for i := 0; i < 3; i++ {
+        lv.AppendEvent(NewLogEvent("retry", "Retrying"))
+    }
+    lv.AppendEvents(randomEvents(20, time.Now()))
+
+    if lv.GetSpilledEventCount() != 11 {
+        t.Fatalf("Expected 11 spilled events, got %d", lv.GetSpilledEventCount())
+    }
+    lv.ScrollToTop()
+    if event := lv.firstEvent; event.EventID != "retry" || !event.fromSpill || event.repeatCount != 3 {
+        t.Errorf("Expected collapsed event paged in with its repeat count, got %s ×%d", event.EventID, event.repeatCount)
+    }
+}
@@ -0,0 +1,347 @@
+package clogviewr
+
+import (
+    gui "code.rocketnine.space/tslocum/cview"
+    "fmt"
+    "github.com/gdamore/tcell/v2"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// StatsCount is the number of events with a key, with the level mix and first and last seen timestamps
+type StatsCount struct {
+    Key          string
+    Count        int
+    InfoCount    int
+    WarningCount int
+    ErrorCount   int
+    FirstSeen    time.Time
+    LastSeen     time.Time
+}
+
+// Stats are the numbers of events aggregated by source, level or a path in the event data, ordered by count
+type Stats struct {
+    // By is the name the events are aggregated by: source, level or a path in the event data, i.e. http.status
+    By     string
+    Counts []StatsCount
+    // Total is the number of aggregated events, Missing is the number of events without the data path
+    Total   int
+    Missing int
+}
+
+// StatsSortColumn is a column stats are sorted by
+type StatsSortColumn int
+
+const (
+    StatsByCount StatsSortColumn = iota
+    StatsByKey
+    StatsByWarnings
+    StatsByErrors
+    StatsByLastSeen
+)
+
+// Aggregate counts the displayed events matching the predicate by source, level or a path in the event data.
+// All displayed events are counted if predicate is nil.
+func (lv *LogView) Aggregate(by string, predicate func(event *LogEvent) bool) (*Stats, error) {
+    key, err := statsKey(by)
+    if err != nil {
+        return nil, err
+    }
+    if predicate == nil {
+        predicate = func(_ *LogEvent) bool { return true }
+    }
+    stats := &Stats{By: by}
+    counts := make(map[string]*StatsCount)
+    lv.forEachMatch(predicate, func(event *LogEvent) {
+        stats.Total++
+        k, ok := key(event)
+        if !ok {
+            stats.Missing++
+            return
+        }
+        count := counts[k]
+        if count == nil {
+            count = &StatsCount{Key: k, FirstSeen: event.Timestamp}
+            counts[k] = count
+        }
+        count.Count++
+        switch event.Level {
+        case LogLevelWarning:
+            count.WarningCount++
+        case LogLevelError:
+            count.ErrorCount++
+        default:
+            count.InfoCount++
+        }
+        count.LastSeen = event.Timestamp
+    })
+    stats.Counts = make([]StatsCount, 0, len(counts))
+    for _, count := range counts {
+        stats.Counts = append(stats.Counts, *count)
+    }
+    stats.Sort(StatsByCount, true)
+    return stats, nil
+}
+
+// Sort sorts the counts by the column, ties are ordered by key
+func (s *Stats) Sort(column StatsSortColumn, descending bool) {
+    sort.SliceStable(s.Counts, func(i, j int) bool {
+        a, b := s.Counts[i], s.Counts[j]
+        c := 0
+        switch column {
+        case StatsByCount:
+            c = a.Count - b.Count
+        case StatsByWarnings:
+            c = a.WarningCount - b.WarningCount
+        case StatsByErrors:
+            c = a.ErrorCount - b.ErrorCount
+        case StatsByLastSeen:
+            if a.LastSeen.Before(b.LastSeen) {
+                c = -1
+            } else if a.LastSeen.After(b.LastSeen) {
+                c = 1
+            }
+        }
+        if c == 0 {
+            c = strings.Compare(a.Key, b.Key)
+            if column != StatsByKey {
+                // ties are always in key order
+                return c < 0
+            }
+        }
+        if descending {
+            return c > 0
+        }
+        return c < 0
+    })
+}
+
+// FilterPattern returns a query pattern, see ParsePattern, matching the events counted with the key
+func (s *Stats) FilterPattern(key string) string {
+    switch s.By {
+    case "source", "src":
+        return `\q source=` + quoteQueryValue(key)
+    case "level", "lvl":
+        return `\q level=` + key
+    default:
+        return `\q ` + s.By + `=` + quoteQueryValue(key)
+    }
+}
+
+// OnStatsSelected is a listener called when a count is selected in StatsView
+type OnStatsSelected func(stats *Stats, count StatsCount)
+
+// StatsView is a table of stats computed by LogView.Aggregate. Counts are sorted by a column with c (count), k (key),
+// w (warnings), e (errors) and l (last seen), choosing the same column again reverses the order.
+type StatsView struct {
+    *gui.Table
+
+    stats      *Stats
+    sortColumn StatsSortColumn
+    descending bool
+
+    timestampFormat string
+    errorColor      tcell.Color
+    warningColor    tcell.Color
+
+    onSelected OnStatsSelected
+
+    sync.Mutex
+}
+
+// statsSortKeys are the keys sorting StatsView by a column
+var statsSortKeys = map[rune]StatsSortColumn{
+    'c': StatsByCount,
+    'k': StatsByKey,
+    'w': StatsByWarnings,
+    'e': StatsByErrors,
+    'l': StatsByLastSeen,
+}
+
+// NewStatsView creates a new empty stats view
+func NewStatsView() *StatsView {
+    sv := &StatsView{
+        Table:           gui.NewTable(),
+        descending:      true,
+        timestampFormat: "15:04:05",
+        errorColor:      tcell.ColorIndianRed,
+        warningColor:    tcell.ColorDarkGoldenrod,
+    }
+    sv.SetSelectable(true, false)
+    sv.SetFixed(1, 0)
+    sv.SetSeparator(' ')
+    sv.SetSelectedFunc(func(row, _ int) {
+        sv.Lock()
+        var count *StatsCount
+        if sv.stats != nil && row > 0 && row <= len(sv.stats.Counts) {
+            count = &sv.stats.Counts[row-1]
+        }
+        stats := sv.stats
+        listener := sv.onSelected
+        sv.Unlock()
+        if count != nil && listener != nil {
+            listener(stats, *count)
+        }
+    })
+    sv.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+        column, ok := statsSortKeys[event.Rune()]
+        if !ok || event.Key() != tcell.KeyRune {
+            return event
+        }
+        sv.SortBy(column)
+        return nil
+    })
+    sv.refresh()
+    return sv
+}
+
+// SetStats sets the stats displayed, sorted by the current column
+func (sv *StatsView) SetStats(stats *Stats) {
+    sv.Lock()
+    defer sv.Unlock()
+
+    sv.stats = stats
+    if stats != nil {
+        stats.Sort(sv.sortColumn, sv.descending)
+    }
+    sv.refresh()
+    sv.Select(1, 0)
+}
+
+// SortBy sorts the counts by the column, in the reverse order if they are already sorted by the column. Counts
+// are in descending order when sorted by a new column, except for keys.
+func (sv *StatsView) SortBy(column StatsSortColumn) {
+    sv.Lock()
+    defer sv.Unlock()
+
+    if column == sv.sortColumn {
+        sv.descending = !sv.descending
+    } else {
+        sv.sortColumn = column
+        sv.descending = column != StatsByKey
+    }
+    if sv.stats == nil {
+        return
+    }
+    // keep the selected count selected
+    selected := ""
+    if row, _ := sv.GetSelection(); row > 0 && row <= len(sv.stats.Counts) {
+        selected = sv.stats.Counts[row-1].Key
+    }
+    sv.stats.Sort(sv.sortColumn, sv.descending)
+    sv.refresh()
+    for i, count := range sv.stats.Counts {
+        if count.Key == selected {
+            sv.Select(i+1, 0)
+        }
+    }
+}
+
+// SetOnStatsSelected sets a listener that is called when a count is selected with Enter or a mouse click
+func (sv *StatsView) SetOnStatsSelected(listener OnStatsSelected) {
+    sv.Lock()
+    defer sv.Unlock()
+
+    sv.onSelected = listener
+}
+
+// SetTimestampFormat sets the format of first and last seen timestamps
+func (sv *StatsView) SetTimestampFormat(format string) {
+    sv.Lock()
+    defer sv.Unlock()
+
+    sv.timestampFormat = format
+    sv.refresh()
+}
+
+// ****************
+// Internal methods
+
+func (sv *StatsView) refresh() {
+    sv.Clear()
+    if sv.stats == nil {
+        sv.SetTitle("Stats")
+        return
+    }
+    title := fmt.Sprintf("%d events by %s", sv.stats.Total, sv.stats.By)
+    if sv.stats.Missing > 0 {
+        title += fmt.Sprintf(", %d without %s", sv.stats.Missing, sv.stats.By)
+    }
+    sv.SetTitle(title + ", Enter filters, c/k/w/e/l sort")
+
+    columns := []string{"Count", "%", "Info", "Warn", "Error", "First seen", "Last seen", strings.ToUpper(sv.stats.By[:1]) + sv.stats.By[1:]}
+    sortedColumns := map[StatsSortColumn]int{StatsByCount: 0, StatsByWarnings: 3, StatsByErrors: 4, StatsByLastSeen: 6, StatsByKey: 7}
+    for col, title := range columns {
+        if col == sortedColumns[sv.sortColumn] {
+            if sv.descending {
+                title += " ▼"
+            } else {
+                title += " ▲"
+            }
+        }
+        cell := gui.NewTableCell(gui.Escape(title))
+        cell.SetSelectable(false)
+        cell.SetAttributes(tcell.AttrBold)
+        sv.SetCell(0, col, cell)
+    }
+    for i, c := range sv.stats.Counts {
+        key := c.Key
+        if key == "" {
+            key = "(empty)"
+        }
+        cells := []*gui.TableCell{
+            gui.NewTableCell(fmt.Sprintf("%d", c.Count)),
+            gui.NewTableCell(fmt.Sprintf("%.1f", 100*float64(c.Count)/float64(sv.stats.Total))),
+            gui.NewTableCell(fmt.Sprintf("%d", c.InfoCount)),
+            gui.NewTableCell(fmt.Sprintf("%d", c.WarningCount)),
+            gui.NewTableCell(fmt.Sprintf("%d", c.ErrorCount)),
+            gui.NewTableCell(c.FirstSeen.Format(sv.timestampFormat)),
+            gui.NewTableCell(c.LastSeen.Format(sv.timestampFormat)),
+            gui.NewTableCell(gui.Escape(key)),
+        }
+        for col := 0; col < 5; col++ {
+            cells[col].SetAlign(gui.AlignRight)
+        }
+        if c.WarningCount > 0 {
+            cells[3].SetTextColor(sv.warningColor)
+        }
+        if c.ErrorCount > 0 {
+            cells[4].SetTextColor(sv.errorColor)
+        }
+        cells[7].SetExpansion(1)
+        for col, cell := range cells {
+            sv.SetCell(i+1, col, cell)
+        }
+    }
+}
+
+// statsKey returns a function finding the key of an event aggregated by the name
+func statsKey(by string) (func(event *LogEvent) (string, bool), error) {
+    switch by {
+    case "source", "src":
+        return func(event *LogEvent) (string, bool) { return event.Source, true }, nil
+    case "level", "lvl":
+        return func(event *LogEvent) (string, bool) { return event.Level.String(), true }, nil
+    case "":
+        return nil, fmt.Errorf("stats require source, level or a path in the event data")
+    case "msg", "message", "id", "after", "before":
+        return nil, fmt.Errorf("stats cannot be aggregated by %s, expected source, level or a path in the event data", by)
+    }
+    for _, r := range by {
+        if !isQueryNameChar(r) {
+            return nil, fmt.Errorf("invalid data path %q, expected letters, digits, ., _ or -", by)
+        }
+    }
+    path := strings.Split(by, ".")
+    return func(event *LogEvent) (string, bool) { return lookupData(event.Data, path) }, nil
+}
+
+// quoteQueryValue quotes the value for a query if it is empty or has spaces or quotes
+func quoteQueryValue(value string) string {
+    if value != "" && !strings.ContainsAny(value, ` "\`) {
+        return value
+    }
+    return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,84 @@
+package clogviewr
+
+import (
+    "testing"
+    "time"
+)
+
+func TestLogView_Aggregate(t *testing.T) {
+    lv := NewLogView()
+    start := time.Date(2022, 3, 1, 10, 0, 0, 0, time.Local)
+    add := func(id, source string, level LogLevel, status interface{}, minute int) {
+        event := NewLogEvent(id, "request")
+        event.Source = source
+        event.Level = level
+        event.Timestamp = start.Add(time.Duration(minute) * time.Minute)
+        if status != nil {
+            event.Data = map[string]interface{}{"http": map[string]interface{}{"status": status}}
+        }
+        lv.AppendEvent(event)
+    }
+    add("e1", "web 1", LogLevelInfo, 200, 0)
+    add("e2", "web 1", LogLevelError, 503, 1)
+    add("e3", "api", LogLevelError, 503, 2)
+    add("e4", "api", LogLevelWarning, 404, 3)
+    add("e5", "api", LogLevelInfo, nil, 4)
+
+    stats, err := lv.Aggregate("source", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if stats.Total != 5 || len(stats.Counts) != 2 || stats.Counts[0].Key != "api" || stats.Counts[0].Count != 3 {
+        t.Errorf("Expected api to be counted first, got %+v", stats.Counts)
+    }
+    api := stats.Counts[0]
+    if api.InfoCount != 1 || api.WarningCount != 1 || api.ErrorCount != 1 || !api.FirstSeen.Equal(start.Add(2*time.Minute)) || !api.LastSeen.Equal(start.Add(4*time.Minute)) {
+        t.Errorf("Unexpected level mix or timestamps of api: %+v", api)
+    }
+
+    stats.Sort(StatsByErrors, true)
+    if stats.Counts[0].Key != "api" || stats.Counts[1].Key != "web 1" {
+        t.Errorf("Expected ties to be ordered by key, got %+v", stats.Counts)
+    }
+    stats.Sort(StatsByKey, true)
+    if stats.Counts[0].Key != "web 1" {
+        t.Errorf("Expected descending keys, got %+v", stats.Counts)
+    }
+
+    predicate, err := ParsePattern(stats.FilterPattern("web 1"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if lv.FindTotalMatches(predicate) != 2 {
+        t.Errorf("Expected drill-down filter %s to match the events of web 1", stats.FilterPattern("web 1"))
+    }
+
+    predicate, _ = ParsePattern(`\q after:10:01 before:10:04`)
+    stats, err = lv.Aggregate("http.status", predicate)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if stats.Total != 3 || stats.Missing != 0 || len(stats.Counts) != 2 || stats.Counts[0].Key != "503" || stats.Counts[0].Count != 2 {
+        t.Errorf("Expected 503 to be counted twice between 10:01 and 10:04, got %+v", stats)
+    }
+    predicate, _ = ParsePattern(stats.FilterPattern("503"))
+    if lv.FindTotalMatches(predicate) != 2 {
+        t.Errorf("Expected drill-down filter %s to match 2 events", stats.FilterPattern("503"))
+    }
+
+    if stats, _ = lv.Aggregate("http.status", nil); stats.Missing != 1 {
+        t.Errorf("Expected an event without status, got %d", stats.Missing)
+    }
+    if stats, _ = lv.Aggregate("level", nil); len(stats.Counts) != 3 || stats.Counts[0].Key != "error" {
+        t.Errorf("Expected errors to be counted first, got %+v", stats.Counts)
+    }
+    predicate, _ = ParsePattern(stats.FilterPattern("warning"))
+    if lv.FindTotalMatches(predicate) != 1 {
+        t.Errorf("Expected drill-down filter %s to match 1 event", stats.FilterPattern("warning"))
+    }
+    for _, by := range []string{"", "msg", "http status"} {
+        if _, err = lv.Aggregate(by, nil); err == nil {
+            t.Errorf("Expected aggregation by %q to fail", by)
+        }
+    }
+}
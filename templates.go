@@ -0,0 +1,290 @@
+package clogviewr
+
+import (
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "unicode"
+)
+
+// templateWildcard replaces variable tokens in templates
+const templateWildcard = "<*>"
+
+// LogTemplate is a snapshot of a message template mined from log events
+type LogTemplate struct {
+    ID        int
+    Pattern   string
+    Count     int
+    FirstSeen time.Time
+    LastSeen  time.Time
+
+    InfoCount    int
+    WarningCount int
+    ErrorCount   int
+}
+
+// TemplateMiner clusters event messages into templates with variable slots as they are appended, using
+// a simplified Drain algorithm: messages are grouped by the number of tokens and their leading tokens, then each
+// message joins the most similar template in its group, and tokens that differ become wildcards.
+//
+// Only the first line of a message is used for clustering. Template statistics are never decremented, so they
+// also cover events evicted from the log view. Once the number of templates reaches its maximum, messages that match
+// none of them are not clustered.
+type TemplateMiner struct {
+    // depth is the number of leading tokens used to group messages
+    depth int
+    // similarity is the minimal share of equal tokens for a message to join a template
+    similarity float64
+    // maxChildren limits the number of distinct tokens per tree node, the rest share a wildcard node
+    maxChildren int
+    // maxTemplates limits the number of templates, 0 is unlimited
+    maxTemplates int
+
+    root      map[int]*templateNode
+    templates map[int]*template
+    lastID    int
+    // changes counts updates, so that views know when to refresh
+    changes uint64
+
+    sync.RWMutex
+}
+
+type templateNode struct {
+    children  map[string]*templateNode
+    templates []*template
+}
+
+type template struct {
+    LogTemplate
+    tokens []string
+}
+
+// defaultMaxTemplates is the maximum number of templates of a new TemplateMiner
+const defaultMaxTemplates = 10_000
+
+// NewTemplateMiner creates a template miner that groups messages by their first token, with similarity of 0.5
+// and up to 10,000 templates
+func NewTemplateMiner() *TemplateMiner {
+    return &TemplateMiner{
+        depth:        1,
+        similarity:   0.5,
+        maxChildren:  100,
+        maxTemplates: defaultMaxTemplates,
+        root:         make(map[int]*templateNode),
+        templates:    make(map[int]*template),
+    }
+}
+
+// SetSimilarity sets the minimal share of equal tokens, in range (0, 1], for a message to join an existing template.
+// Lower values produce fewer, more generic templates.
+func (m *TemplateMiner) SetSimilarity(similarity float64) {
+    m.Lock()
+    defer m.Unlock()
+
+    m.similarity = similarity
+}
+
+// SetMaxTemplates sets the maximum number of templates, 0 removes the limit
+func (m *TemplateMiner) SetMaxTemplates(max int) {
+    m.Lock()
+    defer m.Unlock()
+
+    m.maxTemplates = max
+}
+
+// Add clusters the event message and updates template statistics. It returns the template id, or 0 if the message
+// matches no template and there are already as many templates as allowed.
+func (m *TemplateMiner) Add(event *LogEvent) int {
+    m.Lock()
+    defer m.Unlock()
+
+    tokens := tokenize(event.Message)
+    full := m.maxTemplates > 0 && len(m.templates) >= m.maxTemplates
+    leaf := m.leaf(tokens, !full)
+    t := m.bestMatch(leaf, tokens, m.similarity, false)
+    if t == nil {
+        if full {
+            return 0
+        }
+        m.lastID++
+        t = &template{
+            LogTemplate: LogTemplate{
+                ID:        m.lastID,
+                FirstSeen: event.Timestamp,
+            },
+            tokens: append([]string(nil), tokens...),
+        }
+        leaf.templates = append(leaf.templates, t)
+        m.templates[t.ID] = t
+    } else {
+        for i, token := range tokens {
+            if t.tokens[i] != token {
+                t.tokens[i] = templateWildcard
+            }
+        }
+    }
+    t.Count++
+    if event.Timestamp.Before(t.FirstSeen) {
+        t.FirstSeen = event.Timestamp
+    }
+    if event.Timestamp.After(t.LastSeen) {
+        t.LastSeen = event.Timestamp
+    }
+    switch event.Level {
+    case LogLevelWarning:
+        t.WarningCount++
+    case LogLevelError:
+        t.ErrorCount++
+    default:
+        t.InfoCount++
+    }
+    m.changes++
+    return t.ID
+}
+
+// Match returns the id of the template the message belongs to without updating templates, or 0 if there is none
+func (m *TemplateMiner) Match(message string) int {
+    m.RLock()
+    defer m.RUnlock()
+
+    tokens := tokenize(message)
+    if t := m.bestMatch(m.leaf(tokens, false), tokens, 1, true); t != nil {
+        return t.ID
+    }
+    return 0
+}
+
+// Templates returns all the templates ordered by the number of events, most frequent first
+func (m *TemplateMiner) Templates() []LogTemplate {
+    m.RLock()
+    defer m.RUnlock()
+
+    templates := make([]LogTemplate, 0, len(m.templates))
+    for _, t := range m.templates {
+        lt := t.LogTemplate
+        lt.Pattern = strings.Join(t.tokens, " ")
+        templates = append(templates, lt)
+    }
+    sort.Slice(templates, func(i, j int) bool {
+        if templates[i].Count != templates[j].Count {
+            return templates[i].Count > templates[j].Count
+        }
+        return templates[i].ID < templates[j].ID
+    })
+    return templates
+}
+
+// GetTemplate returns the template by its id
+func (m *TemplateMiner) GetTemplate(id int) (LogTemplate, bool) {
+    m.RLock()
+    defer m.RUnlock()
+
+    t, ok := m.templates[id]
+    if !ok {
+        return LogTemplate{}, false
+    }
+    lt := t.LogTemplate
+    lt.Pattern = strings.Join(t.tokens, " ")
+    return lt, true
+}
+
+// Clear removes all the templates
+func (m *TemplateMiner) Clear() {
+    m.Lock()
+    defer m.Unlock()
+
+    m.root = make(map[int]*templateNode)
+    m.templates = make(map[int]*template)
+    m.changes++
+}
+
+// changeCount returns the number of template updates
+func (m *TemplateMiner) changeCount() uint64 {
+    m.RLock()
+    defer m.RUnlock()
+
+    return m.changes
+}
+
+// ****************
+// Internal methods
+
+// tokenize splits the first line of the message into tokens
+func tokenize(message string) []string {
+    if i := strings.IndexByte(message, '\n'); i >= 0 {
+        message = message[:i]
+    }
+    return strings.Fields(message)
+}
+
+// leaf finds the tree node for the tokens, creating missing nodes if create is true.
+// Tokens with digits are likely to be variables, so they are always grouped under a wildcard node.
+func (m *TemplateMiner) leaf(tokens []string, create bool) *templateNode {
+    node, ok := m.root[len(tokens)]
+    if !ok {
+        if !create {
+            return nil
+        }
+        node = &templateNode{children: make(map[string]*templateNode)}
+        m.root[len(tokens)] = node
+    }
+    for i := 0; i < m.depth && i < len(tokens); i++ {
+        key := tokens[i]
+        if strings.IndexFunc(key, unicode.IsDigit) >= 0 {
+            key = templateWildcard
+        }
+        child, ok := node.children[key]
+        if !ok && create {
+            if len(node.children) >= m.maxChildren {
+                key = templateWildcard
+            }
+            if child, ok = node.children[key]; !ok {
+                child = &templateNode{children: make(map[string]*templateNode)}
+                node.children[key] = child
+            }
+        } else if !ok {
+            if child, ok = node.children[templateWildcard]; !ok {
+                return nil
+            }
+        }
+        node = child
+    }
+    return node
+}
+
+// bestMatch finds the template most similar to the tokens, with at least minSimilarity share of equal tokens.
+// If exact is true, wildcards are treated as equal to any token. Otherwise they are not counted as equal,
+// but on equal similarity the template with more wildcards is preferred.
+func (m *TemplateMiner) bestMatch(node *templateNode, tokens []string, minSimilarity float64, exact bool) *template {
+    if node == nil {
+        return nil
+    }
+    var best *template
+    bestSimilarity := -1.0
+    bestWildcards := 0
+    for _, t := range node.templates {
+        equal, wildcards := 0, 0
+        for i, token := range t.tokens {
+            if token == templateWildcard {
+                wildcards++
+            } else if token == tokens[i] {
+                equal++
+            }
+        }
+        if exact {
+            equal += wildcards
+        }
+        similarity := 1.0
+        if len(tokens) > 0 {
+            similarity = float64(equal) / float64(len(tokens))
+        }
+        if similarity > bestSimilarity || (similarity == bestSimilarity && wildcards > bestWildcards) {
+            best, bestSimilarity, bestWildcards = t, similarity, wildcards
+        }
+    }
+    if best == nil || bestSimilarity < minSimilarity {
+        return nil
+    }
+    return best
+}
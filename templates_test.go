@@ -0,0 +1,109 @@
+package clogviewr
+
+import (
+    "fmt"
+    "testing"
+    "time"
+)
+
+func TestTemplateMiner_Add(t *testing.T) {
+    m := NewTemplateMiner()
+    ts := time.Date(2021, 03, 01, 10, 0, 0, 0, time.Local)
+    users := []string{"alex", "bob", "carol"}
+    for i, user := range users {
+        event := NewLogEvent("u", fmt.Sprintf("user %s logged in from 10.0.0.%d", user, i))
+        event.Timestamp = ts.Add(time.Duration(i) * time.Second)
+        m.Add(event)
+    }
+    failed := NewLogEvent("f", "connection to database failed")
+    failed.Level = LogLevelError
+    m.Add(failed)
+
+    templates := m.Templates()
+    if len(templates) != 2 {
+        t.Fatalf("Expected 2 templates, got %v", templates)
+    }
+    login := templates[0]
+    if login.Pattern != "user <*> logged in from <*>" || login.Count != 3 || login.InfoCount != 3 {
+        t.Errorf("Invalid login template: %v", login)
+    }
+    if !login.FirstSeen.Equal(ts) || !login.LastSeen.Equal(ts.Add(2*time.Second)) {
+        t.Errorf("Invalid first and last seen timestamps: %v", login)
+    }
+    if templates[1].ErrorCount != 1 {
+        t.Errorf("Expected error to be counted: %v", templates[1])
+    }
+
+    if id := m.Match("user dave logged in from 10.0.0.5"); id != login.ID {
+        t.Errorf("Expected message to match the login template, got %d", id)
+    }
+    if id := m.Match("user dave logged out"); id != 0 {
+        t.Errorf("Expected message to match no template, got %d", id)
+    }
+}
+
+func TestLogView_TemplateFilter(t *testing.T) {
+    lv := NewLogView()
+    lv.pageHeight = 5
+    m := NewTemplateMiner()
+    lv.SetTemplateMiner(m)
+    for i := 0; i < 20; i++ {
+        if i%4 == 0 {
+            lv.AppendEvent(NewLogEvent(fmt.Sprintf("r%d", i), fmt.Sprintf("request %d completed in %dms", i, i*10)))
+        } else {
+            lv.AppendEvent(NewLogEvent(fmt.Sprintf("e%d", i), fmt.Sprintf("cache hit for key item-%d", i)))
+        }
+    }
+
+    id := m.Match("request 1 completed in 3ms")
+    lv.SetTemplateFilter(id)
+    lv.ScrollToTop()
+    if lv.GetCurrentEvent().EventID != "r0" {
+        t.Errorf("Expected first visible event to be r0, got %s", lv.GetCurrentEvent().EventID)
+    }
+    lv.SelectNextEvent()
+    if lv.GetCurrentEvent().EventID != "r4" {
+        t.Errorf("Scrolling must skip hidden events, got %s", lv.GetCurrentEvent().EventID)
+    }
+    if lv.ScrollToEventID("e5") {
+        t.Errorf("Hidden events must not be scrolled to")
+    }
+    matches := lv.FindTotalMatches(func(event *LogEvent) bool {
+        return true
+    })
+    if matches != 5 {
+        t.Errorf("Expected 5 visible events to match, got %d", matches)
+    }
+
+    lv.SetTemplateFilter(0)
+    lv.SelectNextEvent()
+    if lv.GetCurrentEvent().EventID != "e5" {
+        t.Errorf("Expected all events to be visible, got %s", lv.GetCurrentEvent().EventID)
+    }
+}
+
+func TestTemplateMiner_MaxTemplates(t *testing.T) {
+    m := NewTemplateMiner()
+    m.SetMaxTemplates(2)
+    first := m.Add(NewLogEvent("1", "connection refused"))
+    m.Add(NewLogEvent("2", "disk full"))
+    if id := m.Add(NewLogEvent("3", "certificate expired")); id != 0 || len(m.Templates()) != 2 {
+        t.Errorf("Expected no new template over the limit, got %d and %d templates", id, len(m.Templates()))
+    }
+    if id := m.Add(NewLogEvent("4", "connection refused")); id != first {
+        t.Errorf("Expected existing templates to keep counting, got %d", id)
+    }
+}
+
+func TestLogView_ClearTemplates(t *testing.T) {
+    lv := NewLogView()
+    miner := NewTemplateMiner()
+    lv.SetTemplateMiner(miner)
+    lv.AppendEvents(randomEvents(5, time.Now()))
+    lv.SetTemplateFilter(miner.Templates()[0].ID)
+
+    lv.Clear()
+    if len(miner.Templates()) != 0 || lv.templateFilter != 0 {
+        t.Errorf("Expected templates and the template filter to be cleared with the events")
+    }
+}
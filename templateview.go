@@ -0,0 +1,144 @@
+package clogviewr
+
+import (
+    gui "code.rocketnine.space/tslocum/cview"
+    "fmt"
+    "github.com/gdamore/tcell/v2"
+    "sync"
+)
+
+// OnTemplateSelected is a listener called when a template is selected in TemplateView
+type OnTemplateSelected func(template LogTemplate)
+
+// TemplateView is a table of message templates mined by TemplateMiner with event counts, first and last seen
+// timestamps and level mix. Templates are ordered by the number of events and refreshed as they change.
+type TemplateView struct {
+    *gui.Table
+
+    miner     *TemplateMiner
+    templates []LogTemplate
+    changes   uint64
+    // templates are refreshed on next draw even if they have not changed
+    stale bool
+
+    timestampFormat string
+    errorColor      tcell.Color
+    warningColor    tcell.Color
+
+    onSelected OnTemplateSelected
+
+    sync.Mutex
+}
+
+// NewTemplateView creates a new template view for templates mined by the miner
+func NewTemplateView(miner *TemplateMiner) *TemplateView {
+    tv := &TemplateView{
+        Table:           gui.NewTable(),
+        miner:           miner,
+        timestampFormat: "15:04:05",
+        errorColor:      tcell.ColorIndianRed,
+        warningColor:    tcell.ColorDarkGoldenrod,
+    }
+    tv.SetSelectable(true, false)
+    tv.SetFixed(1, 0)
+    tv.SetSeparator(' ')
+    tv.SetSelectedFunc(func(row, _ int) {
+        tv.Lock()
+        var template *LogTemplate
+        if row > 0 && row <= len(tv.templates) {
+            template = &tv.templates[row-1]
+        }
+        listener := tv.onSelected
+        tv.Unlock()
+        if template != nil && listener != nil {
+            listener(*template)
+        }
+    })
+    tv.stale = true
+    tv.refresh()
+    return tv
+}
+
+// SetOnTemplateSelected sets a listener that is called when a template is selected with Enter or a mouse click
+func (tv *TemplateView) SetOnTemplateSelected(listener OnTemplateSelected) {
+    tv.Lock()
+    defer tv.Unlock()
+
+    tv.onSelected = listener
+}
+
+// SetTimestampFormat sets the format of first and last seen timestamps
+func (tv *TemplateView) SetTimestampFormat(format string) {
+    tv.Lock()
+    defer tv.Unlock()
+
+    tv.timestampFormat = format
+    tv.stale = true
+}
+
+// Draw refreshes the templates, if they have changed, and draws the table
+func (tv *TemplateView) Draw(screen tcell.Screen) {
+    tv.refresh()
+    tv.Table.Draw(screen)
+}
+
+// ****************
+// Internal methods
+
+func (tv *TemplateView) refresh() {
+    tv.Lock()
+    defer tv.Unlock()
+
+    changes := tv.miner.changeCount()
+    if changes == tv.changes && !tv.stale {
+        return
+    }
+    tv.changes = changes
+    tv.stale = false
+
+    // keep the selected template selected as templates are reordered
+    selectedID := 0
+    if row, _ := tv.GetSelection(); row > 0 && row <= len(tv.templates) {
+        selectedID = tv.templates[row-1].ID
+    }
+    tv.templates = tv.miner.Templates()
+
+    tv.Clear()
+    for col, title := range []string{"Count", "Info", "Warn", "Error", "First seen", "Last seen", "Template"} {
+        cell := gui.NewTableCell(title)
+        cell.SetSelectable(false)
+        cell.SetAttributes(tcell.AttrBold)
+        tv.SetCell(0, col, cell)
+    }
+    for i, t := range tv.templates {
+        cells := []*gui.TableCell{
+            gui.NewTableCell(fmt.Sprintf("%d", t.Count)),
+            gui.NewTableCell(fmt.Sprintf("%d", t.InfoCount)),
+            gui.NewTableCell(fmt.Sprintf("%d", t.WarningCount)),
+            gui.NewTableCell(fmt.Sprintf("%d", t.ErrorCount)),
+            gui.NewTableCell(t.FirstSeen.Format(tv.timestampFormat)),
+            gui.NewTableCell(t.LastSeen.Format(tv.timestampFormat)),
+            gui.NewTableCell(gui.Escape(t.Pattern)),
+        }
+        for col := 0; col < 4; col++ {
+            cells[col].SetAlign(gui.AlignRight)
+        }
+        if t.WarningCount > 0 {
+            cells[2].SetTextColor(tv.warningColor)
+        }
+        if t.ErrorCount > 0 {
+            cells[3].SetTextColor(tv.errorColor)
+        }
+        cells[6].SetExpansion(1)
+        for col, cell := range cells {
+            tv.SetCell(i+1, col, cell)
+        }
+    }
+    row := 1
+    for i, t := range tv.templates {
+        if t.ID == selectedID {
+            row = i + 1
+        }
+    }
+    tv.Select(row, 0)
+}
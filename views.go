@@ -0,0 +1,296 @@
+package clogviewr
+
+import (
+    "encoding/json"
+    "fmt"
+    "github.com/dlclark/regexp2"
+    "github.com/gdamore/tcell/v2"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// View is a named set of log view settings that can be saved and switched to: filters, highlighting, hidden
+// levels and sources, and display of the timestamp and source columns.
+//
+// Filters and highlight rules are stored as patterns, see ParsePattern. Filters and rules without a pattern, which
+// only have a predicate, are left out.
+type View struct {
+    Name string `json:"name"`
+
+    Filters       []string      `json:"filters,omitempty"`
+    ContextBefore int           `json:"contextBefore,omitempty"`
+    ContextAfter  int           `json:"contextAfter,omitempty"`
+    ContextWindow time.Duration `json:"contextWindow,omitempty"`
+    HiddenLevels  []string      `json:"hiddenLevels,omitempty"`
+    HiddenSources []string      `json:"hiddenSources,omitempty"`
+
+    Highlighting     bool            `json:"highlighting"`
+    HighlightPattern string          `json:"highlightPattern,omitempty"`
+    HighlightRules   []ViewHighlight `json:"highlightRules,omitempty"`
+    HighlightLevels  bool            `json:"highlightLevels"`
+
+    ShowTimestamp    bool   `json:"showTimestamp"`
+    TimestampFormat  string `json:"timestampFormat,omitempty"`
+    ShowSource       bool   `json:"showSource"`
+    SourceClipLength int    `json:"sourceClipLength,omitempty"`
+    Wrap             bool   `json:"wrap"`
+}
+
+// ViewHighlight is a highlight rule of a view, Color is a tcell color name or #rrggbb
+type ViewHighlight struct {
+    Pattern string `json:"pattern"`
+    Color   string `json:"color"`
+}
+
+// DefaultViewsPath returns the path of the views file in the user configuration directory
+func DefaultViewsPath() (string, error) {
+    dir, err := os.UserConfigDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(dir, "clogviewr", "views.json"), nil
+}
+
+// LoadViews reads views from the file, a file that does not exist has no views
+func LoadViews(path string) ([]View, error) {
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    views := make([]View, 0)
+    if err = json.Unmarshal(data, &views); err != nil {
+        return nil, fmt.Errorf("invalid views file %s: %v", path, err)
+    }
+    return views, nil
+}
+
+// SaveViews writes the views to the file, creating its directory if needed. The file is replaced only once the
+// views are written completely.
+func SaveViews(path string, views []View) error {
+    data, err := json.MarshalIndent(views, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        return err
+    }
+    tmp := path + ".tmp"
+    if err = os.WriteFile(tmp, data, 0600); err != nil {
+        return err
+    }
+    return os.Rename(tmp, path)
+}
+
+// CaptureView returns the current settings of the log view as a view with the name
+func (lv *LogView) CaptureView(name string) View {
+    lv.RLock()
+    defer lv.RUnlock()
+
+    view := View{
+        Name:             name,
+        Filters:          filterPatterns(lv.filters),
+        ContextBefore:    lv.filterContext.Before,
+        ContextAfter:     lv.filterContext.After,
+        ContextWindow:    lv.filterContext.Window,
+        HiddenLevels:     hiddenLevelNames(lv.hiddenLevels),
+        HiddenSources:    hiddenSourceNames(lv.hiddenSources),
+        Highlighting:     lv.highlightingEnabled,
+        HighlightRules:   highlightRulePatterns(lv.highlightRules),
+        HighlightLevels:  lv.highlightLevels,
+        ShowTimestamp:    lv.showTimestamp,
+        TimestampFormat:  lv.timestampFormat,
+        ShowSource:       lv.showSource,
+        SourceClipLength: lv.sourceClipLength,
+        Wrap:             lv.wrap,
+    }
+    if lv.highlightPattern != nil {
+        view.HighlightPattern = lv.highlightPattern.String()
+    }
+    return view
+}
+
+// ApplyView replaces the filters, highlighting, hidden levels and sources and column settings of the log view with
+// the settings of the view, a view without a highlight pattern clears the pattern. Nothing is changed if a pattern of
+// the view is invalid.
+func (lv *LogView) ApplyView(view View) error {
+    filters, err := parseFilterPatterns(view.Filters)
+    if err != nil {
+        return fmt.Errorf("%v in view %s", err, view.Name)
+    }
+    rules, err := parseHighlightRulePatterns(view.HighlightRules)
+    if err != nil {
+        return fmt.Errorf("%v in view %s", err, view.Name)
+    }
+    hiddenLevels, err := parseHiddenLevels(view.HiddenLevels)
+    if err != nil {
+        return fmt.Errorf("%v in view %s", err, view.Name)
+    }
+    var highlightPattern *regexp2.Regexp
+    if view.HighlightPattern != "" {
+        pattern, err := regexp2.Compile(view.HighlightPattern, regexp2.IgnoreCase+regexp2.RE2)
+        if err != nil {
+            return fmt.Errorf("invalid highlight pattern in view %s: %v", view.Name, err)
+        }
+        highlightPattern = pattern
+    }
+
+    defer lv.fireOnCurrentChange(lv.current)
+    lv.Lock()
+    defer lv.Unlock()
+
+    lv.filters = filters
+    lv.filterContext = FilterContext{Before: view.ContextBefore, After: view.ContextAfter, Window: view.ContextWindow}
+    lv.hiddenLevels = hiddenLevels
+    lv.hiddenSources = hiddenSourceSet(view.HiddenSources)
+    lv.highlightingEnabled = view.Highlighting
+    lv.highlightPattern = highlightPattern
+    lv.highlightRules = rules
+    lv.highlightLevels = view.HighlightLevels
+    lv.showTimestamp = view.ShowTimestamp
+    if view.TimestampFormat != "" {
+        lv.timestampFormat = view.TimestampFormat
+    }
+    lv.showSource = view.ShowSource
+    if view.SourceClipLength > 0 {
+        lv.sourceClipLength = view.SourceClipLength
+    }
+    if lv.wrap != view.Wrap {
+        lv.wrap = view.Wrap
+        lv.forceWrap = true
+    }
+    lv.recolorizeLines()
+    lv.applyFilters()
+    return nil
+}
+
+// *******************************
+// internal implementation details
+
+// colorName returns the tcell name of the color, the first one alphabetically if it has more, or #rrggbb if it has
+// no name
+func colorName(color tcell.Color) string {
+    found := ""
+    for name, c := range tcell.ColorNames {
+        if c == color && (found == "" || name < found) {
+            found = name
+        }
+    }
+    if found == "" {
+        return fmt.Sprintf("#%06x", color.Hex())
+    }
+    return found
+}
+
+// filterPatterns returns the patterns of the filters that have one
+func filterPatterns(filters []EventFilter) []string {
+    patterns := make([]string, 0, len(filters))
+    for _, filter := range filters {
+        if filter.Pattern != "" {
+            patterns = append(patterns, filter.Pattern)
+        }
+    }
+    return patterns
+}
+
+// parseFilterPatterns creates filters from the patterns, see ParsePattern
+func parseFilterPatterns(patterns []string) ([]EventFilter, error) {
+    filters := make([]EventFilter, 0, len(patterns))
+    for _, pattern := range patterns {
+        predicate, err := ParsePattern(pattern)
+        if err != nil {
+            return nil, fmt.Errorf("invalid filter %s: %v", pattern, err)
+        }
+        filters = append(filters, EventFilter{Name: pattern, Pattern: pattern, Predicate: predicate})
+    }
+    return filters, nil
+}
+
+// highlightRulePatterns returns the patterns of the highlight rules that have one, with their colors
+func highlightRulePatterns(rules []HighlightRule) []ViewHighlight {
+    var highlights []ViewHighlight
+    for _, rule := range rules {
+        if rule.Pattern != "" {
+            highlights = append(highlights, ViewHighlight{Pattern: rule.Pattern, Color: colorName(rule.Color)})
+        }
+    }
+    return highlights
+}
+
+// unsavedPatterns returns the number of filters and highlight rules without a pattern, which views and sessions
+// leave out
+func unsavedPatterns(filters []EventFilter, rules []HighlightRule) int {
+    unsaved := 0
+    for _, filter := range filters {
+        if filter.Pattern == "" {
+            unsaved++
+        }
+    }
+    for _, rule := range rules {
+        if rule.Pattern == "" {
+            unsaved++
+        }
+    }
+    return unsaved
+}
+
+// parseHighlightRulePatterns creates highlight rules from the patterns, see ParsePattern
+func parseHighlightRulePatterns(highlights []ViewHighlight) ([]HighlightRule, error) {
+    rules := make([]HighlightRule, 0, len(highlights))
+    for _, highlight := range highlights {
+        predicate, err := ParsePattern(highlight.Pattern)
+        if err != nil {
+            return nil, fmt.Errorf("invalid highlight rule %s: %v", highlight.Pattern, err)
+        }
+        rules = append(rules, HighlightRule{Name: highlight.Pattern, Pattern: highlight.Pattern, Predicate: predicate, Color: tcell.GetColor(highlight.Color)})
+    }
+    return rules, nil
+}
+
+// hiddenLevelNames returns the names of the hidden levels
+func hiddenLevelNames(hiddenLevels [LogLevelAll]bool) []string {
+    var names []string
+    for level, hidden := range hiddenLevels {
+        if hidden {
+            names = append(names, LogLevel(level).String())
+        }
+    }
+    return names
+}
+
+// parseHiddenLevels returns the levels with the names as hidden
+func parseHiddenLevels(names []string) ([LogLevelAll]bool, error) {
+    var hiddenLevels [LogLevelAll]bool
+    for _, name := range names {
+        level, ok := queryLevels[strings.ToLower(name)]
+        if !ok {
+            return hiddenLevels, fmt.Errorf("unknown level %s", name)
+        }
+        hiddenLevels[level] = true
+    }
+    return hiddenLevels, nil
+}
+
+// hiddenSourceNames returns the hidden sources sorted
+func hiddenSourceNames(hiddenSources map[string]bool) []string {
+    var names []string
+    for source := range hiddenSources {
+        names = append(names, source)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// hiddenSourceSet returns the set of hidden sources with the names
+func hiddenSourceSet(names []string) map[string]bool {
+    hiddenSources := make(map[string]bool, len(names))
+    for _, source := range names {
+        hiddenSources[source] = true
+    }
+    return hiddenSources
+}
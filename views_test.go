@@ -0,0 +1,97 @@
+package clogviewr
+
+import (
+    "github.com/gdamore/tcell/v2"
+    "path/filepath"
+    "reflect"
+    "testing"
+    "time"
+)
+
+func TestLogView_Views(t *testing.T) {
+    lv := NewLogView()
+    lv.AppendEvents(randomEvents(20, time.Now()))
+    predicate, _ := ParsePattern(`\q id:e1*`)
+    lv.PushFilter(EventFilter{Name: `\q id:e1*`, Pattern: `\q id:e1*`, Predicate: predicate})
+    lv.SetFilterContext(FilterContext{After: 1})
+    lv.SetLevelVisible(LogLevelWarning, false)
+    lv.SetSourceVisible("healthcheck", false)
+    lv.SetHighlightPattern(`(?P<g1>Event)`)
+    lv.SetHighlighting(true)
+    predicate, _ = ParsePattern("#7")
+    lv.AddHighlightRule(HighlightRule{Name: "#7", Pattern: "#7", Predicate: predicate, Color: tcell.ColorRed})
+    lv.SetShowTimestamp(true)
+    lv.SetSourceClipLength(10)
+
+    path := filepath.Join(t.TempDir(), "config", "views.json")
+    if views, err := LoadViews(path); err != nil || len(views) != 0 {
+        t.Fatalf("Expected no views before they are saved, got %v, %v", views, err)
+    }
+    if err := SaveViews(path, []View{lv.CaptureView("ones")}); err != nil {
+        t.Fatalf("Failed to save views: %v", err)
+    }
+    views, err := LoadViews(path)
+    if err != nil || len(views) != 1 {
+        t.Fatalf("Failed to load views: %v, %v", views, err)
+    }
+    if !reflect.DeepEqual(views[0], lv.CaptureView("ones")) {
+        t.Errorf("Expected the loaded view to equal the saved one, got %+v", views[0])
+    }
+
+    other := NewLogView()
+    other.AppendEvents(randomEvents(20, time.Now()))
+    if err = other.ApplyView(views[0]); err != nil {
+        t.Fatalf("Failed to apply view: %v", err)
+    }
+    if !reflect.DeepEqual(other.CaptureView("ones"), views[0]) {
+        t.Errorf("Expected the view to be applied, got %+v", other.CaptureView("ones"))
+    }
+    if matches := other.FindTotalMatches(func(event *LogEvent) bool { return true }); matches != 12 {
+        t.Errorf("Expected e1, e10-e19 and e2 in the context to be visible, got %d events", matches)
+    }
+    if other.highlightRules[0].Color != tcell.ColorRed {
+        t.Errorf("Expected the highlight rule color to be restored, got %v", other.highlightRules[0].Color)
+    }
+
+    plain := NewLogView().CaptureView("plain")
+    plain.Highlighting = false
+    if err = other.ApplyView(plain); err != nil {
+        t.Fatalf("Failed to apply view: %v", err)
+    }
+    if other.highlightPattern != nil || other.IsHighlightingEnabled() {
+        t.Errorf("Expected a view without highlighting to clear the highlight pattern and disable highlighting")
+    }
+
+    views[0].Filters = []string{`\r (`}
+    if err = other.ApplyView(views[0]); err == nil {
+        t.Errorf("Expected a view with an invalid filter to fail")
+    }
+}
+
+func TestLogView_ViewWithoutPatterns(t *testing.T) {
+    lv := NewLogView()
+    lv.AppendEvents(randomEvents(20, time.Now()))
+    predicate, _ := ParsePattern("#7")
+    lv.PushFilter(EventFilter{Name: "errors only", Predicate: func(event *LogEvent) bool { return event.Level == LogLevelError }})
+    lv.PushFilter(EventFilter{Name: "#7", Pattern: "#7", Predicate: predicate})
+    lv.AddHighlightRule(HighlightRule{Name: "sevens", Predicate: predicate, Color: tcell.ColorRed})
+
+    view := lv.CaptureView("sevens")
+    if !reflect.DeepEqual(view.Filters, []string{"#7"}) || len(view.HighlightRules) != 0 {
+        t.Errorf("Expected only the filter with a pattern to be saved, got %v and %v", view.Filters, view.HighlightRules)
+    }
+    if unsaved := unsavedPatterns(lv.GetFilters(), lv.GetHighlightRules()); unsaved != 2 {
+        t.Errorf("Expected the filter and the rule without a pattern to be left out, got %d", unsaved)
+    }
+    other := NewLogView()
+    if err := other.ApplyView(view); err != nil {
+        t.Fatalf("Failed to apply view: %v", err)
+    }
+    if filters := other.GetFilters(); len(filters) != 1 || filters[0].Pattern != "#7" {
+        t.Errorf("Expected the filter with a pattern to be restored, got %v", filters)
+    }
+    filter, _ := QueryFilter("id:e1*")
+    if _, err := ParsePattern(filter.Pattern); err != nil {
+        t.Errorf("Expected the pattern of a query filter to be valid, got %v", err)
+    }
+}